@@ -0,0 +1,251 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/rookie-ninja/rk-query"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	certReasonFile   = "fileChanged"
+	certReasonSignal = "SIGUSR1"
+	// wait for writers to finish, since cert and key are usually updated one after another
+	certReloadDebounce = 500 * time.Millisecond
+)
+
+// certReloader serves server certificate with tls.Config.GetCertificate and reloads it from files of
+// rkentry.CertEntry once files changed or SIGUSR1 received.
+//
+// SIGHUP is not used since it is registered as shutdown signal by rkentry.GlobalAppCtx.
+//
+// Rotated certificate is kept in reloader only, rkentry.CertEntry is never modified.
+type certReloader struct {
+	entry    *GinEntry
+	certPath string
+	keyPath  string
+	current  *tls.Certificate
+	expiry   *prometheus.GaugeVec
+	watcher  *fsnotify.Watcher
+	sigCh    chan os.Signal
+	quitCh   chan struct{}
+	lock     sync.RWMutex
+}
+
+// Paths of certificate and key of CertEntry in boot config, empty paths would be returned if missing.
+func certPemPaths(boot *rkentry.BootCert, name string) (string, string) {
+	for _, cert := range boot.Cert {
+		if cert != nil && cert.Name == name {
+			return cert.CertPemPath, cert.KeyPemPath
+		}
+	}
+
+	return "", ""
+}
+
+// Create a new certReloader with paths of certificate and key provided by WithCertReload.
+func newCertReloader(entry *GinEntry) (*certReloader, error) {
+	if !entry.IsTlsEnabled() {
+		return nil, errors.New("TLS is not enabled")
+	}
+
+	certPath, keyPath := entry.CertPemPath, entry.KeyPemPath
+	if len(certPath) < 1 || len(keyPath) < 1 {
+		return nil, errors.New("certPemPath and keyPemPath are required for reloading")
+	}
+
+	if rkentry.GlobalAppCtx.GetEmbedFS(rkentry.CertEntryType, entry.CertEntry.GetName()) != nil {
+		return nil, errors.New("certificate from embed.FS could not be reloaded")
+	}
+
+	wd, _ := os.Getwd()
+	if !filepath.IsAbs(certPath) {
+		certPath = filepath.Join(wd, certPath)
+	}
+	if !filepath.IsAbs(keyPath) {
+		keyPath = filepath.Join(wd, keyPath)
+	}
+
+	reloader := &certReloader{
+		entry:    entry,
+		certPath: certPath,
+		keyPath:  keyPath,
+		current:  entry.CertEntry.Certificate,
+		quitCh:   make(chan struct{}),
+	}
+
+	if entry.IsPromEnabled() {
+		reloader.expiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "rk",
+			Subsystem: "cert",
+			Name:      "expiry_timestamp_seconds",
+			Help:      "Expiry of server certificate in unix seconds",
+		}, []string{"entryName", "certEntry"})
+
+		if err := entry.PromEntry.Registerer.Register(reloader.expiry); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				reloader.expiry = are.ExistingCollector.(*prometheus.GaugeVec)
+			} else {
+				return nil, err
+			}
+		}
+
+		reloader.observeExpiry(reloader.current)
+	}
+
+	return reloader, nil
+}
+
+// GetCertificate returns current certificate, used as tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.current, nil
+}
+
+// Start watching directories of certificate and key, and SIGUSR1.
+// Directories are watched instead of files, since files are replaced with symlink in kubernetes secret volume.
+func (r *certReloader) start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range []string{filepath.Dir(r.certPath), filepath.Dir(r.keyPath)} {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	r.watcher = watcher
+	r.sigCh = make(chan os.Signal, 1)
+	if len(certReloadSignals) > 0 {
+		signal.Notify(r.sigCh, certReloadSignals...)
+	}
+
+	go r.watch()
+
+	return nil
+}
+
+// Stop watching.
+func (r *certReloader) stop() {
+	select {
+	case <-r.quitCh:
+		return
+	default:
+		close(r.quitCh)
+	}
+
+	signal.Stop(r.sigCh)
+	if r.watcher != nil {
+		r.watcher.Close()
+	}
+}
+
+func (r *certReloader) watch() {
+	var timer <-chan time.Time
+
+	for {
+		select {
+		case <-r.quitCh:
+			return
+		case <-r.sigCh:
+			r.reload(certReasonSignal)
+		case e, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if e.Op&fsnotify.Chmod == e.Op {
+				continue
+			}
+			timer = time.After(certReloadDebounce)
+		case <-timer:
+			timer = nil
+			r.reload(certReasonFile)
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.entry.LoggerEntry.Warn("Error occurs while watching certificate.", zap.Error(err))
+		}
+	}
+}
+
+// Read certificate and key from files and swap with current one if changed, record as event.
+func (r *certReloader) reload(reason string) error {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err == nil {
+		r.lock.RLock()
+		unchanged := r.current != nil && bytes.Equal(r.current.Certificate[0], cert.Certificate[0])
+		r.lock.RUnlock()
+
+		if unchanged {
+			return nil
+		}
+	}
+
+	event := r.entry.EventEntry.Start(
+		"RotateCert",
+		rkquery.WithEntryName(r.entry.GetName()),
+		rkquery.WithEntryType(r.entry.GetType()))
+	event.AddPayloads(
+		zap.String("certEntry", r.entry.CertEntry.GetName()),
+		zap.String("certPemPath", r.certPath),
+		zap.String("reason", reason))
+
+	// keep serving with previous certificate
+	if err != nil {
+		event.AddErr(err)
+		r.entry.EventEntry.FinishWithCond(event, false)
+		r.entry.LoggerEntry.Warn("Failed to reload certificate.", zap.Error(err))
+		return err
+	}
+
+	r.lock.Lock()
+	r.current = &cert
+	r.lock.Unlock()
+
+	if leaf := r.observeExpiry(&cert); leaf != nil {
+		event.AddPayloads(zap.Time("notAfter", leaf.NotAfter))
+	}
+
+	r.entry.EventEntry.Finish(event)
+
+	return nil
+}
+
+// Update expiry gauge and return parsed leaf certificate.
+func (r *certReloader) observeExpiry(cert *tls.Certificate) *x509.Certificate {
+	if cert == nil || len(cert.Certificate) < 1 {
+		return nil
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil
+	}
+
+	if r.expiry != nil {
+		r.expiry.WithLabelValues(r.entry.GetName(), r.entry.CertEntry.GetName()).Set(float64(leaf.NotAfter.Unix()))
+	}
+
+	return leaf
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals which trigger reloading of certificate.
+var certReloadSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,11 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import "os"
+
+// SIGUSR1 is not available on windows, certificate would be reloaded once files changed only.
+var certReloadSignals []os.Signal
@@ -0,0 +1,237 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func newReloadableCertEntry(t *testing.T, name string) (*rkentry.CertEntry, string, string) {
+	dir := t.TempDir()
+	certPath, keyPath := path.Join(dir, "cert.pem"), path.Join(dir, "key.pem")
+	writeCerts(t, certPath, keyPath)
+
+	certEntry := rkentry.RegisterCertEntry(&rkentry.BootCert{
+		Cert: []*rkentry.BootCertE{
+			{
+				Name:        name,
+				CertPemPath: certPath,
+				KeyPemPath:  keyPath,
+			},
+		},
+	})[0]
+	certEntry.Bootstrap(context.TODO())
+
+	return certEntry, certPath, keyPath
+}
+
+func writeCerts(t *testing.T, certPath, keyPath string) {
+	certPem, keyPem := generateCerts()
+	assert.Nil(t, os.WriteFile(certPath, certPem, 0644))
+	assert.Nil(t, os.WriteFile(keyPath, keyPem, 0644))
+}
+
+func TestNewCertReloader(t *testing.T) {
+	// without TLS
+	_, err := newCertReloader(RegisterGinEntry())
+	assert.NotNil(t, err)
+
+	// without paths
+	certEntry := rkentry.RegisterCertEntry(&rkentry.BootCert{
+		Cert: []*rkentry.BootCertE{
+			{
+				Name: "ut-cert",
+			},
+		},
+	})[0]
+	certificate, _ := tls.X509KeyPair(generateCerts())
+	certEntry.Certificate = &certificate
+	_, err = newCertReloader(RegisterGinEntry(WithCertEntry(certEntry), WithCertReload("", "")))
+	assert.NotNil(t, err)
+
+	// happy case with expiry exported
+	certEntry, certPath, keyPath := newReloadableCertEntry(t, "ut-cert-reload")
+	entry := RegisterGinEntry(
+		WithCertEntry(certEntry),
+		WithCertReload(certPath, keyPath),
+		WithPromEntry(rkentry.RegisterPromEntry(&rkentry.BootProm{
+			Enabled: true,
+		}, rkentry.WithRegistryPromEntry(prometheus.NewRegistry()))))
+	reloader, err := newCertReloader(entry)
+	assert.Nil(t, err)
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, certEntry.Certificate, cert)
+
+	families, _ := entry.PromEntry.Gatherer.Gather()
+	found := false
+	for _, family := range families {
+		if family.GetName() == "rk_cert_expiry_timestamp_seconds" {
+			found = true
+			assert.True(t, family.GetMetric()[0].GetGauge().GetValue() > float64(time.Now().Unix()))
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	certEntry, certPath, keyPath := newReloadableCertEntry(t, "ut-cert-reload")
+	entry := RegisterGinEntry(WithCertEntry(certEntry), WithCertReload(certPath, keyPath))
+	reloader, err := newCertReloader(entry)
+	assert.Nil(t, err)
+	assert.Nil(t, reloader.start())
+	defer reloader.stop()
+
+	// case 1: rotated by file change
+	old, _ := reloader.GetCertificate(nil)
+	writeCerts(t, certPath, keyPath)
+	assert.Eventually(t, func() bool {
+		cert, _ := reloader.GetCertificate(nil)
+		return !bytes.Equal(old.Certificate[0], cert.Certificate[0])
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// certificate of CertEntry is not modified
+	current, _ := reloader.GetCertificate(nil)
+	assert.Equal(t, old, certEntry.Certificate)
+
+	// case 2: invalid file should keep previous certificate
+	assert.Nil(t, os.WriteFile(certPath, []byte("invalid"), 0644))
+	assert.NotNil(t, reloader.reload(certReasonSignal))
+	cert, _ := reloader.GetCertificate(nil)
+	assert.Equal(t, current, cert)
+
+	// case 3: rotated by signal sent to process
+	if len(certReloadSignals) < 1 {
+		return
+	}
+	reloader.watcher.Remove(path.Dir(certPath))
+	time.Sleep(certReloadDebounce)
+	writeCerts(t, certPath, keyPath)
+
+	// receive shutdown signal of rkentry.GlobalAppCtx as rk-boot does
+	shutdownCh := make(chan os.Signal, 1)
+	listening := make(chan struct{})
+	go func() {
+		close(listening)
+		select {
+		case sig := <-rkentry.GlobalAppCtx.GetShutdownSig():
+			shutdownCh <- sig
+		case <-time.After(5 * time.Second):
+		}
+	}()
+	<-listening
+
+	process, _ := os.FindProcess(os.Getpid())
+	assert.Nil(t, process.Signal(certReloadSignals[0]))
+	assert.Eventually(t, func() bool {
+		cert, _ := reloader.GetCertificate(nil)
+		return !bytes.Equal(current.Certificate[0], cert.Certificate[0])
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// process is still running and not shutting down
+	select {
+	case sig := <-shutdownCh:
+		assert.Fail(t, "reload signal triggered shutdown", sig.String())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCertReloader_ReloadConcurrently(t *testing.T) {
+	certEntry, certPath, keyPath := newReloadableCertEntry(t, "ut-cert-reload")
+	entry := RegisterGinEntry(WithCertEntry(certEntry), WithCertReload(certPath, keyPath))
+	reloader, err := newCertReloader(entry)
+	assert.Nil(t, err)
+	entry.certReloader = reloader
+	old := certEntry.Certificate
+
+	// reload while serving, would be reported by race detector if CertEntry is modified
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			writeCerts(t, certPath, keyPath)
+			assert.Nil(t, reloader.reload(certReasonSignal))
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			assert.True(t, entry.IsTlsEnabled())
+			config := entry.newTLSConfig()
+			cert, err := config.GetCertificate(nil)
+			assert.Nil(t, err)
+			assert.NotNil(t, cert)
+		}
+	}
+
+	current, _ := reloader.GetCertificate(nil)
+	assert.False(t, bytes.Equal(old.Certificate[0], current.Certificate[0]))
+	assert.Same(t, old, certEntry.Certificate)
+}
+
+func TestCertPemPaths(t *testing.T) {
+	boot := &rkentry.BootCert{
+		Cert: []*rkentry.BootCertE{
+			nil,
+			{Name: "ut-cert", CertPemPath: "ut-cert.pem", KeyPemPath: "ut-key.pem"},
+		},
+	}
+
+	certPath, keyPath := certPemPaths(boot, "ut-cert")
+	assert.Equal(t, "ut-cert.pem", certPath)
+	assert.Equal(t, "ut-key.pem", keyPath)
+
+	certPath, keyPath = certPemPaths(boot, "ut-missing")
+	assert.Empty(t, certPath)
+	assert.Empty(t, keyPath)
+}
+
+func generateCerts() ([]byte, []byte) {
+	// Create certs and return as []byte
+	ca := &x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{"Fake cert."},
+		},
+		SerialNumber:          big.NewInt(42),
+		NotAfter:              time.Now().Add(2 * time.Hour),
+		IsCA:                  true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	// Create a Private Key
+	key, _ := rsa.GenerateKey(rand.Reader, 4096)
+
+	// Use CA Cert to sign a CSR and create a Public Cert
+	csr := &key.PublicKey
+	cert, _ := x509.CreateCertificate(rand.Reader, ca, ca, csr, key)
+
+	// Convert keys into pem.Block
+	c := &pem.Block{Type: "CERTIFICATE", Bytes: cert}
+	k := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+
+	return pem.EncodeToMemory(c), pem.EncodeToMemory(k)
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-query"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	configReasonFile = "fileChanged"
+	configReasonApi  = "api"
+	// wait for writers to finish, since editors may write file more than once
+	configReloadDebounce = 500 * time.Millisecond
+	// path of reload endpoint in admin router
+	configReloadPath = "/rk/v1/reload"
+	// key of middleware chain in gin.Context, request would go through the chain it started with
+	middlewareChainKey = "rkMiddlewareChain"
+)
+
+// configReloader reloads middleware of gin entry from boot config file once file changed or reload endpoint called.
+//
+// Middleware chain is swapped atomically, requests in-flight would finish with previous chain.
+// Invalid config would be rejected as a whole, previous chain would be kept.
+type configReloader struct {
+	entry   *GinEntry
+	path    string
+	builder *middlewareBuilder
+	current atomic.Value
+	watcher *fsnotify.Watcher
+	quitCh  chan struct{}
+	lock    sync.Mutex
+}
+
+// Create a new configReloader with middleware state built while registering entry.
+func newConfigReloader(entry *GinEntry, path string, builder *middlewareBuilder, state *middlewareState) (*configReloader, error) {
+	if len(path) < 1 {
+		return nil, errors.New("path of boot config is required for reloading")
+	}
+
+	if !filepath.IsAbs(path) {
+		wd, _ := os.Getwd()
+		path = filepath.Join(wd, path)
+	}
+
+	reloader := &configReloader{
+		entry:   entry,
+		path:    path,
+		builder: builder,
+		quitCh:  make(chan struct{}),
+	}
+	reloader.current.Store(state)
+
+	return reloader, nil
+}
+
+// Current middleware state.
+func (r *configReloader) load() *middlewareState {
+	return r.current.Load().(*middlewareState)
+}
+
+// Acquire reference of current middleware state, which should be released once request finished.
+func (r *configReloader) acquire() *middlewareState {
+	for {
+		// state may be replaced and released after loaded
+		if state := r.load(); state.acquire() {
+			return state
+		}
+	}
+}
+
+// Handlers dispatch to middleware of current chain, one handler per slot.
+func (r *configReloader) handlers() []gin.HandlerFunc {
+	res := make([]gin.HandlerFunc, len(r.load().mids))
+
+	for i := range res {
+		index := i
+		res[i] = func(ctx *gin.Context) {
+			mids, ok := ctx.Value(middlewareChainKey).([]gin.HandlerFunc)
+			if !ok {
+				// keep chain referenced until request finished, resources of it would be released after that
+				state := r.acquire()
+				defer state.release()
+
+				mids = state.mids
+				ctx.Set(middlewareChainKey, mids)
+
+				if mids[index] != nil {
+					mids[index](ctx)
+				}
+				ctx.Next()
+				return
+			}
+
+			// gin would continue with next handler
+			if mids[index] != nil {
+				mids[index](ctx)
+			}
+		}
+	}
+
+	return res
+}
+
+// Start watching directory of config file.
+// Directory is watched instead of file, since file is replaced with symlink in kubernetes config map volume.
+func (r *configReloader) start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(filepath.Dir(r.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	r.watcher = watcher
+
+	go r.watch()
+
+	return nil
+}
+
+// Stop watching.
+func (r *configReloader) stop() {
+	select {
+	case <-r.quitCh:
+		return
+	default:
+		close(r.quitCh)
+	}
+
+	if r.watcher != nil {
+		r.watcher.Close()
+	}
+}
+
+func (r *configReloader) watch() {
+	var timer <-chan time.Time
+
+	for {
+		select {
+		case <-r.quitCh:
+			return
+		case e, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if e.Op&fsnotify.Chmod == e.Op {
+				continue
+			}
+			timer = time.After(configReloadDebounce)
+		case <-timer:
+			timer = nil
+			r.reloadFile(configReasonFile)
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.entry.LoggerEntry.Warn("Error occurs while watching boot config.", zap.Error(err))
+		}
+	}
+}
+
+// Handle reload request from admin router, names of changed middleware would be returned.
+func (r *configReloader) handle(ctx *gin.Context) {
+	changed, err := r.reloadFile(configReasonApi)
+	if err != nil {
+		resp := rkmid.GetErrorBuilder().New(http.StatusBadRequest, "Failed to reload boot config", err.Error())
+		ctx.JSON(resp.Code(), resp)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"changed": changed,
+	})
+}
+
+// Read config from file and reload.
+func (r *configReloader) reloadFile(reason string) ([]string, error) {
+	raw, err := os.ReadFile(r.path)
+	if err != nil {
+		r.entry.LoggerEntry.Warn("Failed to read boot config.", zap.Error(err))
+		return nil, err
+	}
+
+	return r.reload(raw, reason)
+}
+
+// Build middleware from config and swap with current one if changed, record as event.
+func (r *configReloader) reload(raw []byte, reason string) ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	prev := r.load()
+
+	state, err := r.parse(raw, prev)
+	changed, restart := make([]string, 0), make([]string, 0)
+	if err == nil {
+		changed, restart = state.diff(prev)
+		if len(changed) < 1 && len(restart) < 1 {
+			state.release()
+			return changed, nil
+		}
+	}
+
+	event := r.entry.EventEntry.Start(
+		"ReloadMiddleware",
+		rkquery.WithEntryName(r.entry.GetName()),
+		rkquery.WithEntryType(r.entry.GetType()))
+	event.AddPayloads(
+		zap.String("path", r.path),
+		zap.String("reason", reason))
+
+	// keep serving with previous middleware
+	if err != nil {
+		event.AddErr(err)
+		r.entry.EventEntry.FinishWithCond(event, false)
+		r.entry.LoggerEntry.Warn("Failed to reload boot config.", zap.Error(err))
+		return nil, err
+	}
+
+	commit(state, prev)
+	r.current.Store(state)
+	prev.replace(state)
+
+	event.AddPayloads(
+		zap.Strings("changed", changed),
+		zap.Strings("restartRequired", restart))
+	r.entry.EventEntry.Finish(event)
+
+	return changed, nil
+}
+
+// Decode config of entry and build middleware.
+func (r *configReloader) parse(raw []byte, prev *middlewareState) (state *middlewareState, err error) {
+	// invalid YAML would shutdown with panic
+	defer func() {
+		if recv := recover(); recv != nil {
+			state, err = nil, fmt.Errorf("%v", recv)
+		}
+	}()
+
+	config := &BootGin{}
+	rkentry.UnmarshalBootYAML(raw, config)
+
+	for i := range config.Gin {
+		element := config.Gin[i]
+		if element.Name != r.entry.GetName() {
+			continue
+		}
+
+		if !element.Enabled {
+			return nil, fmt.Errorf("gin entry %s is disabled in boot config, restart is required", element.Name)
+		}
+
+		if problems := validate(raw).of(i); element.Strict && len(problems) > 0 {
+			return nil, problems
+		}
+
+		return r.builder.build(element, prev)
+	}
+
+	return nil, fmt.Errorf("gin entry %s not found in boot config", r.entry.GetName())
+}
@@ -0,0 +1,283 @@
+//go:build !race
+// +build !race
+
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const reloadBootConfig = `
+---
+gin:
+ - name: greeter-reload
+   port: 8080
+   enabled: true
+   reload:
+     enabled: true
+     path: %s
+   middleware:
+     bodyLimit:
+       enabled: true
+       maxBytes: %d
+     rateLimit:
+       enabled: true
+`
+
+func newReloadableEntry(t *testing.T, maxBytes int) (*GinEntry, string) {
+	configPath := path.Join(t.TempDir(), "boot.yaml")
+	raw := []byte(fmt.Sprintf(reloadBootConfig, configPath, maxBytes))
+	assert.Nil(t, os.WriteFile(configPath, raw, 0644))
+
+	entry := RegisterGinEntryYAML(raw)["greeter-reload"].(*GinEntry)
+	entry.Router.POST("/ut-upload", func(ctx *gin.Context) {
+		_, ok := ctx.Get(middlewareChainKey)
+		assert.True(t, ok)
+		ctx.Status(http.StatusOK)
+	})
+
+	return entry, configPath
+}
+
+func upload(entry *GinEntry, body string) int {
+	w := httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ut-upload", strings.NewReader(body)))
+	return w.Code
+}
+
+func TestNewConfigReloader(t *testing.T) {
+	// without path
+	_, err := newConfigReloader(RegisterGinEntry(), "", nil, nil)
+	assert.NotNil(t, err)
+
+	// with relative path
+	reloader, err := newConfigReloader(RegisterGinEntry(), "boot.yaml", nil, &middlewareState{})
+	assert.Nil(t, err)
+	assert.True(t, filepath.IsAbs(reloader.path))
+	assert.NotNil(t, reloader.load())
+}
+
+func TestGinEntry_ReloadMiddleware(t *testing.T) {
+	// without reload enabled
+	_, err := RegisterGinEntry().ReloadMiddleware([]byte(""))
+	assert.NotNil(t, err)
+
+	entry, configPath := newReloadableEntry(t, 5)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload(entry, "123456"))
+
+	// unchanged
+	changed, err := entry.ReloadMiddleware([]byte(fmt.Sprintf(reloadBootConfig, configPath, 5)))
+	assert.Nil(t, err)
+	assert.Empty(t, changed)
+
+	// with changed limit, rate limiter would be kept
+	prev := entry.configReloader.load()
+	kept := false
+	for i := range prev.slots {
+		if prev.slots[i].name == "rateLimit" {
+			prev.mids[i] = func(*gin.Context) {
+				kept = true
+			}
+		}
+	}
+	changed, err = entry.ReloadMiddleware([]byte(fmt.Sprintf(reloadBootConfig, configPath, 10)))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bodyLimit"}, changed)
+	assert.Equal(t, http.StatusOK, upload(entry, "123456"))
+	assert.True(t, kept)
+
+	// with invalid YAML
+	prev = entry.configReloader.load()
+	_, err = entry.ReloadMiddleware([]byte("gin: ["))
+	assert.NotNil(t, err)
+	assert.Equal(t, prev, entry.configReloader.load())
+
+	// with missing entry
+	_, err = entry.ReloadMiddleware([]byte("gin: []"))
+	assert.NotNil(t, err)
+
+	// with invalid route, nothing would be applied
+	invalid := fmt.Sprintf(reloadBootConfig, configPath, 1) + `
+   routes:
+     - method: GET
+`
+	_, err = entry.ReloadMiddleware([]byte(invalid))
+	assert.NotNil(t, err)
+	assert.Equal(t, prev, entry.configReloader.load())
+	assert.Equal(t, http.StatusOK, upload(entry, "123456"))
+
+	// with static middleware changed
+	static := fmt.Sprintf(reloadBootConfig, configPath, 10) + `
+     prom:
+       enabled: true
+`
+	changed, err = entry.ReloadMiddleware([]byte(static))
+	assert.Nil(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestGinEntry_ReloadMiddleware_Rejected(t *testing.T) {
+	entry, configPath := newReloadableEntry(t, 5)
+	builder := rkmid.GetErrorBuilder()
+
+	// with error model and cache changed, but invalid route
+	invalid := fmt.Sprintf(reloadBootConfig, configPath, 5) + `
+     errorModel: amazon
+     cache:
+       enabled: true
+   routes:
+     - method: GET
+`
+	_, err := entry.ReloadMiddleware([]byte(invalid))
+	assert.NotNil(t, err)
+
+	// error builder and registry would be left unchanged
+	assert.Equal(t, builder, rkmid.GetErrorBuilder())
+	assert.Nil(t, entry.configReloader.builder.promRegistry.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rk",
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Total number of requests served by response cache, result: hit, miss",
+	}, []string{"entryName", "result"})))
+}
+
+func TestGinEntry_ReloadMiddleware_ReleaseRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	configPath := path.Join(t.TempDir(), "boot.yaml")
+	config := func(db int) []byte {
+		return []byte(fmt.Sprintf(`
+---
+gin:
+ - name: greeter-reload-redis
+   port: 8080
+   enabled: true
+   reload:
+     enabled: true
+     path: %s
+   middleware:
+     rateLimit:
+       enabled: true
+       algorithm: gcra
+       reqPerSec: 1000
+       store:
+         type: redis
+         redis:
+           addrs: ["%s"]
+           db: %d
+`, configPath, server.Addr(), db))
+	}
+
+	entry := RegisterGinEntryYAML(config(0))["greeter-reload-redis"].(*GinEntry)
+	started, finish := make(chan struct{}), make(chan struct{})
+	entry.Router.GET("/ut-block", func(ctx *gin.Context) {
+		close(started)
+		<-finish
+		ctx.Status(http.StatusOK)
+	})
+	entry.Router.GET("/ut-path", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	// requests would be limited with redis of db, instead of failing open
+	limitedWith := func(db int) bool {
+		server.DB(db).FlushDB()
+		return serve("/ut-path") == http.StatusOK && len(server.DB(db).Keys()) > 0
+	}
+
+	assert.True(t, limitedWith(0))
+
+	// client of previous chain would be kept until requests in-flight finished
+	done := make(chan struct{})
+	go func() {
+		serve("/ut-block")
+		close(done)
+	}()
+	<-started
+
+	changed, err := entry.ReloadMiddleware(config(1))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rateLimit"}, changed)
+	assert.True(t, limitedWith(1))
+	assert.Equal(t, 2, server.CurrentConnectionCount())
+
+	close(finish)
+	<-done
+	assert.Eventually(t, func() bool {
+		return server.CurrentConnectionCount() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// with rejected reload, client of current chain would be kept
+	invalid := string(config(2)) + `
+   routes:
+     - method: GET
+`
+	_, err = entry.ReloadMiddleware([]byte(invalid))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to build")
+	assert.True(t, limitedWith(1))
+}
+
+func TestConfigReloader_Watch(t *testing.T) {
+	entry, configPath := newReloadableEntry(t, 5)
+	reloader := entry.configReloader
+
+	assert.Nil(t, reloader.start())
+	defer reloader.stop()
+
+	assert.Nil(t, os.WriteFile(configPath, []byte(fmt.Sprintf(reloadBootConfig, configPath, 10)), 0644))
+	assert.Eventually(t, func() bool {
+		return upload(entry, "123456") == http.StatusOK
+	}, 3*time.Second, 100*time.Millisecond)
+
+	// stop twice
+	reloader.stop()
+}
+
+func TestConfigReloader_Handle(t *testing.T) {
+	entry, configPath := newReloadableEntry(t, 5)
+
+	router := gin.New()
+	router.POST(configReloadPath, entry.configReloader.handle)
+
+	// with changed config
+	assert.Nil(t, os.WriteFile(configPath, []byte(fmt.Sprintf(reloadBootConfig, configPath, 10)), 0644))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, configReloadPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "bodyLimit")
+
+	// with invalid config
+	assert.Nil(t, os.WriteFile(configPath, []byte("gin: ["), 0644))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, configReloadPath, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// with missing file
+	assert.Nil(t, os.Remove(configPath))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, configReloadPath, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/jwt"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/tracing"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+const (
+	// path of effective config endpoint in admin router
+	effectiveConfigPath = "/rk/v1/config"
+	// value of redacted secrets
+	redacted = "******"
+)
+
+// secret fields of middleware options defined in rk-entry keyed by type of struct and json key,
+// fields of options defined in rk-gin are marked with tag secret:"true" instead.
+var secretFields = map[reflect.Type][]string{
+	reflect.TypeOf(rkmidauth.BootConfig{}):                            {"basic", "apiKey"},
+	reflect.TypeOf(rkmidjwt.SymmetricConfig{}):                        {"token"},
+	reflect.TypeOf(rkmidjwt.AsymmetricConfig{}):                       {"privateKey"},
+	reflect.TypeOf(rkmidtrace.BootConfig{}.Exporter.Jaeger.Collector): {"password"},
+}
+
+// EffectiveConfig is resolved middleware chain and routes of GinEntry, secrets in options are redacted.
+type EffectiveConfig struct {
+	EntryName    string                 `json:"entryName"`
+	ErrorModel   string                 `json:"errorModel"`
+	IgnoreGlobal []string               `json:"ignoreGlobal"`
+	Middleware   []*EffectiveMiddleware `json:"middleware"`
+	Routes       []*EffectiveRoute      `json:"routes"`
+	AdminRoutes  []*EffectiveRoute      `json:"adminRoutes,omitempty"`
+}
+
+// EffectiveMiddleware is a middleware in chain, in the order requests go through.
+//
+// Options and Ignore are nil for middleware which are added in code instead of boot config.
+type EffectiveMiddleware struct {
+	Name       string                 `json:"name"`
+	Enabled    bool                   `json:"enabled"`
+	Reloadable bool                   `json:"reloadable"`
+	Ignore     []string               `json:"ignore,omitempty"`
+	Options    interface{}            `json:"options,omitempty"`
+	Routes     map[string]interface{} `json:"routes,omitempty"`
+}
+
+// EffectiveRoute is a route registered in router.
+type EffectiveRoute struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+// EffectiveConfig returns resolved middleware chain, global ignore list and routes of entry.
+//
+// Ignore list contains paths added from boot config only.
+func (entry *GinEntry) EffectiveConfig() *EffectiveConfig {
+	res := &EffectiveConfig{
+		EntryName:    entry.GetName(),
+		IgnoreGlobal: getIgnoreGlobal(),
+		Middleware:   make([]*EffectiveMiddleware, 0),
+		Routes:       toEffectiveRoutes(entry.Router),
+	}
+
+	if entry.IsAdminEnabled() {
+		res.AdminRoutes = toEffectiveRoutes(entry.AdminRouter)
+	}
+
+	// middleware from boot config are added in front of chain
+	added := 0
+	state := entry.middlewareState
+	if entry.configReloader != nil {
+		state = entry.configReloader.load()
+		added = len(state.slots)
+	} else if state != nil {
+		added = len(state.enabled())
+	}
+
+	if state != nil {
+		res.ErrorModel = state.errorModel
+		for i := range state.slots {
+			res.Middleware = append(res.Middleware, toEffectiveMiddleware(state.slots[i], state.mids[i] != nil))
+		}
+	}
+
+	// middleware added in code
+	if entry.Router != nil && len(entry.Router.Handlers) > added {
+		for _, handler := range entry.Router.Handlers[added:] {
+			res.Middleware = append(res.Middleware, &EffectiveMiddleware{
+				Name:    nameOfFunction(handler),
+				Enabled: true,
+			})
+		}
+	}
+
+	return res
+}
+
+// Handle effective config request from admin router.
+func (entry *GinEntry) handleEffectiveConfig(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, entry.EffectiveConfig())
+}
+
+// Convert middleware slot with redacted options.
+func toEffectiveMiddleware(slot *middlewareSlot, enabled bool) *EffectiveMiddleware {
+	res := &EffectiveMiddleware{
+		Name:       slot.name,
+		Enabled:    enabled,
+		Reloadable: !slot.static,
+	}
+
+	config := struct {
+		Global map[string]interface{} `json:"global"`
+		Routes map[string]interface{} `json:"routes"`
+	}{}
+	if err := json.Unmarshal([]byte(slot.config), &config); err != nil {
+		return res
+	}
+
+	if ignore, ok := config.Global["ignore"].([]interface{}); ok {
+		for i := range ignore {
+			if s, ok := ignore[i].(string); ok {
+				res.Ignore = append(res.Ignore, s)
+			}
+		}
+	}
+	delete(config.Global, "ignore")
+
+	typ := middlewareType(slot.name)
+	res.Options = redact(config.Global, typ)
+	if len(config.Routes) > 0 {
+		res.Routes = redact(config.Routes, reflect.MapOf(reflect.TypeOf(""), typ)).(map[string]interface{})
+	}
+
+	return res
+}
+
+// Type of options of middleware slot, nil would be returned if slot has no options.
+func middlewareType(name string) reflect.Type {
+	typ := reflect.TypeOf(BootGinElement{}.Middleware)
+	if field, ok := typ.FieldByNameFunc(func(s string) bool { return strings.EqualFold(s, name) }); ok {
+		return field.Type
+	}
+
+	return nil
+}
+
+// Replace non-empty values of secret fields in decoded JSON of type.
+func redact(value interface{}, typ reflect.Type) interface{} {
+	if typ == nil {
+		return value
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if typ.Kind() == reflect.Map {
+			for key := range v {
+				v[key] = redact(v[key], typ.Elem())
+			}
+			break
+		}
+		if typ.Kind() != reflect.Struct {
+			break
+		}
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			key := strings.Split(field.Tag.Get("json"), ",")[0]
+			if len(field.PkgPath) > 0 || key == "-" {
+				continue
+			}
+			// fields of embedded struct are inlined
+			if field.Anonymous && len(key) < 1 {
+				redact(v, field.Type)
+				continue
+			}
+			if len(key) < 1 {
+				key = field.Name
+			}
+
+			child, ok := v[key]
+			if !ok {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" || contains(secretFields[typ], key) {
+				v[key] = redactValue(child)
+			} else {
+				v[key] = redact(child, field.Type)
+			}
+		}
+	case []interface{}:
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+			for i := range v {
+				v[i] = redact(v[i], typ.Elem())
+			}
+		}
+	}
+
+	return value
+}
+
+// Redact secret, empty value would be kept so that it is visible whether secret is configured.
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if len(v) > 0 {
+			return redacted
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = redactValue(v[key])
+		}
+	}
+
+	return value
+}
+
+// Routes of router, nil router would return empty list.
+func toEffectiveRoutes(router *gin.Engine) []*EffectiveRoute {
+	res := make([]*EffectiveRoute, 0)
+	if router == nil {
+		return res
+	}
+
+	routes := router.Routes()
+	for i := range routes {
+		res = append(res, &EffectiveRoute{
+			Method:  routes[i].Method,
+			Path:    routes[i].Path,
+			Handler: routes[i].Handler,
+		})
+	}
+
+	return res
+}
+
+// Name of handler function, copied from gin.
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/jwt"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGinEntry_EffectiveConfig(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-effective
+   port: 8080
+   enabled: true
+   middleware:
+     errorModel: amazon
+     ignore: ["/ut-ignore-effective"]
+     auth:
+       enabled: true
+       basic: ["user:pass"]
+       apiKey: ["my-key"]
+     jwt:
+       enabled: true
+       ignore: ["/ut-login"]
+       symmetric:
+         algorithm: HS256
+         token: my-secret
+     gzip:
+       enabled: true
+       level: bestSpeed
+     rateLimit:
+       enabled: true
+       algorithm: gcra
+       keyBy: header:X-API-Key
+       tiers:
+         - name: gold
+           reqPerSec: 100
+           keys: ["my-gold-key"]
+     trace:
+       exporter:
+         jaeger:
+           collector:
+             username: my-user
+             password: my-collector-pass
+   routes:
+     - path: /ut-gzip
+       method: GET
+       middleware:
+         jwt:
+           enabled: true
+           symmetric:
+             algorithm: HS256
+             token: my-route-secret
+`
+	entry := RegisterGinEntryYAML([]byte(bootConfigStr))["greeter-effective"].(*GinEntry)
+	entry.AddMiddleware(func(ctx *gin.Context) {})
+	entry.Router.GET("/ut-gzip", func(ctx *gin.Context) {})
+
+	config := entry.EffectiveConfig()
+	assert.Equal(t, "greeter-effective", config.EntryName)
+	assert.Equal(t, "amazon", config.ErrorModel)
+	assert.Contains(t, config.IgnoreGlobal, "/ut-ignore-effective")
+	assert.Len(t, config.Routes, 1)
+	assert.Equal(t, "/ut-gzip", config.Routes[0].Path)
+	assert.Empty(t, config.AdminRoutes)
+
+	mids := make(map[string]*EffectiveMiddleware)
+	for i := range config.Middleware {
+		mids[config.Middleware[i].Name] = config.Middleware[i]
+	}
+
+	// middleware from boot config in order
+	assert.Equal(t, "logging", config.Middleware[0].Name)
+	assert.True(t, mids["panic"].Enabled)
+	assert.False(t, mids["prom"].Enabled)
+	assert.False(t, mids["prom"].Reloadable)
+	assert.True(t, mids["gzip"].Enabled)
+	assert.True(t, mids["gzip"].Reloadable)
+	assert.Equal(t, "bestSpeed", mids["gzip"].Options.(map[string]interface{})["level"])
+	assert.Equal(t, []string{"/ut-login"}, mids["jwt"].Ignore)
+
+	// secrets are redacted
+	raw, err := json.Marshal(config)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(raw), "user:pass")
+	assert.NotContains(t, string(raw), "my-key")
+	assert.NotContains(t, string(raw), "my-secret")
+	assert.NotContains(t, string(raw), "my-route-secret")
+	assert.NotContains(t, string(raw), "my-gold-key")
+	assert.NotContains(t, string(raw), "my-collector-pass")
+	assert.Contains(t, string(raw), "my-user")
+	tier := mids["rateLimit"].Options.(map[string]interface{})["tiers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "gold", tier["name"])
+	assert.Equal(t, []interface{}{redacted}, tier["keys"])
+	assert.Contains(t, string(raw), "HS256")
+	assert.Contains(t, mids["jwt"].Routes, "GET /ut-gzip")
+
+	// middleware added in code
+	last := config.Middleware[len(config.Middleware)-1]
+	assert.Contains(t, last.Name, "TestGinEntry_EffectiveConfig")
+	assert.True(t, last.Enabled)
+	assert.Nil(t, last.Options)
+
+	// handler
+	router := gin.New()
+	router.GET(effectiveConfigPath, entry.handleEffectiveConfig)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, effectiveConfigPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "greeter-effective")
+	assert.NotContains(t, w.Body.String(), "my-secret")
+}
+
+func TestGinEntry_EffectiveConfig_WithoutBootConfig(t *testing.T) {
+	entry := RegisterGinEntry(WithName("ut-effective"))
+	entry.AddMiddleware(func(ctx *gin.Context) {})
+
+	config := entry.EffectiveConfig()
+	assert.Empty(t, config.ErrorModel)
+	assert.Len(t, config.Middleware, 1)
+	assert.Empty(t, config.Routes)
+}
+
+func TestRedact(t *testing.T) {
+	type utCollector struct {
+		Password string `json:"password"`
+	}
+	type utOptions struct {
+		Basic     []string                `json:"basic" secret:"true"`
+		Token     string                  `json:"token" secret:"true"`
+		Algorithm string                  `json:"algorithm"`
+		Nested    []*utCollector          `json:"nested"`
+		Routes    map[string]*utCollector `json:"routes"`
+		Asym      rkmidjwt.AsymmetricConfig
+	}
+
+	value := map[string]interface{}{
+		"basic":     []interface{}{"user:pass"},
+		"token":     "",
+		"algorithm": "RS256",
+		"nested":    []interface{}{map[string]interface{}{"password": "pass"}},
+		"routes":    map[string]interface{}{"GET /": map[string]interface{}{"password": "pass"}},
+		"Asym":      map[string]interface{}{"privateKey": "key", "publicKey": "pub"},
+		"unknown":   "secret",
+	}
+
+	// secret tag and secret fields of rk-entry types are redacted, password without tag is kept
+	assert.Equal(t, map[string]interface{}{
+		"basic":     []interface{}{redacted},
+		"token":     "",
+		"algorithm": "RS256",
+		"nested":    []interface{}{map[string]interface{}{"password": "pass"}},
+		"routes":    map[string]interface{}{"GET /": map[string]interface{}{"password": "pass"}},
+		"Asym":      map[string]interface{}{"privateKey": redacted, "publicKey": "pub"},
+		"unknown":   "secret",
+	}, redact(value, reflect.TypeOf(&utOptions{})))
+
+	// without type
+	assert.Equal(t, "secret", redact("secret", nil))
+}
+
+func TestMiddlewareType(t *testing.T) {
+	assert.Equal(t, reflect.TypeOf(rkginlimit.BootConfig{}), middlewareType("rateLimit"))
+	assert.Equal(t, reflect.TypeOf(BootGzip{}), middlewareType("gzip"))
+	assert.Nil(t, middlewareType("panic"))
+}
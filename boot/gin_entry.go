@@ -9,14 +9,16 @@ package rkgin
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quic-go/quic-go/http3"
 	rkentry "github.com/rookie-ninja/rk-entry/v2/entry"
-	rkerror "github.com/rookie-ninja/rk-entry/v2/error"
 	rkmid "github.com/rookie-ninja/rk-entry/v2/middleware"
 	rkmidauth "github.com/rookie-ninja/rk-entry/v2/middleware/auth"
 	rkmidcors "github.com/rookie-ninja/rk-entry/v2/middleware/cors"
@@ -26,36 +28,47 @@ import (
 	rkmidmeta "github.com/rookie-ninja/rk-entry/v2/middleware/meta"
 	rkmidpanic "github.com/rookie-ninja/rk-entry/v2/middleware/panic"
 	rkmidprom "github.com/rookie-ninja/rk-entry/v2/middleware/prom"
-	rkmidlimit "github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
 	rkmidsec "github.com/rookie-ninja/rk-entry/v2/middleware/secure"
 	rkmidtimeout "github.com/rookie-ninja/rk-entry/v2/middleware/timeout"
 	rkmidtrace "github.com/rookie-ninja/rk-entry/v2/middleware/tracing"
 	"github.com/rookie-ninja/rk-gin/v2/middleware/auth"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/cors"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/csrf"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/gzip"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/jwt"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/log"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/meta"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/bodylimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/breaker"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/cache"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/concurrency"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/idempotency"
 	"github.com/rookie-ninja/rk-gin/v2/middleware/panic"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/prom"
 	"github.com/rookie-ninja/rk-gin/v2/middleware/ratelimit"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/secure"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/timeout"
-	"github.com/rookie-ninja/rk-gin/v2/middleware/tracing"
 	"github.com/rookie-ninja/rk-query"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
 	"net/http"
 	"path"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	// GinEntryType type of entry
 	GinEntryType = "GinEntry"
+
+	defaultDrainTimeout = 5 * time.Second
+	// protect against slowloris clients
+	defaultReadHeaderTimeout = 10 * time.Second
+
+	// ProtocolHttp1 serve HTTP/1.1
+	ProtocolHttp1 = "http1"
+	// ProtocolH2C serve HTTP/2 over cleartext TCP
+	ProtocolH2C = "h2c"
+	// ProtocolH2 serve HTTP/2 over TLS
+	ProtocolH2 = "h2"
+	// ProtocolH3 serve HTTP/3 over QUIC on the same port with UDP, TLS is required
+	ProtocolH3 = "h3"
 )
 
 // This must be declared in order to register registration function into rk context
@@ -71,38 +84,68 @@ type BootGin struct {
 
 type BootGinElement struct {
 	Enabled       bool                          `yaml:"enabled" json:"enabled"`
+	Strict        bool                          `yaml:"strict" json:"strict"`
 	Name          string                        `yaml:"name" json:"name"`
 	Port          uint64                        `yaml:"port" json:"port"`
+	Listen        []string                      `yaml:"listen" json:"listen"`
 	Description   string                        `yaml:"description" json:"description"`
+	Protocols     []string                      `yaml:"protocols" json:"protocols"`
 	SW            rkentry.BootSW                `yaml:"sw" json:"sw"`
 	Docs          rkentry.BootDocs              `yaml:"docs" json:"docs"`
 	CommonService rkentry.BootCommonService     `yaml:"commonService" json:"commonService"`
 	Prom          rkentry.BootProm              `yaml:"prom" json:"prom"`
 	CertEntry     string                        `yaml:"certEntry" json:"certEntry"`
+	TLS           BootTLS                       `yaml:"tls" json:"tls"`
 	LoggerEntry   string                        `yaml:"loggerEntry" json:"loggerEntry"`
 	EventEntry    string                        `yaml:"eventEntry" json:"eventEntry"`
 	Static        rkentry.BootStaticFileHandler `yaml:"static" json:"static"`
 	PProf         rkentry.BootPProf             `yaml:"pprof" json:"pprof"`
-	Middleware    struct {
-		Ignore     []string                `yaml:"ignore" json:"ignore"`
-		ErrorModel string                  `yaml:"errorModel" json:"errorModel"`
-		Logging    rkmidlog.BootConfig     `yaml:"logging" json:"logging"`
-		Prom       rkmidprom.BootConfig    `yaml:"prom" json:"prom"`
-		Auth       rkmidauth.BootConfig    `yaml:"auth" json:"auth"`
-		Cors       rkmidcors.BootConfig    `yaml:"cors" json:"cors"`
-		Meta       rkmidmeta.BootConfig    `yaml:"meta" json:"meta"`
-		Jwt        rkmidjwt.BootConfig     `yaml:"jwt" json:"jwt"`
-		Secure     rkmidsec.BootConfig     `yaml:"secure" json:"secure"`
-		RateLimit  rkmidlimit.BootConfig   `yaml:"rateLimit" json:"rateLimit"`
-		Csrf       rkmidcsrf.BootConfig    `yaml:"csrf" yaml:"csrf"`
-		Timeout    rkmidtimeout.BootConfig `yaml:"timeout" json:"timeout"`
-		Trace      rkmidtrace.BootConfig   `yaml:"trace" json:"trace"`
-		Gzip       struct {
-			Enabled bool     `yaml:"enabled" json:"enabled"`
-			Ignore  []string `yaml:"ignore" json:"ignore"`
-			Level   string   `yaml:"level" json:"level"`
-		} `yaml:"gzip" json:"gzip"`
+	Admin         struct {
+		Enabled         bool                 `yaml:"enabled" json:"enabled"`
+		Port            uint64               `yaml:"port" json:"port"`
+		CertEntry       string               `yaml:"certEntry" json:"certEntry"`
+		ApplyMiddleware bool                 `yaml:"applyMiddleware" json:"applyMiddleware"`
+		Auth            rkmidauth.BootConfig `yaml:"auth" json:"auth"`
+	} `yaml:"admin" json:"admin"`
+	Shutdown struct {
+		PreStopDelayMs      int `yaml:"preStopDelayMs" json:"preStopDelayMs"`
+		DrainTimeoutMs      int `yaml:"drainTimeoutMs" json:"drainTimeoutMs"`
+		ForceCloseTimeoutMs int `yaml:"forceCloseTimeoutMs" json:"forceCloseTimeoutMs"`
+	} `yaml:"shutdown" json:"shutdown"`
+	Server struct {
+		ReadHeaderTimeoutMs int `yaml:"readHeaderTimeoutMs" json:"readHeaderTimeoutMs"`
+		ReadTimeoutMs       int `yaml:"readTimeoutMs" json:"readTimeoutMs"`
+		WriteTimeoutMs      int `yaml:"writeTimeoutMs" json:"writeTimeoutMs"`
+		IdleTimeoutMs       int `yaml:"idleTimeoutMs" json:"idleTimeoutMs"`
+		MaxHeaderBytes      int `yaml:"maxHeaderBytes" json:"maxHeaderBytes"`
+	} `yaml:"server" json:"server"`
+	Middleware struct {
+		Ignore      []string                    `yaml:"ignore" json:"ignore"`
+		ErrorModel  string                      `yaml:"errorModel" json:"errorModel"`
+		Logging     rkmidlog.BootConfig         `yaml:"logging" json:"logging"`
+		Prom        rkmidprom.BootConfig        `yaml:"prom" json:"prom"`
+		Auth        rkmidauth.BootConfig        `yaml:"auth" json:"auth"`
+		Cors        rkmidcors.BootConfig        `yaml:"cors" json:"cors"`
+		Meta        rkmidmeta.BootConfig        `yaml:"meta" json:"meta"`
+		Jwt         rkmidjwt.BootConfig         `yaml:"jwt" json:"jwt"`
+		Secure      rkmidsec.BootConfig         `yaml:"secure" json:"secure"`
+		RateLimit   rkginlimit.BootConfig       `yaml:"rateLimit" json:"rateLimit"`
+		Csrf        rkmidcsrf.BootConfig        `yaml:"csrf" json:"csrf"`
+		Timeout     rkmidtimeout.BootConfig     `yaml:"timeout" json:"timeout"`
+		Trace       rkmidtrace.BootConfig       `yaml:"trace" json:"trace"`
+		Gzip        BootGzip                    `yaml:"gzip" json:"gzip"`
+		Breaker     rkginbreaker.BootConfig     `yaml:"breaker" json:"breaker"`
+		Concurrency rkginconcurrency.BootConfig `yaml:"concurrency" json:"concurrency"`
+		Cache       rkgincache.BootConfig       `yaml:"cache" json:"cache"`
+		Idempotency rkginidempotency.BootConfig `yaml:"idempotency" json:"idempotency"`
+		BodyLimit   rkginbodylimit.BootConfig   `yaml:"bodyLimit" json:"bodyLimit"`
 	} `yaml:"middleware" json:"middleware"`
+	Reload struct {
+		Enabled bool   `yaml:"enabled" json:"enabled"`
+		Path    string `yaml:"path" json:"path"`
+	} `yaml:"reload" json:"reload"`
+	Routes []*BootRoute `yaml:"routes" json:"routes"`
+	Proxy  BootProxy    `yaml:"proxy" json:"proxy"`
 }
 
 // GinEntry implements rkentry.Entry interface.
@@ -112,7 +155,14 @@ type GinEntry struct {
 	entryDescription   string                          `json:"-" yaml:"-"`
 	Router             *gin.Engine                     `json:"-" yaml:"-"`
 	Server             *http.Server                    `json:"-" yaml:"-"`
+	H3Server           *http3.Server                   `json:"-" yaml:"-"`
+	AdminRouter        *gin.Engine                     `json:"-" yaml:"-"`
+	AdminServer        *http.Server                    `json:"-" yaml:"-"`
+	AdminPort          uint64                          `json:"-" yaml:"-"`
+	AdminCertEntry     *rkentry.CertEntry              `json:"-" yaml:"-"`
+	Protocols          []string                        `json:"-" yaml:"-"`
 	Port               uint64                          `json:"-" yaml:"-"`
+	ListenAddrs        []string                        `json:"-" yaml:"-"`
 	LoggerEntry        *rkentry.LoggerEntry            `json:"-" yaml:"-"`
 	EventEntry         *rkentry.EventEntry             `json:"-" yaml:"-"`
 	SwEntry            *rkentry.SWEntry                `json:"-" yaml:"-"`
@@ -121,8 +171,33 @@ type GinEntry struct {
 	PromEntry          *rkentry.PromEntry              `json:"-" yaml:"-"`
 	StaticFileEntry    *rkentry.StaticFileHandlerEntry `json:"-" yaml:"-"`
 	CertEntry          *rkentry.CertEntry              `json:"-" yaml:"-"`
+	ClientAuth         tls.ClientAuthType              `json:"-" yaml:"-"`
+	ClientCAs          *x509.CertPool                  `json:"-" yaml:"-"`
+	TLSMinVersion      uint16                          `json:"-" yaml:"-"`
+	TLSCipherSuites    []uint16                        `json:"-" yaml:"-"`
+	CertReload         bool                            `json:"-" yaml:"-"`
+	CertPemPath        string                          `json:"-" yaml:"-"`
+	KeyPemPath         string                          `json:"-" yaml:"-"`
+	ProxyRules         []*BootProxyRule                `json:"-" yaml:"-"`
 	PProfEntry         *rkentry.PProfEntry             `json:"-" yaml:"-"`
+	PreStopDelay       time.Duration                   `json:"-" yaml:"-"`
+	DrainTimeout       time.Duration                   `json:"-" yaml:"-"`
+	ForceCloseTimeout  time.Duration                   `json:"-" yaml:"-"`
+	ReadHeaderTimeout  time.Duration                   `json:"-" yaml:"-"`
+	ReadTimeout        time.Duration                   `json:"-" yaml:"-"`
+	WriteTimeout       time.Duration                   `json:"-" yaml:"-"`
+	IdleTimeout        time.Duration                   `json:"-" yaml:"-"`
+	MaxHeaderBytes     int                             `json:"-" yaml:"-"`
 	bootstrapLogOnce   sync.Once                       `json:"-" yaml:"-"`
+	h3Conn             net.PacketConn                  `json:"-" yaml:"-"`
+	certReloader       *certReloader                   `json:"-" yaml:"-"`
+	configReloader     *configReloader                 `json:"-" yaml:"-"`
+	middlewareState    *middlewareState                `json:"-" yaml:"-"`
+	routeInfos         map[string]*RouteInfo           `json:"-" yaml:"-"`
+	openAPI            []byte                          `json:"-" yaml:"-"`
+	draining           int32                           `json:"-" yaml:"-"`
+	inflight           int32                           `json:"-" yaml:"-"`
+	h3Inflight         int32                           `json:"-" yaml:"-"`
 }
 
 // RegisterGinEntryYAML register gin entries with provided config file (Must YAML file).
@@ -149,6 +224,9 @@ func RegisterGinEntryYAML(raw []byte) map[string]rkentry.Entry {
 	// 1: Decode config map into boot config struct
 	config := &BootGin{}
 	rkentry.UnmarshalBootYAML(raw, config)
+	errs := validate(raw)
+	certs := &rkentry.BootCert{}
+	rkentry.UnmarshalBootYAML(raw, certs)
 
 	// 2: Init gin entries with boot config
 	for i := range config.Gin {
@@ -165,6 +243,16 @@ func RegisterGinEntryYAML(raw []byte) map[string]rkentry.Entry {
 			loggerEntry = rkentry.LoggerEntryStdout
 		}
 
+		// refuse to start with invalid config in strict mode, otherwise, warn with every problem
+		if problems := errs.of(i); len(problems) > 0 {
+			if element.Strict {
+				rkentry.ShutdownWithError(problems)
+			}
+			for j := range problems {
+				loggerEntry.Warn("Invalid boot config.", zap.String("entryName", name), zap.Error(problems[j]))
+			}
+		}
+
 		// event entry
 		eventEntry := rkentry.GlobalAppCtx.GetEventEntry(element.EventEntry)
 		if eventEntry == nil {
@@ -193,119 +281,103 @@ func RegisterGinEntryYAML(raw []byte) map[string]rkentry.Entry {
 		// Register pprof entry
 		pprofEntry := rkentry.RegisterPProfEntry(&element.PProf, rkentry.WithNamePProfEntry(element.Name))
 
-		inters := make([]gin.HandlerFunc, 0)
-
-		// add global path ignorance
-		rkmid.AddPathToIgnoreGlobal(element.Middleware.Ignore...)
-
-		// set error builder based on error builder
-		switch strings.ToLower(element.Middleware.ErrorModel) {
-		case "", "google":
-			rkmid.SetErrorBuilder(rkerror.NewErrorBuilderGoogle())
-		case "amazon":
-			rkmid.SetErrorBuilder(rkerror.NewErrorBuilderAMZN())
-		}
-
-		// logging middlewares
-		if element.Middleware.Logging.Enabled {
-			inters = append(inters, rkginlog.Middleware(
-				rkmidlog.ToOptions(&element.Middleware.Logging, element.Name, GinEntryType,
-					loggerEntry, eventEntry)...))
-		}
-
-		// Default interceptor should be placed after logging middleware, we should make sure interceptors never panic
-		// insert panic interceptor
-		inters = append(inters, rkginpanic.Middleware(
-			rkmidpanic.WithEntryNameAndType(element.Name, GinEntryType)))
-
-		// metrics middleware
-		if element.Middleware.Prom.Enabled {
-			inters = append(inters, rkginprom.Middleware(
-				rkmidprom.ToOptions(&element.Middleware.Prom, element.Name, GinEntryType,
-					promRegistry, rkmidprom.LabelerTypeHttp)...))
-		}
-
-		// tracing middleware
-		if element.Middleware.Trace.Enabled {
-			inters = append(inters, rkgintrace.Middleware(
-				rkmidtrace.ToOptions(&element.Middleware.Trace, element.Name, GinEntryType)...))
-		}
-
-		// cors middleware
-		if element.Middleware.Cors.Enabled {
-			inters = append(inters, rkgincors.Middleware(
-				rkmidcors.ToOptions(&element.Middleware.Cors, element.Name, GinEntryType)...))
-		}
-
-		// jwt middleware
-		if element.Middleware.Jwt.Enabled {
-			inters = append(inters, rkginjwt.Middleware(
-				rkmidjwt.ToOptions(&element.Middleware.Jwt, element.Name, GinEntryType)...))
-		}
-
-		// secure middleware
-		if element.Middleware.Secure.Enabled {
-			inters = append(inters, rkginsec.Middleware(
-				rkmidsec.ToOptions(&element.Middleware.Secure, element.Name, GinEntryType)...))
-		}
-
-		// csrf middleware
-		if element.Middleware.Csrf.Enabled {
-			inters = append(inters, rkgincsrf.Middleware(
-				rkmidcsrf.ToOptions(&element.Middleware.Csrf, element.Name, GinEntryType)...))
-		}
-
-		// gzip middleware
-		if element.Middleware.Gzip.Enabled {
-			opts := []rkgingzip.Option{
-				rkgingzip.WithEntryNameAndType(element.Name, GinEntryType),
-				rkgingzip.WithLevel(element.Middleware.Gzip.Level),
-				rkgingzip.WithPathToIgnore(element.Middleware.Gzip.Ignore...),
-			}
-
-			inters = append(inters, rkgingzip.Middleware(opts...))
+		// admin port for built-in routes
+		var adminPort uint64
+		if element.Admin.Enabled {
+			adminPort = element.Admin.Port
 		}
 
-		// meta middleware
-		if element.Middleware.Meta.Enabled {
-			inters = append(inters, rkginmeta.Middleware(
-				rkmidmeta.ToOptions(&element.Middleware.Meta, element.Name, GinEntryType)...))
+		// built-in paths would never be shed by concurrency middleware
+		builder := &middlewareBuilder{
+			entryName:     element.Name,
+			loggerEntry:   loggerEntry,
+			eventEntry:    eventEntry,
+			promRegistry:  promRegistry,
+			criticalPaths: builtinPaths(commonServiceEntry, promEntry, pprofEntry, swEntry, docsEntry),
 		}
 
-		// auth middlewares
-		if element.Middleware.Auth.Enabled {
-			inters = append(inters, rkginauth.Middleware(
-				rkmidauth.ToOptions(&element.Middleware.Auth, element.Name, GinEntryType)...))
+		middleware, err := builder.build(element, nil)
+		if err != nil {
+			rkentry.ShutdownWithError(err)
 		}
+		commit(middleware, nil)
 
-		// timeout middlewares
-		if element.Middleware.Timeout.Enabled {
-			inters = append(inters, rkgintout.Middleware(
-				rkmidtimeout.ToOptions(&element.Middleware.Timeout, element.Name, GinEntryType)...))
+		// mutual TLS and TLS versions
+		tlsOpts, err := element.TLS.toOptions()
+		if err != nil {
+			rkentry.ShutdownWithError(err)
 		}
-
-		// rate limit middleware
-		if element.Middleware.RateLimit.Enabled {
-			inters = append(inters, rkginlimit.Middleware(
-				rkmidlimit.ToOptions(&element.Middleware.RateLimit, element.Name, GinEntryType)...))
+		if element.TLS.Reload {
+			tlsOpts = append(tlsOpts, WithCertReload(certPemPaths(certs, element.CertEntry)))
 		}
 
-		entry := RegisterGinEntry(
+		entryOpts := []GinEntryOption{
 			WithLoggerEntry(loggerEntry),
 			WithEventEntry(eventEntry),
 			WithName(name),
 			WithDescription(element.Description),
 			WithPort(element.Port),
+			WithListenAddrs(element.Listen...),
+			WithAdmin(adminPort, rkentry.GlobalAppCtx.GetCertEntry(element.Admin.CertEntry)),
+			WithProtocols(element.Protocols...),
 			WithSwEntry(swEntry),
 			WithDocsEntry(docsEntry),
 			WithPromEntry(promEntry),
 			WithCommonServiceEntry(commonServiceEntry),
 			WithCertEntry(certEntry),
 			WithPProfEntry(pprofEntry),
-			WithStaticFileHandlerEntry(staticEntry))
+			WithStaticFileHandlerEntry(staticEntry),
+			WithShutdownTimeout(
+				time.Duration(element.Shutdown.PreStopDelayMs)*time.Millisecond,
+				time.Duration(element.Shutdown.DrainTimeoutMs)*time.Millisecond,
+				time.Duration(element.Shutdown.ForceCloseTimeoutMs)*time.Millisecond),
+			WithServerTimeouts(
+				time.Duration(element.Server.ReadHeaderTimeoutMs)*time.Millisecond,
+				time.Duration(element.Server.ReadTimeoutMs)*time.Millisecond,
+				time.Duration(element.Server.WriteTimeoutMs)*time.Millisecond,
+				time.Duration(element.Server.IdleTimeoutMs)*time.Millisecond),
+			WithMaxHeaderBytes(element.Server.MaxHeaderBytes),
+		}
+
+		// reverse proxy
+		if element.Proxy.Enabled {
+			entryOpts = append(entryOpts, WithProxyRules(element.Proxy.Rules...))
+		}
+
+		entry := RegisterGinEntry(append(entryOpts, tlsOpts...)...)
+		entry.middlewareState = middleware
+
+		// middleware could be reloaded from boot config file if enabled
+		inters := middleware.enabled()
+		if element.Reload.Enabled {
+			reloader, err := newConfigReloader(entry, element.Reload.Path, builder, middleware)
+			if err != nil {
+				rkentry.ShutdownWithError(err)
+			}
+			entry.configReloader = reloader
+			inters = reloader.handlers()
+		}
 
 		entry.AddMiddleware(inters...)
 
+		// admin middlewares, apply the same middlewares as public router if needed
+		if entry.IsAdminEnabled() {
+			adminInters := make([]gin.HandlerFunc, 0)
+			if element.Admin.ApplyMiddleware {
+				adminInters = append(adminInters, inters...)
+			} else {
+				adminInters = append(adminInters, rkginpanic.Middleware(
+					rkmidpanic.WithEntryNameAndType(element.Name, GinEntryType)))
+			}
+
+			if element.Admin.Auth.Enabled {
+				adminInters = append(adminInters, rkginauth.Middleware(
+					rkmidauth.ToOptions(&element.Admin.Auth, element.Name, GinEntryType)...))
+			}
+
+			entry.AddAdminMiddleware(adminInters...)
+		}
+
 		res[name] = entry
 	}
 
@@ -315,11 +387,13 @@ func RegisterGinEntryYAML(raw []byte) map[string]rkentry.Entry {
 // RegisterGinEntry register GinEntry with options.
 func RegisterGinEntry(opts ...GinEntryOption) *GinEntry {
 	entry := &GinEntry{
-		entryType:        GinEntryType,
-		entryDescription: "Internal RK entry which helps to bootstrap with Gin framework.",
-		LoggerEntry:      rkentry.NewLoggerEntryStdout(),
-		EventEntry:       rkentry.NewEventEntryStdout(),
-		Port:             80,
+		entryType:         GinEntryType,
+		entryDescription:  "Internal RK entry which helps to bootstrap with Gin framework.",
+		LoggerEntry:       rkentry.NewLoggerEntryStdout(),
+		EventEntry:        rkentry.NewEventEntryStdout(),
+		Port:              80,
+		DrainTimeout:      defaultDrainTimeout,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
 	}
 
 	for i := range opts {
@@ -335,11 +409,35 @@ func RegisterGinEntry(opts ...GinEntryOption) *GinEntry {
 		entry.Router = gin.New()
 	}
 
-	if entry.Port != 0 {
-		entry.Server = &http.Server{
-			Addr:    "0.0.0.0:" + strconv.FormatUint(entry.Port, 10),
-			Handler: entry.Router,
+	if entry.Port != 0 || len(entry.ListenAddrs) > 0 {
+		addr := ""
+		if entry.Port != 0 {
+			addr = "0.0.0.0:" + strconv.FormatUint(entry.Port, 10)
+		}
+		handler := entry.trackInflight(entry.Router)
+
+		// HTTP/3 server shares the same handler and would be advertised with Alt-Svc header from TCP server
+		if entry.Port != 0 && entry.IsProtocolEnabled(ProtocolH3) {
+			entry.H3Server = &http3.Server{
+				Addr:           addr,
+				Handler:        entry.trackH3Inflight(handler),
+				MaxHeaderBytes: entry.MaxHeaderBytes,
+			}
+			handler = entry.advertiseH3(handler)
+		}
+
+		// h2c handler would fall back to handler for HTTP/1.1 requests
+		if entry.IsProtocolEnabled(ProtocolH2C) {
+			handler = h2c.NewHandler(handler, &http2.Server{})
 		}
+
+		entry.Server = entry.newServer(addr, handler)
+	}
+
+	// built-in routes would be served by a dedicated router if admin port provided
+	if entry.AdminPort != 0 {
+		entry.AdminRouter = gin.New()
+		entry.AdminServer = entry.newServer("0.0.0.0:"+strconv.FormatUint(entry.AdminPort, 10), entry.AdminRouter)
 	}
 
 	// add entry name and entry type into loki syncer if enabled
@@ -351,6 +449,19 @@ func RegisterGinEntry(opts ...GinEntryOption) *GinEntry {
 	return entry
 }
 
+// Create http.Server with timeouts of entry.
+func (entry *GinEntry) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: entry.ReadHeaderTimeout,
+		ReadTimeout:       entry.ReadTimeout,
+		WriteTimeout:      entry.WriteTimeout,
+		IdleTimeout:       entry.IdleTimeout,
+		MaxHeaderBytes:    entry.MaxHeaderBytes,
+	}
+}
+
 // GetName Get entry name.
 func (entry *GinEntry) GetName() string {
 	return entry.entryName
@@ -370,13 +481,28 @@ func (entry *GinEntry) GetDescription() string {
 func (entry *GinEntry) Bootstrap(ctx context.Context) {
 	event, logger := entry.logBasicInfo("Bootstrap", ctx)
 
+	// built-in routes would be registered into admin router if enabled
+	router := entry.Router
+	if entry.IsAdminEnabled() {
+		router = entry.AdminRouter
+	}
+
+	// OpenAPI document of routes registered by user, served through swagger and docs entry
+	if entry.IsSwEnabled() || entry.IsDocsEnabled() {
+		if doc, err := entry.newOpenAPI(); err != nil {
+			logger.Warn("Failed to generate OpenAPI document.", zap.Error(err))
+		} else {
+			entry.openAPI = doc
+		}
+	}
+
 	// Is common service enabled?
 	if entry.IsCommonServiceEnabled() {
 		// Register common service path into Router.
-		entry.Router.GET(entry.CommonServiceEntry.ReadyPath, gin.WrapF(entry.CommonServiceEntry.Ready))
-		entry.Router.GET(entry.CommonServiceEntry.AlivePath, gin.WrapF(entry.CommonServiceEntry.Alive))
-		entry.Router.GET(entry.CommonServiceEntry.GcPath, gin.WrapF(entry.CommonServiceEntry.Gc))
-		entry.Router.GET(entry.CommonServiceEntry.InfoPath, gin.WrapF(entry.CommonServiceEntry.Info))
+		router.GET(entry.CommonServiceEntry.ReadyPath, gin.WrapF(entry.ready))
+		router.GET(entry.CommonServiceEntry.AlivePath, gin.WrapF(entry.CommonServiceEntry.Alive))
+		router.GET(entry.CommonServiceEntry.GcPath, gin.WrapF(entry.CommonServiceEntry.Gc))
+		router.GET(entry.CommonServiceEntry.InfoPath, gin.WrapF(entry.CommonServiceEntry.Info))
 
 		// Bootstrap common service entry.
 		entry.CommonServiceEntry.Bootstrap(ctx)
@@ -384,13 +510,15 @@ func (entry *GinEntry) Bootstrap(ctx context.Context) {
 
 	// Is swagger enabled?
 	if entry.IsSwEnabled() {
-		entry.Router.GET(path.Join(entry.SwEntry.Path, "*any"), gin.WrapF(entry.SwEntry.ConfigFileHandler()))
+		router.GET(path.Join(entry.SwEntry.Path, "*any"), gin.WrapF(entry.withOpenAPI(
+			entry.SwEntry.Path, "swagger-config.json", "urls", entry.SwEntry.ConfigFileHandler())))
 		entry.SwEntry.Bootstrap(ctx)
 	}
 
 	// Is docs enabled?
 	if entry.IsDocsEnabled() {
-		entry.Router.GET(path.Join(entry.DocsEntry.Path, "*any"), gin.WrapF(entry.DocsEntry.ConfigFileHandler()))
+		router.GET(path.Join(entry.DocsEntry.Path, "*any"), gin.WrapF(entry.withOpenAPI(
+			entry.DocsEntry.Path, "specs", "specs", entry.DocsEntry.ConfigFileHandler())))
 		entry.DocsEntry.Bootstrap(ctx)
 	}
 
@@ -400,16 +528,60 @@ func (entry *GinEntry) Bootstrap(ctx context.Context) {
 		entry.StaticFileEntry.Bootstrap(ctx)
 	}
 
+	// Register reverse proxy routes into Router, proxied requests would go through middlewares
+	for i := range entry.ProxyRules {
+		proxy, err := newProxy(entry.ProxyRules[i])
+		if err != nil {
+			rkentry.ShutdownWithError(err)
+		}
+		proxy.register(entry.Router)
+	}
+
 	// Is prometheus enabled?
 	if entry.IsPromEnabled() {
 		// Register prom path into Router.
-		entry.Router.GET(entry.PromEntry.Path, gin.WrapH(promhttp.HandlerFor(entry.PromEntry.Gatherer, promhttp.HandlerOpts{})))
+		router.GET(entry.PromEntry.Path, gin.WrapH(promhttp.HandlerFor(entry.PromEntry.Gatherer, promhttp.HandlerOpts{})))
 		entry.PromEntry.Bootstrap(ctx)
 	}
 
 	// Is pprof enabled?
 	if entry.IsPProfEnabled() {
-		pprof.Register(entry.Router, entry.PProfEntry.Path)
+		pprof.Register(router, entry.PProfEntry.Path)
+	}
+
+	// Reload certificate once files changed or SIGUSR1 received
+	if entry.CertReload && entry.IsTlsEnabled() {
+		reloader, err := newCertReloader(entry)
+		if err == nil {
+			err = reloader.start()
+		}
+
+		if err != nil {
+			logger.Warn("Failed to enable certificate reloading.", zap.Error(err))
+		} else {
+			entry.certReloader = reloader
+		}
+	}
+
+	// Reload middleware once boot config file changed or reload endpoint in admin router called
+	if entry.configReloader != nil {
+		if err := entry.configReloader.start(); err != nil {
+			logger.Warn("Failed to watch boot config.", zap.Error(err))
+		}
+
+		if entry.IsAdminEnabled() {
+			entry.AdminRouter.POST(configReloadPath, entry.configReloader.handle)
+		}
+	}
+
+	// Effective config of middleware and routes
+	if entry.IsAdminEnabled() {
+		entry.AdminRouter.GET(effectiveConfigPath, entry.handleEffectiveConfig)
+	}
+
+	// Start admin server
+	if entry.IsAdminEnabled() {
+		go entry.startAdminServer(event, logger)
 	}
 
 	// Start gin server
@@ -417,43 +589,76 @@ func (entry *GinEntry) Bootstrap(ctx context.Context) {
 
 	entry.bootstrapLogOnce.Do(func() {
 		// Print link and logging message
-		scheme := "http"
-		if entry.IsTlsEnabled() {
-			scheme = "https"
-		}
-
 		if entry.IsSwEnabled() {
-			entry.LoggerEntry.Info(fmt.Sprintf("SwaggerEntry: %s://localhost:%d%s", scheme, entry.Port, entry.SwEntry.Path))
+			entry.LoggerEntry.Info(fmt.Sprintf("SwaggerEntry: %s", entry.builtinLinks(entry.SwEntry.Path)))
 		}
 		if entry.IsDocsEnabled() {
-			entry.LoggerEntry.Info(fmt.Sprintf("DocsEntry: %s://localhost:%d%s", scheme, entry.Port, entry.DocsEntry.Path))
+			entry.LoggerEntry.Info(fmt.Sprintf("DocsEntry: %s", entry.builtinLinks(entry.DocsEntry.Path)))
 		}
 		if entry.IsPromEnabled() {
-			entry.LoggerEntry.Info(fmt.Sprintf("PromEntry: %s://localhost:%d%s", scheme, entry.Port, entry.PromEntry.Path))
+			entry.LoggerEntry.Info(fmt.Sprintf("PromEntry: %s", entry.builtinLinks(entry.PromEntry.Path)))
 		}
 		if entry.IsStaticFileHandlerEnabled() {
-			entry.LoggerEntry.Info(fmt.Sprintf("StaticFileHandlerEntry: %s://localhost:%d%s", scheme, entry.Port, entry.StaticFileEntry.Path))
+			entry.LoggerEntry.Info(fmt.Sprintf("StaticFileHandlerEntry: %s", entry.listenLinks(entry.StaticFileEntry.Path)))
 		}
 		if entry.IsCommonServiceEnabled() {
 			handlers := []string{
-				fmt.Sprintf("%s://localhost:%d%s", scheme, entry.Port, entry.CommonServiceEntry.ReadyPath),
-				fmt.Sprintf("%s://localhost:%d%s", scheme, entry.Port, entry.CommonServiceEntry.AlivePath),
-				fmt.Sprintf("%s://localhost:%d%s", scheme, entry.Port, entry.CommonServiceEntry.InfoPath),
+				entry.builtinLinks(entry.CommonServiceEntry.ReadyPath),
+				entry.builtinLinks(entry.CommonServiceEntry.AlivePath),
+				entry.builtinLinks(entry.CommonServiceEntry.InfoPath),
 			}
 
 			entry.LoggerEntry.Info(fmt.Sprintf("CommonSreviceEntry: %s", strings.Join(handlers, ", ")))
 		}
 		if entry.IsPProfEnabled() {
-			entry.LoggerEntry.Info(fmt.Sprintf("PProfEntry: %s://localhost:%d%s", scheme, entry.Port, entry.PProfEntry.Path))
+			entry.LoggerEntry.Info(fmt.Sprintf("PProfEntry: %s", entry.builtinLinks(entry.PProfEntry.Path)))
 		}
 		entry.EventEntry.Finish(event)
 	})
 }
 
 // Interrupt GinEntry.
+//
+// Server would be stopped before any other entries, so that in-flight requests could still reach them.
+// 1: Flip ready endpoint to failing and wait for PreStopDelay, so that load balancer could remove this instance.
+// 2: Drain in-flight requests within DrainTimeout.
+// 3: Force close connections and wait for remaining requests within ForceCloseTimeout.
+// 4: Stop admin server within DrainTimeout.
 func (entry *GinEntry) Interrupt(ctx context.Context) {
 	event, logger := entry.logBasicInfo("Interrupt", ctx)
 
+	if entry.Router != nil && entry.Server != nil {
+		drainDuration, aborted, err := entry.drain(ctx)
+		event.AddPayloads(
+			zap.Int64("drainDurationMs", drainDuration.Milliseconds()),
+			zap.Int("abortedRequests", aborted))
+
+		if err != nil {
+			event.AddErr(err)
+			logger.Warn("Error occurs while stopping gin-server.", event.ListPayloads()...)
+		}
+
+		if aborted > 0 {
+			logger.Warn(fmt.Sprintf("%d in-flight requests were aborted while stopping gin-server.", aborted))
+		}
+	}
+
+	// stop admin server after public server, so that probes and metrics are available while draining
+	if entry.IsAdminEnabled() {
+		if err := entry.stopAdminServer(ctx); err != nil {
+			event.AddErr(err)
+			logger.Warn("Error occurs while stopping admin server.", zap.Error(err))
+		}
+	}
+
+	if entry.certReloader != nil {
+		entry.certReloader.stop()
+	}
+
+	if entry.configReloader != nil {
+		entry.configReloader.stop()
+	}
+
 	if entry.IsStaticFileHandlerEnabled() {
 		// Interrupt entry
 		entry.StaticFileEntry.Interrupt(ctx)
@@ -482,16 +687,6 @@ func (entry *GinEntry) Interrupt(ctx context.Context) {
 		entry.PProfEntry.Interrupt(ctx)
 	}
 
-	if entry.Router != nil && entry.Server != nil {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-
-		if err := entry.Server.Shutdown(ctx); err != nil {
-			event.AddErr(err)
-			logger.Warn("Error occurs while stopping gin-server.", event.ListPayloads()...)
-		}
-	}
-
 	entry.EventEntry.Finish(event)
 
 	rkentry.GlobalAppCtx.RemoveEntry(entry)
@@ -522,6 +717,10 @@ func (entry *GinEntry) MarshalJSON() ([]byte, error) {
 		"type":                   entry.entryType,
 		"description":            entry.entryDescription,
 		"port":                   entry.Port,
+		"protocols":              entry.Protocols,
+		"listenAddrs":            entry.ListenAddrs,
+		"adminPort":              entry.AdminPort,
+		"proxyRules":             entry.ProxyRules,
 		"swEntry":                entry.SwEntry,
 		"docsEntry":              entry.DocsEntry,
 		"commonServiceEntry":     entry.CommonServiceEntry,
@@ -561,6 +760,18 @@ func (entry *GinEntry) AddMiddleware(mids ...gin.HandlerFunc) {
 	entry.Router.Use(mids...)
 }
 
+// ReloadMiddleware rebuild middleware from boot config, returns names of changed middleware.
+//
+// Middleware is reloadable only if reload is enabled in boot config, requests in-flight would finish with previous
+// middleware. Middleware whose config unchanged would be kept, and nothing would be applied if config is invalid.
+func (entry *GinEntry) ReloadMiddleware(raw []byte) ([]string, error) {
+	if entry.configReloader == nil {
+		return nil, errors.New("reload is not enabled")
+	}
+
+	return entry.configReloader.reload(raw, configReasonApi)
+}
+
 // IsSwEnabled Is swagger entry enabled?
 func (entry *GinEntry) IsSwEnabled() bool {
 	return entry.SwEntry != nil
@@ -591,6 +802,45 @@ func (entry *GinEntry) IsPProfEnabled() bool {
 	return entry.PProfEntry != nil
 }
 
+// AddAdminMiddleware Add middlewares into admin router.
+// This function should be called before Bootstrap() called.
+func (entry *GinEntry) AddAdminMiddleware(mids ...gin.HandlerFunc) {
+	if entry.IsAdminEnabled() {
+		entry.AdminRouter.Use(mids...)
+	}
+}
+
+// IsAdminEnabled Is admin listener enabled?
+func (entry *GinEntry) IsAdminEnabled() bool {
+	return entry.AdminRouter != nil && entry.AdminServer != nil
+}
+
+// IsAdminTlsEnabled Is TLS enabled for admin listener?
+func (entry *GinEntry) IsAdminTlsEnabled() bool {
+	return entry.AdminCertEntry != nil && entry.AdminCertEntry.Certificate != nil
+}
+
+// IsProtocolEnabled Is protocol enabled?
+// HTTP/1.1 and HTTP/2 over TLS are enabled if protocols were not provided.
+func (entry *GinEntry) IsProtocolEnabled(protocol string) bool {
+	if len(entry.Protocols) < 1 {
+		return protocol == ProtocolHttp1 || protocol == ProtocolH2
+	}
+
+	for i := range entry.Protocols {
+		if strings.ToLower(entry.Protocols[i]) == protocol {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsDraining Is server draining in-flight requests?
+func (entry *GinEntry) IsDraining() bool {
+	return atomic.LoadInt32(&entry.draining) == 1
+}
+
 // IsTlsEnabled Is TLS enabled?
 func (entry *GinEntry) IsTlsEnabled() bool {
 	return entry.CertEntry != nil && entry.CertEntry.Certificate != nil
@@ -621,6 +871,11 @@ func (entry *GinEntry) logBasicInfo(operation string, ctx context.Context) (rkqu
 	event.AddPayloads(
 		zap.Uint64("ginPort", entry.Port))
 
+	if len(entry.ListenAddrs) > 0 {
+		event.AddPayloads(
+			zap.Strings("listenAddrs", entry.ListenAddrs))
+	}
+
 	// add SwEntry info
 	if entry.IsSwEnabled() {
 		event.AddPayloads(
@@ -641,11 +896,23 @@ func (entry *GinEntry) logBasicInfo(operation string, ctx context.Context) (rkqu
 			zap.String("docsPath", entry.DocsEntry.Path))
 	}
 
+	// add admin info
+	if entry.IsAdminEnabled() {
+		event.AddPayloads(
+			zap.Bool("adminEnabled", true),
+			zap.Uint64("adminPort", entry.AdminPort))
+	}
+
 	// add PromEntry info
 	if entry.IsPromEnabled() {
+		promPort := entry.Port
+		if entry.IsAdminEnabled() {
+			promPort = entry.AdminPort
+		}
+
 		event.AddPayloads(
 			zap.Bool("promEnabled", true),
-			zap.Uint64("promPort", entry.Port),
+			zap.Uint64("promPort", promPort),
 			zap.String("promPath", entry.PromEntry.Path))
 	}
 
@@ -666,7 +933,15 @@ func (entry *GinEntry) logBasicInfo(operation string, ctx context.Context) (rkqu
 	// add tls info
 	if entry.IsTlsEnabled() {
 		event.AddPayloads(
-			zap.Bool("tlsEnabled", true))
+			zap.Bool("tlsEnabled", true),
+			zap.String("clientAuth", entry.ClientAuth.String()),
+			zap.Bool("certReload", entry.CertReload))
+	}
+
+	// add protocols info
+	if len(entry.Protocols) > 0 {
+		event.AddPayloads(
+			zap.Strings("protocols", entry.Protocols))
 	}
 
 	logger.Info(fmt.Sprintf("%s GinEntry", operation))
@@ -674,31 +949,323 @@ func (entry *GinEntry) logBasicInfo(operation string, ctx context.Context) (rkqu
 	return event, logger
 }
 
+// Count in-flight requests, so that we could tell how many of them were aborted while draining.
+func (entry *GinEntry) trackInflight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&entry.inflight, 1)
+		defer atomic.AddInt32(&entry.inflight, -1)
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Count in-flight requests of HTTP/3 server, which would be waited for while draining.
+func (entry *GinEntry) trackH3Inflight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&entry.h3Inflight, 1)
+		defer atomic.AddInt32(&entry.h3Inflight, -1)
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Ready handler which fails while server is draining, otherwise, delegate to CommonServiceEntry.
+func (entry *GinEntry) ready(w http.ResponseWriter, req *http.Request) {
+	if entry.IsDraining() {
+		resp := rkmid.GetErrorBuilder().New(http.StatusServiceUnavailable, "Server is shutting down")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.Code())
+		bytes, _ := json.Marshal(resp)
+		w.Write(bytes)
+		return
+	}
+
+	entry.CommonServiceEntry.Ready(w, req)
+}
+
+// Stop server gracefully and return time spent and number of requests aborted.
+func (entry *GinEntry) drain(ctx context.Context) (time.Duration, int, error) {
+	atomic.StoreInt32(&entry.draining, 1)
+
+	// wait for load balancer to notice that we are not ready anymore
+	if entry.PreStopDelay > 0 {
+		select {
+		case <-time.After(entry.PreStopDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	start := time.Now()
+
+	drainCtx, cancel := context.WithTimeout(ctx, entry.DrainTimeout)
+	defer cancel()
+
+	// HTTP/3 server is drained along with TCP server within the same timeout
+	h3Aborted := make(chan int, 1)
+	go func() {
+		h3Aborted <- entry.drainH3(drainCtx)
+	}()
+
+	err := entry.Server.Shutdown(drainCtx)
+	aborted := <-h3Aborted
+	if err != nil {
+		// requests still running after drain timeout would be cut off
+		aborted = int(atomic.LoadInt32(&entry.inflight))
+		if closeErr := entry.Server.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+
+	if aborted > 0 {
+		// give handlers a chance to observe closed connections and return
+		deadline := time.Now().Add(entry.ForceCloseTimeout)
+		for atomic.LoadInt32(&entry.inflight) > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	return time.Since(start), aborted, err
+}
+
+// Wait for in-flight requests of HTTP/3 server and close it, returns number of requests aborted.
+// HTTP/3 server does not support graceful shutdown yet, requests still running after ctx done would be cut off.
+func (entry *GinEntry) drainH3(ctx context.Context) int {
+	if entry.H3Server == nil {
+		return 0
+	}
+
+	for atomic.LoadInt32(&entry.h3Inflight) > 0 && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	aborted := int(atomic.LoadInt32(&entry.h3Inflight))
+	entry.H3Server.Close()
+	if entry.h3Conn != nil {
+		entry.h3Conn.Close()
+	}
+
+	return aborted
+}
+
 // Start server
 // We move the code here for testability
 func (entry *GinEntry) startServer(event rkquery.Event, logger *zap.Logger) {
 	if entry.Server != nil {
-		// If TLS was enabled, we need to load server certificate and key and start http server with ListenAndServeTLS()
+		listeners, err := entry.listen()
+		if err != nil {
+			logger.Error("Error occurs while creating gin-listener.", event.ListPayloads()...)
+			entry.bootstrapLogOnce.Do(func() {
+				entry.EventEntry.FinishWithCond(event, false)
+			})
+			rkentry.ShutdownWithError(err)
+			return
+		}
+
+		// If TLS was enabled, we need to load server certificate and key and start http server with ServeTLS()
 		if entry.IsTlsEnabled() {
-			entry.Server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{*entry.CertEntry.Certificate}}
+			entry.Server.TLSConfig = entry.newTLSConfig()
 
-			if err := entry.Server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-				logger.Error("Error occurs while serving gin-listener-tls.", event.ListPayloads()...)
-				entry.bootstrapLogOnce.Do(func() {
-					entry.EventEntry.FinishWithCond(event, false)
-				})
-				rkentry.ShutdownWithError(err)
+			// disable HTTP/2 which would be configured by http.Server automatically
+			if !entry.IsProtocolEnabled(ProtocolH2) {
+				entry.Server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
 			}
-		} else {
-			if err := entry.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				logger.Error("Error occurs while serving gin-listener.", event.ListPayloads()...)
-				entry.bootstrapLogOnce.Do(func() {
-					entry.EventEntry.FinishWithCond(event, false)
-				})
-				rkentry.ShutdownWithError(err)
+
+			if entry.H3Server != nil {
+				go entry.startH3Server(event, logger)
 			}
+		} else if entry.IsProtocolEnabled(ProtocolH2) || entry.IsProtocolEnabled(ProtocolH3) {
+			logger.Warn("TLS is not enabled, h2 and h3 protocols would be ignored.")
+		}
+
+		// all listeners share the same server, serve the last one in current goroutine
+		for i := range listeners[:len(listeners)-1] {
+			go entry.serve(listeners[i], event, logger)
+		}
+		entry.serve(listeners[len(listeners)-1], event, logger)
+	}
+}
+
+// Create listeners of port and listen addresses.
+func (entry *GinEntry) listen() ([]net.Listener, error) {
+	res := make([]net.Listener, 0)
+
+	addrs := make([]string, 0)
+	if len(entry.Server.Addr) > 0 {
+		addrs = append(addrs, entry.Server.Addr)
+	}
+	addrs = append(addrs, entry.ListenAddrs...)
+
+	for i := range addrs {
+		listeners, err := newListeners(addrs[i])
+		if err != nil {
+			for j := range res {
+				res[j].Close()
+			}
+			return nil, err
+		}
+
+		res = append(res, listeners...)
+	}
+
+	return res, nil
+}
+
+// Serve listener with TLS if enabled.
+func (entry *GinEntry) serve(ln net.Listener, event rkquery.Event, logger *zap.Logger) {
+	if entry.IsTlsEnabled() {
+		if err := entry.Server.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+			logger.Error("Error occurs while serving gin-listener-tls.", event.ListPayloads()...)
+			entry.bootstrapLogOnce.Do(func() {
+				entry.EventEntry.FinishWithCond(event, false)
+			})
+			rkentry.ShutdownWithError(err)
+		}
+	} else {
+		if err := entry.Server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error("Error occurs while serving gin-listener.", event.ListPayloads()...)
+			entry.bootstrapLogOnce.Do(func() {
+				entry.EventEntry.FinishWithCond(event, false)
+			})
+			rkentry.ShutdownWithError(err)
+		}
+	}
+}
+
+// List links of path on every listener, separated with comma.
+func (entry *GinEntry) listenLinks(p string) string {
+	scheme := "http"
+	if entry.IsTlsEnabled() {
+		scheme = "https"
+	}
+
+	res := make([]string, 0)
+
+	if entry.Port != 0 {
+		res = append(res, fmt.Sprintf("%s://localhost:%d%s", scheme, entry.Port, p))
+	}
+
+	for i := range entry.ListenAddrs {
+		res = append(res, listenURL(scheme, entry.ListenAddrs[i])+p)
+	}
+
+	return strings.Join(res, ", ")
+}
+
+// List links of built-in path, admin listener would be used if enabled.
+func (entry *GinEntry) builtinLinks(p string) string {
+	if !entry.IsAdminEnabled() {
+		return entry.listenLinks(p)
+	}
+
+	scheme := "http"
+	if entry.IsAdminTlsEnabled() {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://localhost:%d%s", scheme, entry.AdminPort, p)
+}
+
+// List paths of built-in routes, nil entry would be skipped.
+func builtinPaths(commonService *rkentry.CommonServiceEntry, prom *rkentry.PromEntry, pprof *rkentry.PProfEntry, sw *rkentry.SWEntry, docs *rkentry.DocsEntry) []string {
+	res := make([]string, 0)
+
+	if commonService != nil {
+		res = append(res, commonService.ReadyPath, commonService.AlivePath, commonService.GcPath, commonService.InfoPath)
+	}
+	if prom != nil {
+		res = append(res, prom.Path)
+	}
+	if pprof != nil {
+		res = append(res, pprof.Path)
+	}
+	if sw != nil {
+		res = append(res, sw.Path)
+	}
+	if docs != nil {
+		res = append(res, docs.Path)
+	}
+
+	return res
+}
+
+// Stop admin server within DrainTimeout, connections would be closed if timed out.
+func (entry *GinEntry) stopAdminServer(ctx context.Context) error {
+	stopCtx, cancel := context.WithTimeout(ctx, entry.DrainTimeout)
+	defer cancel()
+
+	if err := entry.AdminServer.Shutdown(stopCtx); err != nil {
+		entry.AdminServer.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Start admin server which serves built-in routes.
+func (entry *GinEntry) startAdminServer(event rkquery.Event, logger *zap.Logger) {
+	var err error
+	if entry.IsAdminTlsEnabled() {
+		entry.AdminServer.TLSConfig = &tls.Config{Certificates: []tls.Certificate{*entry.AdminCertEntry.Certificate}}
+		// admin server shares rotated certificate if served with the same CertEntry
+		if entry.certReloader != nil && entry.AdminCertEntry == entry.CertEntry {
+			entry.AdminServer.TLSConfig = &tls.Config{GetCertificate: entry.certReloader.GetCertificate}
 		}
+		err = entry.AdminServer.ListenAndServeTLS("", "")
+	} else {
+		err = entry.AdminServer.ListenAndServe()
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		logger.Error("Error occurs while serving gin-admin-listener.", event.ListPayloads()...)
+		entry.bootstrapLogOnce.Do(func() {
+			entry.EventEntry.FinishWithCond(event, false)
+		})
+		rkentry.ShutdownWithError(err)
+	}
+}
+
+// Start HTTP/3 server on UDP port with the same TLS config as TCP server.
+func (entry *GinEntry) startH3Server(event rkquery.Event, logger *zap.Logger) {
+	conn, err := net.ListenPacket("udp", entry.H3Server.Addr)
+	if err == nil {
+		entry.h3Conn = conn
+		entry.H3Server.TLSConfig = http3.ConfigureTLSConfig(entry.Server.TLSConfig.Clone())
+		err = entry.H3Server.Serve(conn)
+	}
+
+	if err != nil && err != http.ErrServerClosed && !entry.IsDraining() {
+		logger.Error("Error occurs while serving gin-listener-h3.", event.ListPayloads()...)
+		entry.bootstrapLogOnce.Do(func() {
+			entry.EventEntry.FinishWithCond(event, false)
+		})
+		rkentry.ShutdownWithError(err)
+	}
+}
+
+// ALPN protocols of TLS listener based on enabled protocols.
+func (entry *GinEntry) tlsNextProtos() []string {
+	res := make([]string, 0)
+
+	if entry.IsProtocolEnabled(ProtocolH2) {
+		res = append(res, "h2")
+	}
+
+	if entry.IsProtocolEnabled(ProtocolHttp1) || entry.IsProtocolEnabled(ProtocolH2C) {
+		res = append(res, "http/1.1")
 	}
+
+	return res
+}
+
+// Add Alt-Svc header to responses served by TCP listener so that clients could upgrade to HTTP/3.
+func (entry *GinEntry) advertiseH3(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if entry.IsTlsEnabled() && req.ProtoMajor < 3 {
+			entry.H3Server.SetQuicHeaders(w.Header())
+		}
+
+		next.ServeHTTP(w, req)
+	})
 }
 
 // ***************** Options *****************
@@ -765,6 +1332,76 @@ func WithPProfEntry(p *rkentry.PProfEntry) GinEntryOption {
 	}
 }
 
+// WithClientAuth provide client auth type and CA pool which verifies client certificates.
+func WithClientAuth(clientAuth tls.ClientAuthType, clientCAs *x509.CertPool) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.ClientAuth = clientAuth
+		entry.ClientCAs = clientCAs
+	}
+}
+
+// WithTLSMinVersion provide minimum TLS version, like tls.VersionTLS12.
+func WithTLSMinVersion(version uint16) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.TLSMinVersion = version
+	}
+}
+
+// WithTLSCipherSuites provide cipher suites of TLS 1.0-1.2, like tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+func WithTLSCipherSuites(suites ...uint16) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.TLSCipherSuites = append(entry.TLSCipherSuites, suites...)
+	}
+}
+
+// WithCertReload reload certificate of CertEntry from files once changed or SIGUSR1 received.
+func WithCertReload(certPemPath, keyPemPath string) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.CertReload = true
+		entry.CertPemPath = certPemPath
+		entry.KeyPemPath = keyPemPath
+	}
+}
+
+// WithProxyRules provide reverse proxy rules.
+func WithProxyRules(rules ...*BootProxyRule) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.ProxyRules = append(entry.ProxyRules, rules...)
+	}
+}
+
+// WithShutdownTimeout provide pre-stop delay, drain timeout and force close timeout used while Interrupt.
+// Drain timeout would not be overridden if zero value provided.
+func WithShutdownTimeout(preStopDelay, drainTimeout, forceCloseTimeout time.Duration) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.PreStopDelay = preStopDelay
+		entry.ForceCloseTimeout = forceCloseTimeout
+		if drainTimeout > 0 {
+			entry.DrainTimeout = drainTimeout
+		}
+	}
+}
+
+// WithServerTimeouts provide timeouts of http.Server, zero value means no timeout.
+// Read header timeout would not be overridden if zero value provided.
+func WithServerTimeouts(readHeaderTimeout, readTimeout, writeTimeout, idleTimeout time.Duration) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.ReadTimeout = readTimeout
+		entry.WriteTimeout = writeTimeout
+		entry.IdleTimeout = idleTimeout
+		if readHeaderTimeout > 0 {
+			entry.ReadHeaderTimeout = readHeaderTimeout
+		}
+	}
+}
+
+// WithMaxHeaderBytes provide max size of request headers, http.DefaultMaxHeaderBytes would be used if missing.
+func WithMaxHeaderBytes(maxHeaderBytes int) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.MaxHeaderBytes = maxHeaderBytes
+	}
+}
+
 // WithPort provide port.
 func WithPort(port uint64) GinEntryOption {
 	return func(entry *GinEntry) {
@@ -772,6 +1409,29 @@ func WithPort(port uint64) GinEntryOption {
 	}
 }
 
+// WithProtocols provide protocols to serve, options: http1, h2c, h2, h3.
+func WithProtocols(protocols ...string) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.Protocols = append(entry.Protocols, protocols...)
+	}
+}
+
+// WithAdmin provide port and optional rkentry.CertEntry of admin listener.
+// Prometheus, pprof, swagger, docs and common service routes would be served by admin listener.
+func WithAdmin(port uint64, certEntry *rkentry.CertEntry) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.AdminPort = port
+		entry.AdminCertEntry = certEntry
+	}
+}
+
+// WithListenAddrs provide extra listen addresses, options: host:port, unix:///path.sock, fd://3, systemd.
+func WithListenAddrs(addrs ...string) GinEntryOption {
+	return func(entry *GinEntry) {
+		entry.ListenAddrs = append(entry.ListenAddrs, addrs...)
+	}
+}
+
 // WithName provide name.
 func WithName(name string) GinEntryOption {
 	return func(entry *GinEntry) {
@@ -10,20 +10,22 @@ package rkgin
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
 	"crypto/tls"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
 	"github.com/gin-gonic/gin"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/rookie-ninja/rk-entry/v2/entry"
 	"github.com/rookie-ninja/rk-gin/v2/middleware/meta"
 	"github.com/stretchr/testify/assert"
-	"math/big"
+	"golang.org/x/net/http2"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -211,6 +213,435 @@ func TestGinEntry_Bootstrap(t *testing.T) {
 	entry.Interrupt(context.TODO())
 }
 
+func TestGinEntry_Interrupt_Drain(t *testing.T) {
+	// case 1: no in-flight requests
+	entry := RegisterGinEntry(
+		WithPort(8080),
+		WithCommonServiceEntry(rkentry.RegisterCommonServiceEntry(&rkentry.BootCommonService{
+			Enabled: true,
+		})),
+		WithShutdownTimeout(0, time.Second, 0))
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, false)
+
+	// ready endpoint should fail once draining
+	w := httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, entry.CommonServiceEntry.ReadyPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	duration, aborted, err := entry.drain(context.TODO())
+	assert.Nil(t, err)
+	assert.Zero(t, aborted)
+	assert.True(t, duration < time.Second)
+	assert.True(t, entry.IsDraining())
+
+	w = httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, entry.CommonServiceEntry.ReadyPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	rkentry.GlobalAppCtx.RemoveEntry(entry)
+
+	// case 2: in-flight request exceeds drain timeout
+	entry = RegisterGinEntry(
+		WithPort(8080),
+		WithShutdownTimeout(10*time.Millisecond, 100*time.Millisecond, 100*time.Millisecond))
+	release := make(chan struct{})
+	entry.Router.GET("/slow", func(ctx *gin.Context) {
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, false)
+
+	go http.Get("http://localhost:8080/slow")
+	time.Sleep(100 * time.Millisecond)
+
+	duration, aborted, err = entry.drain(context.TODO())
+	close(release)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, aborted)
+	assert.True(t, duration >= 100*time.Millisecond)
+
+	rkentry.GlobalAppCtx.RemoveEntry(entry)
+}
+
+func TestGinEntry_Interrupt_DrainH3(t *testing.T) {
+	certEntry := rkentry.RegisterCertEntry(&rkentry.BootCert{
+		Cert: []*rkentry.BootCertE{
+			{
+				Name: "ut-cert",
+			},
+		},
+	})[0]
+	certificate, _ := tls.X509KeyPair(generateCerts())
+	certEntry.Certificate = &certificate
+
+	entry := RegisterGinEntry(
+		WithPort(8080),
+		WithCertEntry(certEntry),
+		WithProtocols(ProtocolHttp1, ProtocolH3),
+		WithShutdownTimeout(0, time.Second, 100*time.Millisecond))
+	release := make(chan struct{})
+	entry.Router.GET("/slow", func(ctx *gin.Context) {
+		<-release
+		ctx.String(http.StatusOK, ctx.Request.Proto)
+	})
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, true)
+
+	h3Client := &http.Client{
+		Transport: &http3.RoundTripper{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	respCh := make(chan string)
+	go func() {
+		resp, err := h3Client.Get("https://localhost:8080/slow")
+		assert.Nil(t, err)
+		respCh <- readBody(resp)
+	}()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&entry.h3Inflight) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// case 1: in-flight request of HTTP/3 server finishes while draining
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	duration, aborted, err := entry.drain(context.TODO())
+	assert.Nil(t, err)
+	assert.Zero(t, aborted)
+	assert.True(t, duration >= 100*time.Millisecond)
+	assert.Equal(t, "HTTP/3.0", <-respCh)
+
+	rkentry.GlobalAppCtx.RemoveEntry(entry)
+
+	// case 2: in-flight request of HTTP/3 server exceeds drain timeout
+	entry = RegisterGinEntry(
+		WithPort(8080),
+		WithCertEntry(certEntry),
+		WithProtocols(ProtocolHttp1, ProtocolH3),
+		WithShutdownTimeout(0, 100*time.Millisecond, 100*time.Millisecond))
+	release = make(chan struct{})
+	entry.Router.GET("/slow", func(ctx *gin.Context) {
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, true)
+
+	// connection to previous server is not reusable
+	h3Client = &http.Client{
+		Transport: &http3.RoundTripper{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	go h3Client.Get("https://localhost:8080/slow")
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&entry.h3Inflight) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	duration, aborted, err = entry.drain(context.TODO())
+	close(release)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, aborted)
+	assert.True(t, duration >= 100*time.Millisecond)
+
+	rkentry.GlobalAppCtx.RemoveEntry(entry)
+}
+
+func TestGinEntry_Protocols(t *testing.T) {
+	// case 1: h2c
+	entry := RegisterGinEntry(
+		WithPort(8080),
+		WithProtocols(ProtocolHttp1, ProtocolH2C))
+	entry.Router.GET("/proto", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.Request.Proto)
+	})
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, false)
+
+	h2cClient := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+	resp, err := h2cClient.Get("http://localhost:8080/proto")
+	assert.Nil(t, err)
+	assert.Equal(t, "HTTP/2.0", readBody(resp))
+
+	// HTTP/1.1 still works
+	resp, err = http.Get("http://localhost:8080/proto")
+	assert.Nil(t, err)
+	assert.Equal(t, "HTTP/1.1", readBody(resp))
+
+	entry.Interrupt(context.TODO())
+
+	// case 2: h2 and h3 with TLS
+	certEntry := rkentry.RegisterCertEntry(&rkentry.BootCert{
+		Cert: []*rkentry.BootCertE{
+			{
+				Name: "ut-cert",
+			},
+		},
+	})[0]
+	certificate, _ := tls.X509KeyPair(generateCerts())
+	certEntry.Certificate = &certificate
+
+	entry = RegisterGinEntry(
+		WithPort(8080),
+		WithCertEntry(certEntry),
+		WithProtocols(ProtocolHttp1, ProtocolH2, ProtocolH3))
+	assert.NotNil(t, entry.H3Server)
+	entry.Router.GET("/proto", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.Request.Proto)
+	})
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, true)
+
+	h2Client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		},
+	}
+	resp, err = h2Client.Get("https://localhost:8080/proto")
+	assert.Nil(t, err)
+	assert.Contains(t, resp.Header.Get("Alt-Svc"), "h3=\":8080\"")
+	assert.Equal(t, "HTTP/2.0", readBody(resp))
+
+	h3Client := &http.Client{
+		Transport: &http3.RoundTripper{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	resp, err = h3Client.Get("https://localhost:8080/proto")
+	assert.Nil(t, err)
+	assert.Equal(t, "HTTP/3.0", readBody(resp))
+
+	entry.Interrupt(context.TODO())
+}
+
+func TestGinEntry_OpenAPIWithSwAndDocs(t *testing.T) {
+	entry := RegisterGinEntry(
+		WithName("ut-openapi-boot"),
+		WithPort(0),
+		WithSwEntry(rkentry.RegisterSWEntry(&rkentry.BootSW{
+			Enabled: true,
+		})),
+		WithDocsEntry(rkentry.RegisterDocsEntry(&rkentry.BootDocs{
+			Enabled: true,
+		})))
+	entry.AddRoute(http.MethodGet, "/v1/greeter", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	}, WithRouteSummary("Greeter"))
+
+	entry.Bootstrap(context.TODO())
+	defer entry.Interrupt(context.TODO())
+
+	// built-in routes are not documented
+	raw, err := entry.OpenAPI()
+	assert.Nil(t, err)
+	assert.Contains(t, string(raw), "/v1/greeter")
+	assert.NotContains(t, string(raw), entry.SwEntry.Path)
+
+	for _, p := range []string{path.Join(entry.SwEntry.Path, "swagger-config.json"), path.Join(entry.DocsEntry.Path, "specs")} {
+		w := httptest.NewRecorder()
+		entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "ut-openapi-boot-openapi.json")
+	}
+
+	w := httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path.Join(entry.SwEntry.Path, "ut-openapi-boot-openapi.json"), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Greeter")
+}
+
+func TestGinEntry_Admin(t *testing.T) {
+	entry := RegisterGinEntry(
+		WithPort(8080),
+		WithAdmin(8081, nil),
+		WithCommonServiceEntry(rkentry.RegisterCommonServiceEntry(&rkentry.BootCommonService{
+			Enabled: true,
+		})),
+		WithPromEntry(rkentry.RegisterPromEntry(&rkentry.BootProm{
+			Enabled: true,
+		})))
+	assert.True(t, entry.IsAdminEnabled())
+	assert.False(t, entry.IsAdminTlsEnabled())
+
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, false)
+	validateServerIsUp(t, 8081, false)
+
+	// built-in routes should be registered into admin router only
+	assert.Empty(t, entry.Router.Routes())
+	assert.NotEmpty(t, entry.AdminRouter.Routes())
+	assert.Equal(t, "http://localhost:8081/metrics", entry.builtinLinks(entry.PromEntry.Path))
+
+	resp, err := http.Get("http://localhost:8081" + entry.PromEntry.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://localhost:8080" + entry.PromEntry.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	entry.Interrupt(context.TODO())
+
+	// admin listener should be closed
+	_, err = http.Get("http://localhost:8081" + entry.PromEntry.Path)
+	assert.NotNil(t, err)
+}
+
+func TestGinEntry_AdminOnly(t *testing.T) {
+	entry := RegisterGinEntry(
+		WithPort(0),
+		WithAdmin(8081, nil),
+		WithCommonServiceEntry(rkentry.RegisterCommonServiceEntry(&rkentry.BootCommonService{
+			Enabled: true,
+		})))
+	assert.Nil(t, entry.Server)
+	assert.True(t, entry.IsAdminEnabled())
+
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8081, false)
+
+	entry.Interrupt(context.TODO())
+
+	// admin listener should be closed without public server
+	_, err := http.Get("http://localhost:8081" + entry.CommonServiceEntry.AlivePath)
+	assert.NotNil(t, err)
+}
+
+func TestRegisterGinEntryYAML_WithAdmin(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-admin
+   port: 8080
+   enabled: true
+   commonService:
+     enabled: true
+   admin:
+     enabled: true
+     port: 8081
+     auth:
+       enabled: true
+       basic:
+         - "user:pass"
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-admin"].(*GinEntry)
+	assert.True(t, entry.IsAdminEnabled())
+	assert.Equal(t, uint64(8081), entry.AdminPort)
+
+	entry.Bootstrap(context.TODO())
+	defer entry.Interrupt(context.TODO())
+
+	// admin router requires basic auth
+	w := httptest.NewRecorder()
+	entry.AdminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, entry.CommonServiceEntry.AlivePath, nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, entry.CommonServiceEntry.AlivePath, nil)
+	req.SetBasicAuth("user", "pass")
+	w = httptest.NewRecorder()
+	entry.AdminRouter.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// public router does not require basic auth, but has no built-in routes
+	w = httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, entry.CommonServiceEntry.AlivePath, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRegisterGinEntryYAML_WithBreaker(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-breaker
+   port: 8080
+   enabled: true
+   prom:
+     enabled: true
+   middleware:
+     breaker:
+       enabled: true
+       consecutiveFailures: 1
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-breaker"].(*GinEntry)
+	entry.Router.GET("/ut-fail", func(ctx *gin.Context) {
+		ctx.Status(http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ut-fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ut-fail", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// state exported into registry of entry
+	families, _ := entry.PromEntry.Gatherer.Gather()
+	found := false
+	for _, family := range families {
+		if family.GetName() == "rk_breaker_state" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRegisterGinEntryYAML_WithConcurrency(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-concurrency
+   port: 8080
+   enabled: true
+   commonService:
+     enabled: true
+   middleware:
+     concurrency:
+       enabled: true
+       maxLimit: 1
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-concurrency"].(*GinEntry)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	entry.Router.GET("/ut-block", func(ctx *gin.Context) {
+		close(started)
+		<-release
+	})
+	entry.Router.GET("/ut-other", func(ctx *gin.Context) {})
+
+	entry.Bootstrap(context.TODO())
+	defer entry.Interrupt(context.TODO())
+
+	// occupy the only slot
+	go entry.Router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ut-block", nil))
+	<-started
+	defer close(release)
+
+	w := httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ut-other", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// built-in paths are never shed
+	w = httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, entry.CommonServiceEntry.AlivePath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestGinEntry_startServer_TlsServerFail(t *testing.T) {
 	defer assertPanic(t)
 
@@ -267,32 +698,14 @@ func TestRegisterGinEntriesWithConfig(t *testing.T) {
 	assert.Nil(t, greeter3)
 }
 
-func generateCerts() ([]byte, []byte) {
-	// Create certs and return as []byte
-	ca := &x509.Certificate{
-		Subject: pkix.Name{
-			Organization: []string{"Fake cert."},
-		},
-		SerialNumber:          big.NewInt(42),
-		NotAfter:              time.Now().Add(2 * time.Hour),
-		IsCA:                  true,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
-		BasicConstraintsValid: true,
+func readBody(resp *http.Response) string {
+	if resp == nil {
+		return ""
 	}
+	defer resp.Body.Close()
 
-	// Create a Private Key
-	key, _ := rsa.GenerateKey(rand.Reader, 4096)
-
-	// Use CA Cert to sign a CSR and create a Public Cert
-	csr := &key.PublicKey
-	cert, _ := x509.CreateCertificate(rand.Reader, ca, ca, csr, key)
-
-	// Convert keys into pem.Block
-	c := &pem.Block{Type: "CERTIFICATE", Bytes: cert}
-	k := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
-
-	return pem.EncodeToMemory(c), pem.EncodeToMemory(k)
+	bytes, _ := io.ReadAll(resp.Body)
+	return string(bytes)
 }
 
 func validateServerIsUp(t *testing.T, port uint64, isTls bool) {
@@ -345,3 +758,396 @@ func TestMain(m *testing.M) {
 	gin.SetMode(gin.ReleaseMode)
 	os.Exit(m.Run())
 }
+
+func TestRegisterGinEntryYAML_WithDistributedRateLimit(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-ratelimit
+   port: 8080
+   enabled: true
+   middleware:
+     rateLimit:
+       enabled: true
+       algorithm: gcra
+       reqPerSec: 1
+       keyBy: header:X-API-Key
+       store:
+         type: memory
+         redis:
+           prefix: "ut:"
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-ratelimit"].(*GinEntry)
+	entry.Router.GET("/ut-path", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+		req.Header.Set("X-API-Key", key)
+		entry.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("ut-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("ut-key").Code)
+	assert.Equal(t, http.StatusOK, serve("ut-other").Code)
+}
+
+func TestRegisterGinEntryYAML_WithIdentityRateLimit(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-identity-ratelimit
+   port: 8080
+   enabled: true
+   middleware:
+     auth:
+       enabled: true
+       apiKey: ["ut-gold", "ut-bronze"]
+     rateLimit:
+       enabled: true
+       algorithm: gcra
+       reqPerSec: 1
+       keyBy: auth
+       tiers:
+         - name: gold
+           reqPerSec: 2
+           keys: ["apikey:caf57989915b792b"]
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-identity-ratelimit"].(*GinEntry)
+	entry.Router.GET("/ut-path", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	serve := func(key string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+		req.Header.Set("X-API-Key", key)
+		entry.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// gold tier
+	assert.Equal(t, http.StatusOK, serve("ut-gold"))
+	assert.Equal(t, http.StatusOK, serve("ut-gold"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("ut-gold"))
+
+	// default limit
+	assert.Equal(t, http.StatusOK, serve("ut-bronze"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("ut-bronze"))
+}
+
+func TestRegisterGinEntryYAML_WithCache(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-cache
+   port: 8080
+   enabled: true
+   prom:
+     enabled: true
+   middleware:
+     cache:
+       enabled: true
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-cache"].(*GinEntry)
+	calls := 0
+	entry.Router.GET("/ut-path", func(ctx *gin.Context) {
+		calls++
+		ctx.String(http.StatusOK, "ut-body")
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ut-path", nil))
+		assert.Equal(t, "ut-body", w.Body.String())
+	}
+	assert.Equal(t, 1, calls)
+
+	// hits and misses exported into registry of entry
+	families, _ := entry.PromEntry.Gatherer.Gather()
+	found := false
+	for _, family := range families {
+		if family.GetName() == "rk_cache_requests_total" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRegisterGinEntryYAML_WithIdempotency(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-idempotency
+   port: 8080
+   enabled: true
+   middleware:
+     idempotency:
+       enabled: true
+       header: X-Request-Key
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-idempotency"].(*GinEntry)
+	calls := 0
+	entry.Router.POST("/ut-path", func(ctx *gin.Context) {
+		calls++
+		ctx.String(http.StatusOK, "ut-body")
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/ut-path", nil)
+		req.Header.Set("X-Request-Key", "ut-key")
+		entry.Router.ServeHTTP(w, req)
+		assert.Equal(t, "ut-body", w.Body.String())
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestRegisterGinEntryYAML_WithBodyLimit(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-body-limit
+   port: 8080
+   enabled: true
+   server:
+     readHeaderTimeoutMs: 1000
+     readTimeoutMs: 2000
+     writeTimeoutMs: 3000
+     idleTimeoutMs: 4000
+     maxHeaderBytes: 1024
+   middleware:
+     bodyLimit:
+       enabled: true
+       maxBytes: 5
+   routes:
+     - path: /ut-upload
+       middleware:
+         bodyLimit:
+           enabled: true
+           maxBytes: 10
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-body-limit"].(*GinEntry)
+
+	// server timeouts
+	assert.Equal(t, time.Second, entry.Server.ReadHeaderTimeout)
+	assert.Equal(t, 2*time.Second, entry.Server.ReadTimeout)
+	assert.Equal(t, 3*time.Second, entry.Server.WriteTimeout)
+	assert.Equal(t, 4*time.Second, entry.Server.IdleTimeout)
+	assert.Equal(t, 1024, entry.Server.MaxHeaderBytes)
+
+	handler := func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	}
+	entry.Router.POST("/ut-path", handler)
+	entry.Router.POST("/ut-upload", handler)
+
+	serve := func(path, body string) int {
+		w := httptest.NewRecorder()
+		entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w.Code
+	}
+
+	// global limit
+	assert.Equal(t, http.StatusOK, serve("/ut-path", "12345"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve("/ut-path", "123456"))
+
+	// limit overridden in route
+	assert.Equal(t, http.StatusOK, serve("/ut-upload", "123456"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve("/ut-upload", "12345678901"))
+}
+
+func TestRegisterGinEntry_WithServerTimeouts(t *testing.T) {
+	// without options
+	entry := RegisterGinEntry(WithName("ut-timeouts"), WithPort(8080))
+	assert.Equal(t, defaultReadHeaderTimeout, entry.Server.ReadHeaderTimeout)
+	assert.Zero(t, entry.Server.ReadTimeout)
+
+	// with options
+	entry = RegisterGinEntry(
+		WithName("ut-timeouts"),
+		WithPort(8080),
+		WithAdmin(8081, nil),
+		WithServerTimeouts(0, time.Second, time.Second, time.Second),
+		WithMaxHeaderBytes(1024))
+	assert.Equal(t, defaultReadHeaderTimeout, entry.Server.ReadHeaderTimeout)
+	assert.Equal(t, time.Second, entry.Server.ReadTimeout)
+	assert.Equal(t, time.Second, entry.AdminServer.WriteTimeout)
+	assert.Equal(t, 1024, entry.AdminServer.MaxHeaderBytes)
+}
+
+func TestRegisterGinEntryYAML_WithGzipEncodings(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-gzip
+   port: 8080
+   enabled: true
+   middleware:
+     gzip:
+       enabled: true
+       level: bestSpeed
+       levels:
+         br: bestCompression
+       encodings: [zstd, gzip]
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-gzip"].(*GinEntry)
+	entry.Router.GET("/ut-path", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ut-body")
+	})
+
+	serve := func(acceptEncoding string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		entry.Router.ServeHTTP(w, req)
+		return w.Header().Get("Content-Encoding")
+	}
+
+	// br is not enabled
+	assert.Equal(t, "zstd", serve("br, zstd, gzip"))
+	assert.Equal(t, "gzip", serve("br, zstd;q=0.5, gzip"))
+	assert.Empty(t, serve("br"))
+}
+
+func TestRegisterGinEntryYAML_WithGzipMinLength(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-gzip-min-length
+   port: 8080
+   enabled: true
+   middleware:
+     gzip:
+       enabled: true
+       minLength: 10
+       excludedContentTypes: [text/csv]
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-gzip-min-length"].(*GinEntry)
+	entry.Router.GET("/ut-path/:body", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.Param("body"))
+	})
+	entry.Router.GET("/ut-csv", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "text/csv", []byte("ut-column-1,ut-column-2"))
+	})
+
+	serve := func(path string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		entry.Router.ServeHTTP(w, req)
+		return w.Header().Get("Content-Encoding")
+	}
+
+	assert.Empty(t, serve("/ut-path/short"))
+	assert.Equal(t, "gzip", serve("/ut-path/long-enough-body"))
+	assert.Empty(t, serve("/ut-csv"))
+}
+
+func TestRegisterGinEntryYAML_WithStreamingTimeout(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-streaming-timeout
+   port: 8080
+   enabled: true
+   middleware:
+     timeout:
+       enabled: true
+       timeoutMs: 50
+   routes:
+     - path: /ut-path
+       middleware:
+         timeout:
+           enabled: true
+           mode: streaming
+           timeoutMs: 50
+     - path: /ut-stream
+       middleware:
+         timeout:
+           enabled: true
+           mode: streaming
+           timeoutMs: 50
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-streaming-timeout"].(*GinEntry)
+
+	entry.Router.GET("/ut-path", func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+	})
+	entry.Router.GET("/ut-stream", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "chunk")
+		ctx.Writer.Flush()
+		time.Sleep(100 * time.Millisecond)
+	})
+	entry.Router.GET("/ut-buffered", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "chunk")
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// timed out before anything written
+	assert.Equal(t, http.StatusRequestTimeout, serve("/ut-path").Code)
+
+	// flushed response is kept after timeout
+	w := serve("/ut-stream")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "chunk", w.Body.String())
+
+	// buffered mode of entry
+	assert.Equal(t, http.StatusRequestTimeout, serve("/ut-buffered").Code)
+}
+
+func TestGinEntry_CertReload(t *testing.T) {
+	certEntry, certPath, keyPath := newReloadableCertEntry(t, "ut-cert-reload")
+	entry := RegisterGinEntry(
+		WithPort(8080),
+		WithCertEntry(certEntry),
+		WithCertReload(certPath, keyPath))
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, true)
+
+	assert.NotNil(t, entry.certReloader)
+	assert.NotNil(t, entry.Server.TLSConfig.GetCertificate)
+	assert.Empty(t, entry.Server.TLSConfig.Certificates)
+
+	entry.Interrupt(context.TODO())
+}
+
+func TestRegisterGinEntryYAML_WithCertReload(t *testing.T) {
+	bootConfigStr := `
+---
+cert:
+  - name: ut-cert-yaml
+    certPemPath: ut-cert.pem
+    keyPemPath: ut-key.pem
+gin:
+ - name: greeter-cert-reload
+   port: 8080
+   enabled: true
+   certEntry: ut-cert-yaml
+   tls:
+     reload: true
+`
+	entry := RegisterGinEntryYAML([]byte(bootConfigStr))["greeter-cert-reload"].(*GinEntry)
+	assert.True(t, entry.CertReload)
+	assert.Equal(t, "ut-cert.pem", entry.CertPemPath)
+	assert.Equal(t, "ut-key.pem", entry.KeyPemPath)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	listenSchemeUnix = "unix://"
+	listenSchemeFd   = "fd://"
+	listenSystemd    = "systemd"
+	// file descriptors passed by systemd starts from 3, see sd_listen_fds(3)
+	listenFdsStart = 3
+)
+
+// Create listeners with address.
+//
+// Supported formats:
+// 1: host:port, bind TCP address, ":port" would bind all interfaces.
+// 2: unix:///path/to/file.sock, bind unix domain socket, stale socket file would be removed.
+// 3: fd://3, use inherited file descriptor.
+// 4: systemd, use all inherited file descriptors described by LISTEN_FDS with socket activation.
+func newListeners(addr string) ([]net.Listener, error) {
+	switch {
+	case addr == listenSystemd:
+		return newSystemdListeners()
+	case strings.HasPrefix(addr, listenSchemeFd):
+		fd, err := strconv.Atoi(strings.TrimPrefix(addr, listenSchemeFd))
+		if err != nil {
+			return nil, fmt.Errorf("invalid file descriptor in %s", addr)
+		}
+
+		ln, err := newFdListener(fd)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.Listener{ln}, nil
+	case strings.HasPrefix(addr, listenSchemeUnix):
+		sockPath := strings.TrimPrefix(addr, listenSchemeUnix)
+
+		// remove stale socket file left by previous process
+		if info, err := os.Stat(sockPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(sockPath); err != nil {
+				return nil, err
+			}
+		}
+
+		ln, err := net.Listen("unix", sockPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.Listener{ln}, nil
+	default:
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.Listener{ln}, nil
+	}
+}
+
+// Create listeners with file descriptors passed by systemd.
+func newSystemdListeners() ([]net.Listener, error) {
+	if pid := os.Getenv("LISTEN_PID"); len(pid) > 0 && pid != strconv.Itoa(os.Getpid()) {
+		return nil, errors.New("LISTEN_PID does not match current process")
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, errors.New("no file descriptors found in LISTEN_FDS")
+	}
+
+	res := make([]net.Listener, 0, count)
+	for fd := listenFdsStart; fd < listenFdsStart+count; fd++ {
+		ln, err := newFdListener(fd)
+		if err != nil {
+			for i := range res {
+				res[i].Close()
+			}
+			return nil, err
+		}
+		res = append(res, ln)
+	}
+
+	return res, nil
+}
+
+// Create listener with inherited file descriptor.
+func newFdListener(fd int) (net.Listener, error) {
+	f := os.NewFile(uintptr(fd), listenSchemeFd+strconv.Itoa(fd))
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+// Build base URL of listen address for logging, 0.0.0.0 would be replaced with localhost.
+func listenURL(scheme, addr string) string {
+	switch {
+	case addr == listenSystemd, strings.HasPrefix(addr, listenSchemeFd):
+		return addr
+	case strings.HasPrefix(addr, listenSchemeUnix):
+		return scheme + "+" + addr
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	if len(host) < 1 || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, port))
+}
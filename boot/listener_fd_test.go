@@ -0,0 +1,33 @@
+//go:build !race && !windows
+// +build !race,!windows
+
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestNewListeners_WithFd(t *testing.T) {
+	// duplicate descriptor since it would be closed by newListeners, otherwise, descriptor reused by
+	// another connection would be closed again by finalizer of os.File
+	tcp, _ := net.Listen("tcp", "127.0.0.1:0")
+	f, _ := tcp.(*net.TCPListener).File()
+	fd, _ := syscall.Dup(int(f.Fd()))
+	f.Close()
+
+	listeners, err := newListeners(listenSchemeFd + strconv.Itoa(fd))
+	assert.Nil(t, err)
+	assert.Len(t, listeners, 1)
+	assert.Equal(t, tcp.Addr().String(), listeners[0].Addr().String())
+	listeners[0].Close()
+	tcp.Close()
+}
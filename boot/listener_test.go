@@ -0,0 +1,116 @@
+//go:build !race
+// +build !race
+
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestNewListeners(t *testing.T) {
+	// case 1: tcp
+	listeners, err := newListeners("127.0.0.1:0")
+	assert.Nil(t, err)
+	assert.Len(t, listeners, 1)
+	assert.Equal(t, "tcp", listeners[0].Addr().Network())
+	listeners[0].Close()
+
+	// case 2: unix socket with stale file
+	sockPath := path.Join(t.TempDir(), "ut.sock")
+	stale, err := net.Listen("unix", sockPath)
+	assert.Nil(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listeners, err = newListeners(listenSchemeUnix + sockPath)
+	assert.Nil(t, err)
+	assert.Len(t, listeners, 1)
+	assert.Equal(t, "unix", listeners[0].Addr().Network())
+	listeners[0].Close()
+
+	// case 3: invalid file descriptor
+	listeners, err = newListeners(listenSchemeFd + "invalid")
+	assert.NotNil(t, err)
+	assert.Nil(t, listeners)
+
+	// case 4: systemd without LISTEN_FDS
+	os.Unsetenv("LISTEN_FDS")
+	listeners, err = newListeners(listenSystemd)
+	assert.NotNil(t, err)
+	assert.Nil(t, listeners)
+
+	// case 5: systemd with LISTEN_PID of another process
+	os.Setenv("LISTEN_PID", "1")
+	os.Setenv("LISTEN_FDS", "1")
+	listeners, err = newListeners(listenSystemd)
+	assert.NotNil(t, err)
+	assert.Nil(t, listeners)
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+}
+
+func TestListenURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:8080", listenURL("http", "0.0.0.0:8080"))
+	assert.Equal(t, "http://localhost:8080", listenURL("http", ":8080"))
+	assert.Equal(t, "https://127.0.0.1:8080", listenURL("https", "127.0.0.1:8080"))
+	assert.Equal(t, "http+unix:///tmp/ut.sock", listenURL("http", "unix:///tmp/ut.sock"))
+	assert.Equal(t, "fd://3", listenURL("http", "fd://3"))
+	assert.Equal(t, "systemd", listenURL("http", "systemd"))
+}
+
+func TestGinEntry_ListenAddrs(t *testing.T) {
+	sockPath := path.Join(t.TempDir(), "ut.sock")
+
+	entry := RegisterGinEntry(
+		WithPort(8080),
+		WithListenAddrs("127.0.0.1:8081", listenSchemeUnix+sockPath))
+	entry.Router.GET("/ut", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ut")
+	})
+	entry.Bootstrap(context.TODO())
+	validateServerIsUp(t, 8080, false)
+
+	assert.Equal(t,
+		"http://localhost:8080/ut, http://127.0.0.1:8081/ut, http+unix://"+sockPath+"/ut",
+		entry.listenLinks("/ut"))
+
+	// tcp listener
+	resp, err := http.Get("http://127.0.0.1:8081/ut")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut", readBody(resp))
+
+	// unix socket listener
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", sockPath)
+			},
+		},
+	}
+	resp, err = unixClient.Get("http://unix/ut")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut", readBody(resp))
+
+	entry.Interrupt(context.TODO())
+
+	// all listeners should be closed
+	_, err = http.Get("http://127.0.0.1:8081/ut")
+	assert.NotNil(t, err)
+	_, err = os.Stat(sockPath)
+	assert.True(t, os.IsNotExist(err))
+
+	rkentry.GlobalAppCtx.RemoveEntry(entry)
+}
@@ -0,0 +1,515 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/log"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/panic"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/prom"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/tracing"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/log"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/panic"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/prom"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/tracing"
+	"strings"
+	"sync"
+)
+
+var (
+	// paths ignored globally which are added from boot config, since rkmid does not expose them
+	ignoreGlobal     = make([]string, 0)
+	ignoreGlobalLock sync.Mutex
+)
+
+// middlewareSlot is a position in middleware chain, nil middleware means disabled.
+type middlewareSlot struct {
+	// name of middleware in boot config
+	name string
+	// global and route level config, used to detect changes while reloading
+	config string
+	// middleware bound to entries like prometheus registry could not be reloaded
+	static bool
+	build  func() (gin.HandlerFunc, error)
+}
+
+// middlewareState is middleware chain built from boot config, with one middleware per slot.
+//
+// State is referenced by its owner and requests in-flight, resources of middleware like redis client
+// would be released once all references released.
+type middlewareState struct {
+	slots      []*middlewareSlot
+	mids       []gin.HandlerFunc
+	ignore     []string
+	errorModel string
+	// functions to release resources of middleware per slot
+	releases [][]func()
+	// whether middleware of slot is reused from previous state
+	reused []bool
+	refs   int
+	lock   sync.Mutex
+}
+
+// Acquire reference of state, false would be returned if state has been released.
+func (state *middlewareState) acquire() bool {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	if state.refs < 1 {
+		return false
+	}
+	state.refs++
+
+	return true
+}
+
+// Release reference of state, resources of middleware would be released once no one references it.
+func (state *middlewareState) release() {
+	state.lock.Lock()
+	state.refs--
+	released := state.refs == 0
+	state.lock.Unlock()
+
+	if released {
+		state.releaseSlots(nil)
+	}
+}
+
+// Release resources of middleware of slots, except the ones reused by next state.
+func (state *middlewareState) releaseSlots(next *middlewareState) {
+	for i := range state.releases {
+		if next != nil && next.reused[i] {
+			continue
+		}
+		for _, f := range state.releases[i] {
+			f()
+		}
+	}
+}
+
+// Replace state with next one, resources of middleware reused by next state would be owned by it.
+// Resources of the rest would be released once requests in-flight finished.
+func (state *middlewareState) replace(next *middlewareState) {
+	state.lock.Lock()
+	for i := range state.releases {
+		if next.reused[i] {
+			next.releases[i], state.releases[i] = state.releases[i], nil
+		}
+	}
+	state.lock.Unlock()
+
+	state.release()
+}
+
+// Middleware which are enabled.
+func (state *middlewareState) enabled() []gin.HandlerFunc {
+	res := make([]gin.HandlerFunc, 0)
+	for i := range state.mids {
+		if state.mids[i] != nil {
+			res = append(res, state.mids[i])
+		}
+	}
+
+	return res
+}
+
+// Compare with previous state, returns names of changed config and the ones which need restart to take effect.
+func (state *middlewareState) diff(prev *middlewareState) (changed, restart []string) {
+	changed, restart = make([]string, 0), make([]string, 0)
+
+	if state.errorModel != prev.errorModel {
+		changed = append(changed, "errorModel")
+	}
+
+	if added, removed := diffStrings(prev.ignore, state.ignore); len(removed) > 0 {
+		restart = append(restart, "ignore")
+	} else if len(added) > 0 {
+		changed = append(changed, "ignore")
+	}
+
+	for i := range state.slots {
+		if state.slots[i].config == prev.slots[i].config {
+			continue
+		}
+
+		if state.slots[i].static {
+			restart = append(restart, state.slots[i].name)
+		} else {
+			changed = append(changed, state.slots[i].name)
+		}
+	}
+
+	return changed, restart
+}
+
+// middlewareBuilder builds middleware of gin entry from boot config,
+// entries shared by middleware would be kept while reloading.
+type middlewareBuilder struct {
+	entryName     string
+	loggerEntry   *rkentry.LoggerEntry
+	eventEntry    *rkentry.EventEntry
+	promRegistry  *prometheus.Registry
+	criticalPaths []string
+	// functions to release resources of middleware being built
+	releases []func()
+	// registerer of middleware being built, registrations would be rolled back if build failed
+	registerer *stagedRegisterer
+}
+
+// Register function to release resources of middleware being built, like redis client.
+func (b *middlewareBuilder) onRelease(f func()) {
+	b.releases = append(b.releases, f)
+}
+
+// Build middleware from boot config, middleware of previous state would be reused if config unchanged,
+// so that states like rate limiter and cache are kept.
+//
+// Build has no side effect on running chain, state should be applied with commit once accepted.
+// Resources of middleware built and metrics registered would be released if any middleware failed to build.
+func (b *middlewareBuilder) build(element *BootGinElement, prev *middlewareState) (state *middlewareState, err error) {
+	b.releases = nil
+	b.registerer = &stagedRegisterer{Registerer: b.promRegistry}
+
+	state = &middlewareState{
+		slots:      b.slots(element),
+		ignore:     element.Middleware.Ignore,
+		errorModel: strings.ToLower(element.Middleware.ErrorModel),
+		refs:       1,
+	}
+	state.mids = make([]gin.HandlerFunc, len(state.slots))
+	state.releases = make([][]func(), len(state.slots))
+	state.reused = make([]bool, len(state.slots))
+
+	// middleware would shutdown with panic if config is invalid
+	defer func() {
+		if recv := recover(); recv != nil {
+			err = fmt.Errorf("%v", recv)
+		}
+		if err != nil {
+			// release resources of middleware built, including the one failed
+			state.releases = append(state.releases, b.releases)
+			state.releaseSlots(nil)
+			state = nil
+			b.registerer.rollback()
+		}
+		b.releases, b.registerer = nil, nil
+	}()
+
+	for i, slot := range state.slots {
+		if prev != nil && (slot.static || slot.config == prev.slots[i].config) {
+			state.mids[i] = prev.mids[i]
+			state.reused[i] = true
+			continue
+		}
+
+		state.mids[i], err = slot.build()
+		state.releases[i], b.releases = b.releases, nil
+		if err != nil {
+			return state, fmt.Errorf("failed to build %s middleware, %v", slot.name, err)
+		}
+	}
+
+	return state, nil
+}
+
+// Slots of middleware in order, number of slots is fixed regardless of config.
+func (b *middlewareBuilder) slots(element *BootGinElement) []*middlewareSlot {
+	configs := middlewareConfigs(element)
+	name := b.entryName
+
+	// middleware with route level overrides
+	routeSlot := func(key string, global func() gin.HandlerFunc, build func(*BootRoute) (gin.HandlerFunc, bool)) *middlewareSlot {
+		return &middlewareSlot{
+			name:   key,
+			config: configs[strings.ToLower(key)],
+			build: func() (gin.HandlerFunc, error) {
+				return withRoutes(element.Routes, global(), build)
+			},
+		}
+	}
+
+	return []*middlewareSlot{
+		// logging middlewares
+		{
+			name:   "logging",
+			config: configs["logging"],
+			build: func() (gin.HandlerFunc, error) {
+				if !element.Middleware.Logging.Enabled {
+					return nil, nil
+				}
+				return rkginlog.Middleware(
+					rkmidlog.ToOptions(&element.Middleware.Logging, name, GinEntryType,
+						b.loggerEntry, b.eventEntry)...), nil
+			},
+		},
+		// Default interceptor should be placed after logging middleware, we should make sure interceptors never panic
+		// insert panic interceptor
+		{
+			name: "panic",
+			build: func() (gin.HandlerFunc, error) {
+				return rkginpanic.Middleware(rkmidpanic.WithEntryNameAndType(name, GinEntryType)), nil
+			},
+		},
+		// metrics middleware, metrics are registered once
+		{
+			name:   "prom",
+			config: configs["prom"],
+			static: true,
+			build: func() (gin.HandlerFunc, error) {
+				if !element.Middleware.Prom.Enabled {
+					return nil, nil
+				}
+				return rkginprom.Middleware(
+					rkmidprom.ToOptions(&element.Middleware.Prom, name, GinEntryType,
+						b.promRegistry, rkmidprom.LabelerTypeHttp)...), nil
+			},
+		},
+		// tracing middleware, exporter is created once
+		{
+			name:   "trace",
+			config: configs["trace"],
+			static: true,
+			build: func() (gin.HandlerFunc, error) {
+				if !element.Middleware.Trace.Enabled {
+					return nil, nil
+				}
+				return rkgintrace.Middleware(
+					rkmidtrace.ToOptions(&element.Middleware.Trace, name, GinEntryType)...), nil
+			},
+		},
+		// concurrency middleware, shed requests as early as possible
+		routeSlot("concurrency", func() gin.HandlerFunc {
+			return newConcurrencyMiddleware(name, &element.Middleware.Concurrency, b.registerer, b.criticalPaths)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newConcurrencyMiddleware(name, route.Middleware.Concurrency, b.registerer, b.criticalPaths), route.Middleware.Concurrency != nil
+		}),
+		// body limit middleware, reject oversized requests before any body is consumed
+		routeSlot("bodyLimit", func() gin.HandlerFunc {
+			return newBodyLimitMiddleware(name, &element.Middleware.BodyLimit, b.registerer)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newBodyLimitMiddleware(name, route.Middleware.BodyLimit, b.registerer), route.Middleware.BodyLimit != nil
+		}),
+		// cors middleware
+		routeSlot("cors", func() gin.HandlerFunc {
+			return newCorsMiddleware(name, &element.Middleware.Cors)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newCorsMiddleware(name, route.Middleware.Cors), route.Middleware.Cors != nil
+		}),
+		// jwt middleware
+		routeSlot("jwt", func() gin.HandlerFunc {
+			return newJwtMiddleware(name, &element.Middleware.Jwt)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newJwtMiddleware(name, route.Middleware.Jwt), route.Middleware.Jwt != nil
+		}),
+		// secure middleware
+		routeSlot("secure", func() gin.HandlerFunc {
+			return newSecureMiddleware(name, &element.Middleware.Secure)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newSecureMiddleware(name, route.Middleware.Secure), route.Middleware.Secure != nil
+		}),
+		// csrf middleware
+		routeSlot("csrf", func() gin.HandlerFunc {
+			return newCsrfMiddleware(name, &element.Middleware.Csrf)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newCsrfMiddleware(name, route.Middleware.Csrf), route.Middleware.Csrf != nil
+		}),
+		// gzip middleware
+		routeSlot("gzip", func() gin.HandlerFunc {
+			return newGzipMiddleware(name, &element.Middleware.Gzip)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newGzipMiddleware(name, route.Middleware.Gzip), route.Middleware.Gzip != nil
+		}),
+		// meta middleware
+		routeSlot("meta", func() gin.HandlerFunc {
+			return newMetaMiddleware(name, &element.Middleware.Meta)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newMetaMiddleware(name, route.Middleware.Meta), route.Middleware.Meta != nil
+		}),
+		// auth middlewares
+		routeSlot("auth", func() gin.HandlerFunc {
+			return newAuthMiddleware(name, &element.Middleware.Auth)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newAuthMiddleware(name, route.Middleware.Auth), route.Middleware.Auth != nil
+		}),
+		// circuit breaker middleware, placed before timeout middleware so that timed out requests would be observed
+		routeSlot("breaker", func() gin.HandlerFunc {
+			return newBreakerMiddleware(name, &element.Middleware.Breaker, b.registerer)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newBreakerMiddleware(name, route.Middleware.Breaker, b.registerer), route.Middleware.Breaker != nil
+		}),
+		// timeout middlewares
+		routeSlot("timeout", func() gin.HandlerFunc {
+			return newEntryTimeoutMiddleware(name, &element.Middleware.Timeout)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newTimeoutMiddleware(name, route.Middleware.Timeout), route.Middleware.Timeout != nil
+		}),
+		// rate limit middleware
+		routeSlot("rateLimit", func() gin.HandlerFunc {
+			return newRateLimitMiddleware(name, &element.Middleware.RateLimit, b.onRelease)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newRateLimitMiddleware(name, route.Middleware.RateLimit, b.onRelease), route.Middleware.RateLimit != nil
+		}),
+		// idempotency middleware, keys are scoped by principal authenticated by auth middleware
+		routeSlot("idempotency", func() gin.HandlerFunc {
+			return newIdempotencyMiddleware(name, &element.Middleware.Idempotency)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newIdempotencyMiddleware(name, route.Middleware.Idempotency), route.Middleware.Idempotency != nil
+		}),
+		// cache middleware, placed last so that cached responses are still authorized and rate limited
+		routeSlot("cache", func() gin.HandlerFunc {
+			return newCacheMiddleware(name, &element.Middleware.Cache, b.registerer)
+		}, func(route *BootRoute) (gin.HandlerFunc, bool) {
+			return newCacheMiddleware(name, route.Middleware.Cache, b.registerer), route.Middleware.Cache != nil
+		}),
+	}
+}
+
+// Config of middleware keyed by lower case name, including route level overrides.
+func middlewareConfigs(element *BootGinElement) map[string]string {
+	global := make(map[string]json.RawMessage)
+	bytes, _ := json.Marshal(element.Middleware)
+	json.Unmarshal(bytes, &global)
+
+	overrides := make(map[string]map[string]json.RawMessage)
+	for i := range element.Routes {
+		key, err := element.Routes[i].key()
+		if err != nil {
+			continue
+		}
+
+		route := make(map[string]json.RawMessage)
+		bytes, _ := json.Marshal(element.Routes[i].Middleware)
+		json.Unmarshal(bytes, &route)
+
+		for name, config := range route {
+			name = strings.ToLower(name)
+			if string(config) == "null" {
+				continue
+			}
+			if _, ok := overrides[name]; !ok {
+				overrides[name] = make(map[string]json.RawMessage)
+			}
+			overrides[name][key] = config
+		}
+	}
+
+	res := make(map[string]string)
+	for name, config := range global {
+		name = strings.ToLower(name)
+		bytes, _ := json.Marshal(map[string]interface{}{
+			"global": config,
+			"routes": overrides[name],
+		})
+		res[name] = string(bytes)
+	}
+
+	return res
+}
+
+// Commit state built as current middleware chain, error builder and ignored paths would be applied.
+func commit(state *middlewareState, prev *middlewareState) {
+	if builder := newErrorBuilder(state.errorModel); builder != nil {
+		rkmid.SetErrorBuilder(builder)
+	}
+
+	applyIgnore(state, prev)
+}
+
+// Add global path ignorance, paths removed from config would be kept until restart.
+func applyIgnore(state *middlewareState, prev *middlewareState) {
+	ignore := state.ignore
+	if prev != nil {
+		ignore, _ = diffStrings(prev.ignore, state.ignore)
+	}
+
+	ignoreGlobalLock.Lock()
+	defer ignoreGlobalLock.Unlock()
+
+	for i := range ignore {
+		if len(ignore[i]) > 0 && !contains(ignoreGlobal, ignore[i]) {
+			ignoreGlobal = append(ignoreGlobal, ignore[i])
+		}
+	}
+
+	rkmid.AddPathToIgnoreGlobal(ignore...)
+}
+
+// Paths ignored globally which are added from boot config.
+func getIgnoreGlobal() []string {
+	ignoreGlobalLock.Lock()
+	defer ignoreGlobalLock.Unlock()
+
+	return append(make([]string, 0, len(ignoreGlobal)), ignoreGlobal...)
+}
+
+// stagedRegisterer registers collectors to registry, and keeps the ones registered,
+// so that registry would be left unchanged if build failed.
+type stagedRegisterer struct {
+	prometheus.Registerer
+	registered []prometheus.Collector
+}
+
+// Register collector and keep it, collectors already registered are owned by others and would not be kept.
+func (r *stagedRegisterer) Register(collector prometheus.Collector) error {
+	if err := r.Registerer.Register(collector); err != nil {
+		return err
+	}
+
+	r.registered = append(r.registered, collector)
+	return nil
+}
+
+// MustRegister collectors, panic if any failed.
+func (r *stagedRegisterer) MustRegister(collectors ...prometheus.Collector) {
+	for i := range collectors {
+		if err := r.Register(collectors[i]); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister collectors registered.
+func (r *stagedRegisterer) rollback() {
+	for i := range r.registered {
+		r.Registerer.Unregister(r.registered[i])
+	}
+	r.registered = nil
+}
+
+// Error builder of error model, nil would be returned if unknown.
+func newErrorBuilder(errorModel string) rkerror.ErrorBuilder {
+	switch strings.ToLower(errorModel) {
+	case "", "google":
+		return rkerror.NewErrorBuilderGoogle()
+	case "amazon":
+		return rkerror.NewErrorBuilderAMZN()
+	}
+
+	return nil
+}
+
+// Strings added to and removed from prev.
+func diffStrings(prev, next []string) (added, removed []string) {
+	for i := range next {
+		if !contains(prev, next[i]) {
+			added = append(added, next[i])
+		}
+	}
+
+	for i := range prev {
+		if !contains(next, prev[i]) {
+			removed = append(removed, prev[i])
+		}
+	}
+
+	return added, removed
+}
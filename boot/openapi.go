@@ -0,0 +1,521 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OpenAPIVersion is the version of generated OpenAPI document
+	OpenAPIVersion = "3.0.3"
+	// version of API if version of application is missing
+	defaultAPIVersion = "1.0.0"
+)
+
+var (
+	// methods supported by OpenAPI
+	openAPIMethods = []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+		http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace}
+	// path parameters of gin, like :id and *path
+	ginParamRegex    = regexp.MustCompile(`[:*]([^/]+)`)
+	invalidNameRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+	timeType         = reflect.TypeOf(time.Time{})
+	rawMessageType   = reflect.TypeOf(json.RawMessage{})
+)
+
+// RouteOption option for route registered with GinEntry.AddRoute.
+type RouteOption func(*RouteInfo)
+
+// RouteInfo is metadata of route, used to generate OpenAPI document.
+//
+// Fields of request with uri, header tags are documented as path and header parameters.
+// Fields with form tags are documented as query parameters for methods without body, like GET,
+// otherwise, request is documented as JSON body. Fields with binding:"required" are required.
+type RouteInfo struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	Request     reflect.Type
+	Responses   map[int]reflect.Type
+}
+
+// WithRouteSummary provide summary of route.
+func WithRouteSummary(summary string) RouteOption {
+	return func(info *RouteInfo) {
+		info.Summary = summary
+	}
+}
+
+// WithRouteDescription provide description of route.
+func WithRouteDescription(description string) RouteOption {
+	return func(info *RouteInfo) {
+		info.Description = description
+	}
+}
+
+// WithRouteTags provide tags of route, routes are grouped by tags in swagger UI.
+func WithRouteTags(tags ...string) RouteOption {
+	return func(info *RouteInfo) {
+		info.Tags = append(info.Tags, tags...)
+	}
+}
+
+// WithRouteDeprecated mark route as deprecated.
+func WithRouteDeprecated() RouteOption {
+	return func(info *RouteInfo) {
+		info.Deprecated = true
+	}
+}
+
+// WithRouteRequest provide request of route, an instance or nil pointer of the type is expected.
+func WithRouteRequest(req interface{}) RouteOption {
+	return func(info *RouteInfo) {
+		if req != nil {
+			info.Request = reflect.TypeOf(req)
+		}
+	}
+}
+
+// WithRouteResponse provide response of route with status code, nil body means response without body.
+func WithRouteResponse(code int, body interface{}) RouteOption {
+	return func(info *RouteInfo) {
+		if info.Responses == nil {
+			info.Responses = make(map[int]reflect.Type)
+		}
+
+		info.Responses[code] = nil
+		if body != nil {
+			info.Responses[code] = reflect.TypeOf(body)
+		}
+	}
+}
+
+// AddRoute register handler into Router with metadata of route, which would be documented in OpenAPI document.
+// This function should be called before Bootstrap() called.
+func (entry *GinEntry) AddRoute(method, relativePath string, handler gin.HandlerFunc, opts ...RouteOption) {
+	info := &RouteInfo{
+		Method: strings.ToUpper(method),
+		Path:   relativePath,
+	}
+
+	for i := range opts {
+		opts[i](info)
+	}
+
+	entry.Router.Handle(info.Method, info.Path, handler)
+
+	if entry.routeInfos == nil {
+		entry.routeInfos = make(map[string]*RouteInfo)
+	}
+	entry.routeInfos[routeKey(info.Method, info.Path)] = info
+}
+
+// OpenAPI returns OpenAPI document of routes in Router, with metadata of routes registered with AddRoute.
+//
+// Document is generated while bootstrapping, before built-in routes are registered,
+// it would be generated with current routes if entry is not bootstrapped yet.
+func (entry *GinEntry) OpenAPI() ([]byte, error) {
+	if entry.openAPI != nil {
+		return entry.openAPI, nil
+	}
+
+	return entry.newOpenAPI()
+}
+
+// Generate OpenAPI document of routes.
+func (entry *GinEntry) newOpenAPI() ([]byte, error) {
+	version := defaultAPIVersion
+	if appInfo := rkentry.GlobalAppCtx.GetAppInfoEntry(); appInfo != nil && len(appInfo.Version) > 0 {
+		version = appInfo.Version
+	}
+
+	g := &openAPIGenerator{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, route := range entry.Router.Routes() {
+		if !contains(openAPIMethods, route.Method) {
+			continue
+		}
+
+		info, ok := entry.routeInfos[routeKey(route.Method, route.Path)]
+		if !ok {
+			info = &RouteInfo{Method: route.Method, Path: route.Path}
+		}
+
+		p := ginParamRegex.ReplaceAllString(route.Path, "{$1}")
+		if _, ok := paths[p]; !ok {
+			paths[p] = make(map[string]interface{})
+		}
+		paths[p][strings.ToLower(route.Method)] = g.operation(info)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":       entry.GetName(),
+			"description": entry.GetDescription(),
+			"version":     version,
+		},
+		"paths": paths,
+	}
+
+	if len(g.schemas) > 0 {
+		doc["components"] = map[string]interface{}{
+			"schemas": g.schemas,
+		}
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Serve OpenAPI document along with spec files of SW or Docs entry, document would be appended to list of specs.
+//
+// configFile is the file listing specs in basePath, and listKey is the key of list.
+func (entry *GinEntry) withOpenAPI(basePath, configFile, listKey string, handler http.HandlerFunc) http.HandlerFunc {
+	name := entry.GetName() + "-openapi.json"
+	specPath := path.Join(basePath, name)
+	configPath := path.Join(basePath, configFile)
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if entry.openAPI == nil {
+			handler(writer, request)
+			return
+		}
+
+		switch strings.TrimSuffix(request.URL.Path, "/") {
+		case specPath:
+			writer.Header().Set("cache-control", "no-cache")
+			writer.Header().Set("Content-Type", "application/json")
+			writer.Write(entry.openAPI)
+		case configPath:
+			buf := &specWriter{header: make(http.Header), code: http.StatusOK}
+			handler(buf, request)
+
+			config := make(map[string]interface{})
+			if buf.code != http.StatusOK || json.Unmarshal(buf.body.Bytes(), &config) != nil {
+				buf.flush(writer)
+				return
+			}
+
+			specs, _ := config[listKey].([]interface{})
+			config[listKey] = append(specs, map[string]interface{}{
+				"name": name,
+				"url":  specPath,
+			})
+
+			raw, _ := json.Marshal(config)
+			buf.body.Reset()
+			buf.body.Write(raw)
+			buf.header.Del("Content-Length")
+			buf.flush(writer)
+		default:
+			handler(writer, request)
+		}
+	}
+}
+
+// specWriter buffers response of SW or Docs entry.
+type specWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *specWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *specWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *specWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+// Write buffered response.
+func (w *specWriter) flush(writer http.ResponseWriter) {
+	for k, v := range w.header {
+		writer.Header()[k] = v
+	}
+	writer.WriteHeader(w.code)
+	writer.Write(w.body.Bytes())
+}
+
+// openAPIGenerator generates operations of OpenAPI, named structs are shared as components.
+type openAPIGenerator struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+// Operation of route.
+func (g *openAPIGenerator) operation(info *RouteInfo) map[string]interface{} {
+	op := make(map[string]interface{})
+
+	if len(info.Summary) > 0 {
+		op["summary"] = info.Summary
+	}
+	if len(info.Description) > 0 {
+		op["description"] = info.Description
+	}
+	if len(info.Tags) > 0 {
+		op["tags"] = info.Tags
+	}
+	if info.Deprecated {
+		op["deprecated"] = true
+	}
+
+	// parameters of path, would be overridden by fields of request with uri tag
+	params := make([]map[string]interface{}, 0)
+	for _, match := range ginParamRegex.FindAllStringSubmatch(info.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	if info.Request != nil {
+		withBody := info.Method != http.MethodGet && info.Method != http.MethodHead &&
+			info.Method != http.MethodDelete && info.Method != http.MethodOptions
+
+		params = g.parameters(info.Request, withBody, params)
+
+		if withBody {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": g.schema(info.Request)},
+				},
+			}
+		}
+	}
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	responses := make(map[string]interface{})
+	for code, typ := range info.Responses {
+		resp := map[string]interface{}{
+			"description": http.StatusText(code),
+		}
+		if typ != nil {
+			resp["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schema(typ)},
+			}
+		}
+		responses[strconv.Itoa(code)] = resp
+	}
+	if len(responses) < 1 {
+		responses[strconv.Itoa(http.StatusOK)] = map[string]interface{}{
+			"description": http.StatusText(http.StatusOK),
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// Parameters from fields of request with uri, header and form tags.
+func (g *openAPIGenerator) parameters(typ reflect.Type, withBody bool, params []map[string]interface{}) []map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return params
+	}
+
+	tags, ins := []string{"uri", "header"}, []string{"path", "header"}
+	if !withBody {
+		tags, ins = append(tags, "form"), append(ins, "query")
+	}
+
+	for _, field := range structFields(typ) {
+		for j, tag := range tags {
+			in := ins[j]
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if len(name) < 1 || name == "-" {
+				continue
+			}
+
+			param := map[string]interface{}{
+				"name":     name,
+				"in":       in,
+				"required": in == "path" || isRequired(field),
+				"schema":   g.schema(field.Type),
+			}
+
+			// override parameters of path with the same name
+			replaced := false
+			for i := range params {
+				if params[i]["name"] == name && params[i]["in"] == in {
+					params[i], replaced = param, true
+				}
+			}
+			if !replaced {
+				params = append(params, param)
+			}
+		}
+	}
+
+	return params
+}
+
+// Schema of type, named struct would be referenced from components.
+func (g *openAPIGenerator) schema(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case typ == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case typ == rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if len(typ.Name()) < 1 {
+			return g.object(typ)
+		}
+
+		name, ok := g.names[typ]
+		if !ok {
+			name = g.name(typ)
+			g.names[typ] = name
+			// placeholder for recursive types
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(typ)
+		}
+
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(typ.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	}
+
+	return map[string]interface{}{}
+}
+
+// Schema of struct with fields named as json tags.
+func (g *openAPIGenerator) object(typ reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for _, field := range structFields(typ) {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) < 1 {
+			name = field.Name
+		}
+
+		props[name] = g.schema(field.Type)
+		if isRequired(field) {
+			required = append(required, name)
+		}
+	}
+
+	res := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		res["required"] = required
+	}
+
+	return res
+}
+
+// Name of struct in components, package name would be prefixed if name is taken by another type.
+func (g *openAPIGenerator) name(typ reflect.Type) string {
+	base := invalidNameRegex.ReplaceAllString(typ.Name(), "_")
+	if _, ok := g.schemas[base]; !ok {
+		return base
+	}
+
+	base = invalidNameRegex.ReplaceAllString(path.Base(typ.PkgPath()), "_") + "." + base
+	name := base
+	for i := 2; ; i++ {
+		if _, ok := g.schemas[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// Exported fields of struct, fields of embedded struct without json name are promoted as encoding/json does.
+func structFields(typ reflect.Type) []reflect.StructField {
+	res := make([]reflect.StructField, 0)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		if field.Anonymous && len(field.Tag.Get("json")) < 1 {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				res = append(res, structFields(embedded)...)
+				continue
+			}
+		}
+
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		res = append(res, field)
+	}
+
+	return res
+}
+
+// Field is required if binding tag of gin contains required.
+func isRequired(field reflect.StructField) bool {
+	return contains(strings.Split(field.Tag.Get("binding"), ","), "required")
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type utBase struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type utNode struct {
+	utBase
+	Name     string            `json:"name" binding:"required"`
+	Children []*utNode         `json:"children,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Secret   string            `json:"-"`
+	Raw      []byte            `json:"raw"`
+	internal string
+}
+
+type utGetReq struct {
+	ID      string `uri:"id"`
+	Verbose bool   `form:"verbose" binding:"required"`
+	Trace   string `header:"X-Trace-Id"`
+}
+
+func TestGinEntry_OpenAPI(t *testing.T) {
+	entry := RegisterGinEntry(WithName("ut-openapi"), WithDescription("ut"))
+
+	handler := func(ctx *gin.Context) {}
+	entry.AddRoute(http.MethodGet, "/v1/nodes/:id", handler,
+		WithRouteSummary("Get node"),
+		WithRouteDescription("Get node by id"),
+		WithRouteTags("node"),
+		WithRouteRequest(utGetReq{}),
+		WithRouteResponse(http.StatusOK, &utNode{}),
+		WithRouteResponse(http.StatusNotFound, nil))
+	entry.AddRoute("post", "/v1/nodes", handler,
+		WithRouteDeprecated(),
+		WithRouteRequest(&utNode{}),
+		WithRouteResponse(http.StatusCreated, []utNode{}))
+	// route without metadata
+	entry.Router.GET("/v1/files/*path", handler)
+
+	raw, err := entry.OpenAPI()
+	assert.Nil(t, err)
+
+	doc := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(raw, &doc))
+	assert.Equal(t, OpenAPIVersion, doc["openapi"])
+	assert.Equal(t, "ut-openapi", doc["info"].(map[string]interface{})["title"])
+	assert.Equal(t, "ut", doc["info"].(map[string]interface{})["description"])
+
+	paths := doc["paths"].(map[string]interface{})
+	assert.Len(t, paths, 3)
+
+	// operation with parameters
+	get := paths["/v1/nodes/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "Get node", get["summary"])
+	assert.Equal(t, "Get node by id", get["description"])
+	assert.Equal(t, []interface{}{"node"}, get["tags"])
+	assert.Nil(t, get["requestBody"])
+	params := get["parameters"].([]interface{})
+	assert.Len(t, params, 3)
+	assert.Equal(t, map[string]interface{}{
+		"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+	}, params[0])
+	assert.Equal(t, "verbose", params[1].(map[string]interface{})["name"])
+	assert.Equal(t, "query", params[1].(map[string]interface{})["in"])
+	assert.Equal(t, true, params[1].(map[string]interface{})["required"])
+	assert.Equal(t, "header", params[2].(map[string]interface{})["in"])
+	responses := get["responses"].(map[string]interface{})
+	assert.Contains(t, responses["200"], "content")
+	assert.NotContains(t, responses["404"], "content")
+	assert.Equal(t, "Not Found", responses["404"].(map[string]interface{})["description"])
+
+	// operation with body
+	post := paths["/v1/nodes"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Equal(t, true, post["deprecated"])
+	assert.Contains(t, string(raw), `"$ref": "#/components/schemas/utNode"`)
+	assert.NotNil(t, post["requestBody"])
+	assert.Contains(t, post["responses"], "201")
+
+	// operation without metadata
+	files := paths["/v1/files/{path}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Contains(t, files["responses"], "200")
+	assert.Len(t, files["parameters"], 1)
+
+	// components
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	node := schemas["utNode"].(map[string]interface{})
+	props := node["properties"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"name"}, node["required"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, props["createdAt"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "byte"}, props["raw"])
+	assert.Equal(t, "#/components/schemas/utNode", props["children"].(map[string]interface{})["items"].(map[string]interface{})["$ref"])
+	assert.NotContains(t, props, "Secret")
+	assert.NotContains(t, props, "internal")
+	assert.Contains(t, props, "labels")
+
+	// cached after bootstrap
+	entry.openAPI = []byte("{}")
+	raw, err = entry.OpenAPI()
+	assert.Nil(t, err)
+	assert.Equal(t, "{}", string(raw))
+}
+
+func TestOpenAPIGenerator_Name(t *testing.T) {
+	g := &openAPIGenerator{
+		schemas: map[string]interface{}{"Time": nil},
+		names:   make(map[reflect.Type]string),
+	}
+
+	assert.Equal(t, "utNode", g.name(reflect.TypeOf(utNode{})))
+	assert.Equal(t, "time.Time", g.name(reflect.TypeOf(time.Time{})))
+
+	g.schemas["time.Time"] = nil
+	assert.Equal(t, "time.Time2", g.name(reflect.TypeOf(time.Time{})))
+}
+
+func TestGinEntry_WithOpenAPI(t *testing.T) {
+	entry := RegisterGinEntry(WithName("ut-openapi-sw"))
+	handler := func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/sw/swagger-config.json":
+			writer.Header().Set("Content-Length", "100")
+			writer.Write([]byte(`{"urls":[{"name":"common","url":"/sw/common"}]}`))
+		default:
+			http.NotFound(writer, request)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/sw/*any", gin.WrapF(entry.withOpenAPI("/sw/", "swagger-config.json", "urls", handler)))
+	serve := func(p string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		return w
+	}
+
+	// without document
+	assert.Equal(t, `{"urls":[{"name":"common","url":"/sw/common"}]}`, serve("/sw/swagger-config.json").Body.String())
+	assert.Equal(t, http.StatusNotFound, serve("/sw/ut-openapi-sw-openapi.json").Code)
+
+	// with document
+	entry.openAPI = []byte(`{"openapi":"3.0.3"}`)
+	w := serve("/sw/swagger-config.json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"urls":[{"name":"common","url":"/sw/common"},{"name":"ut-openapi-sw-openapi.json","url":"/sw/ut-openapi-sw-openapi.json"}]}`, w.Body.String())
+
+	w = serve("/sw/ut-openapi-sw-openapi.json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"openapi":"3.0.3"}`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	assert.Equal(t, http.StatusNotFound, serve("/sw/other.json").Code)
+}
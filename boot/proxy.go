@@ -0,0 +1,320 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ProxyBalancerRoundRobin pick upstreams one after another
+	ProxyBalancerRoundRobin = "roundRobin"
+	// ProxyBalancerWeighted pick upstreams with smooth weighted round-robin
+	ProxyBalancerWeighted = "weighted"
+
+	proxyPathParam = "proxyPath"
+	// body of idempotent request would be buffered for retries if not larger than this
+	proxyRetryMaxBodyBytes = 1 << 20
+)
+
+// BootProxy bootstrap config of reverse proxy.
+type BootProxy struct {
+	Enabled bool             `yaml:"enabled" json:"enabled"`
+	Rules   []*BootProxyRule `yaml:"rules" json:"rules"`
+}
+
+// BootProxyRule bootstrap config of reverse proxy rule, requests with path prefix would be proxied to upstreams.
+type BootProxyRule struct {
+	Prefix    string `yaml:"prefix" json:"prefix"`
+	Rewrite   string `yaml:"rewrite" json:"rewrite"`
+	Balancer  string `yaml:"balancer" json:"balancer"`
+	TimeoutMs int    `yaml:"timeoutMs" json:"timeoutMs"`
+	Retry     int    `yaml:"retry" json:"retry"`
+	Upstreams []struct {
+		Url    string `yaml:"url" json:"url"`
+		Weight int    `yaml:"weight" json:"weight"`
+	} `yaml:"upstreams" json:"upstreams"`
+	Headers struct {
+		Add    map[string]string `yaml:"add" json:"add"`
+		Remove []string          `yaml:"remove" json:"remove"`
+	} `yaml:"headers" json:"headers"`
+}
+
+// Upstream of reverse proxy.
+type proxyUpstream struct {
+	url     *url.URL
+	weight  int
+	current int
+}
+
+// Reverse proxy of one rule.
+//
+// Upstream is picked in transport for every attempt, so that retries would go to next upstream.
+type proxy struct {
+	rule      *BootProxyRule
+	upstreams []*proxyUpstream
+	timeout   time.Duration
+	reverse   *httputil.ReverseProxy
+	transport http.RoundTripper
+	next      int
+	lock      sync.Mutex
+}
+
+// Create a new proxy with rule.
+func newProxy(rule *BootProxyRule) (*proxy, error) {
+	if !strings.HasPrefix(rule.Prefix, "/") || rule.Prefix == "/" {
+		return nil, fmt.Errorf("invalid prefix of proxy rule %s, should start with / and not be /", rule.Prefix)
+	}
+
+	if len(rule.Upstreams) < 1 {
+		return nil, fmt.Errorf("upstreams of proxy rule %s are required", rule.Prefix)
+	}
+
+	switch rule.Balancer {
+	case "", ProxyBalancerRoundRobin, ProxyBalancerWeighted:
+	default:
+		return nil, fmt.Errorf("invalid balancer %s, options: [%s, %s]",
+			rule.Balancer, ProxyBalancerRoundRobin, ProxyBalancerWeighted)
+	}
+
+	res := &proxy{
+		rule:      rule,
+		timeout:   time.Duration(rule.TimeoutMs) * time.Millisecond,
+		transport: http.DefaultTransport,
+	}
+
+	for i := range rule.Upstreams {
+		u, err := url.Parse(rule.Upstreams[i].Url)
+		if err != nil {
+			return nil, err
+		}
+		if len(u.Scheme) < 1 || len(u.Host) < 1 {
+			return nil, fmt.Errorf("invalid upstream %s, scheme and host are required", rule.Upstreams[i].Url)
+		}
+
+		weight := rule.Upstreams[i].Weight
+		if weight < 1 {
+			weight = 1
+		}
+
+		res.upstreams = append(res.upstreams, &proxyUpstream{url: u, weight: weight})
+	}
+
+	res.reverse = &httputil.ReverseProxy{
+		Director:     res.direct,
+		Transport:    res,
+		ErrorHandler: res.handleError,
+	}
+
+	return res, nil
+}
+
+// Rewrite path and headers, upstream would be set in RoundTrip.
+func (p *proxy) direct(req *http.Request) {
+	if len(p.rule.Rewrite) > 0 {
+		req.URL.Path = joinPath(p.rule.Rewrite, strings.TrimPrefix(req.URL.Path, p.rule.Prefix))
+		req.URL.RawPath = ""
+	}
+
+	for k, v := range p.rule.Headers.Add {
+		req.Header.Set(k, v)
+	}
+	for _, k := range p.rule.Headers.Remove {
+		req.Header.Del(k)
+	}
+
+	// explicitly disable User-Agent so it's not set to default value
+	if _, ok := req.Header["User-Agent"]; !ok {
+		req.Header.Set("User-Agent", "")
+	}
+}
+
+// RoundTrip send request to upstreams, idempotent requests would be retried with next upstream.
+//
+// Body of request would be buffered in memory for retries, request with body larger than
+// proxyRetryMaxBodyBytes would not be retried.
+func (p *proxy) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	var body []byte
+	if isIdempotent(req.Method) && p.rule.Retry > 0 {
+		buffered, ok, err := bufferBody(req)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			body = buffered
+			attempts += p.rule.Retry
+		}
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		upstream := p.pick()
+
+		outReq := req.Clone(req.Context())
+		if body != nil {
+			outReq.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		outReq.Host = ""
+		outReq.URL.Scheme = upstream.url.Scheme
+		outReq.URL.Host = upstream.url.Host
+		outReq.URL.Path = joinPath(upstream.url.Path, req.URL.Path)
+		outReq.URL.RawPath = ""
+
+		var resp *http.Response
+		if resp, err = p.transport.RoundTrip(outReq); err == nil {
+			return resp, nil
+		}
+
+		// no need to retry if request was cancelled or timed out
+		if req.Context().Err() != nil {
+			break
+		}
+	}
+
+	return nil, err
+}
+
+// Read body of request into memory so that it could be sent again.
+// False would be returned if body is larger than proxyRetryMaxBodyBytes, and body of request would be kept intact.
+func bufferBody(req *http.Request) ([]byte, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, proxyRetryMaxBodyBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(body) > proxyRetryMaxBodyBytes {
+		// send bytes already read along with the rest of body
+		req.Body = &proxyBody{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+		return nil, false, nil
+	}
+
+	return body, true, nil
+}
+
+// proxyBody reads from Reader and closes original body of request.
+type proxyBody struct {
+	io.Reader
+	io.Closer
+}
+
+// Pick upstream with balancer.
+func (p *proxy) pick() *proxyUpstream {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.rule.Balancer != ProxyBalancerWeighted {
+		res := p.upstreams[p.next%len(p.upstreams)]
+		p.next++
+		return res
+	}
+
+	// smooth weighted round-robin
+	total := 0
+	var res *proxyUpstream
+	for _, upstream := range p.upstreams {
+		upstream.current += upstream.weight
+		total += upstream.weight
+		if res == nil || upstream.current > res.current {
+			res = upstream
+		}
+	}
+	res.current -= total
+
+	return res
+}
+
+// Write error with error builder, 504 if timed out, 502 otherwise.
+func (p *proxy) handleError(w http.ResponseWriter, req *http.Request, err error) {
+	code := http.StatusBadGateway
+	if errors.Is(err, context.DeadlineExceeded) {
+		code = http.StatusGatewayTimeout
+	}
+
+	resp := rkmid.GetErrorBuilder().New(code, "Failed to proxy request", err.Error())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Code())
+	bytes, _ := json.Marshal(resp)
+	w.Write(bytes)
+}
+
+// Handle request in gin, tracing span would be injected into proxied request.
+func (p *proxy) handle(ctx *gin.Context) {
+	req := ctx.Request
+	if p.timeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(req.Context(), p.timeout)
+		defer cancel()
+		req = req.WithContext(timeoutCtx)
+	}
+
+	rkginctx.InjectSpanToHttpRequest(ctx, req)
+
+	p.reverse.ServeHTTP(&proxyWriter{ResponseWriter: ctx.Writer}, req)
+}
+
+// proxyWriter hides http.CloseNotifier of gin.ResponseWriter which would panic if underlying writer does not
+// implement it, request context is used by httputil.ReverseProxy instead.
+type proxyWriter struct {
+	http.ResponseWriter
+}
+
+// Flush implements http.Flusher for streaming responses.
+func (w *proxyWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Register proxy routes into router with all methods.
+func (p *proxy) register(router gin.IRoutes) {
+	router.Any(p.rule.Prefix, p.handle)
+	router.Any(path.Join(p.rule.Prefix, "*"+proxyPathParam), p.handle)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// Join paths with single slash, trailing slash of b would be kept.
+func joinPath(a, b string) string {
+	if len(b) < 1 {
+		return a
+	}
+
+	aSlash := strings.HasSuffix(a, "/")
+	bSlash := strings.HasPrefix(b, "/")
+	switch {
+	case aSlash && bSlash:
+		return a + b[1:]
+	case !aSlash && !bSlash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,290 @@
+//go:build !race
+// +build !race
+
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newProxyRule(prefix string, upstreams ...string) *BootProxyRule {
+	rule := &BootProxyRule{
+		Prefix: prefix,
+	}
+	for i := range upstreams {
+		rule.Upstreams = append(rule.Upstreams, struct {
+			Url    string `yaml:"url" json:"url"`
+			Weight int    `yaml:"weight" json:"weight"`
+		}{Url: upstreams[i]})
+	}
+
+	return rule
+}
+
+func newUpstream(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Upstream", name)
+		w.Header().Set("X-Path", req.URL.Path)
+		w.Header().Set("X-Added", req.Header.Get("X-Added"))
+		w.Header().Set("X-Removed", req.Header.Get("X-Removed"))
+		w.Header().Set("X-Host", req.Host)
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func serveProxy(p *proxy, method, path string) *httptest.ResponseRecorder {
+	router := gin.New()
+	p.register(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestNewProxy(t *testing.T) {
+	// invalid prefix
+	_, err := newProxy(newProxyRule("/", "http://localhost:8080"))
+	assert.NotNil(t, err)
+	_, err = newProxy(newProxyRule("legacy", "http://localhost:8080"))
+	assert.NotNil(t, err)
+
+	// without upstreams
+	_, err = newProxy(newProxyRule("/legacy"))
+	assert.NotNil(t, err)
+
+	// invalid upstream
+	_, err = newProxy(newProxyRule("/legacy", "localhost"))
+	assert.NotNil(t, err)
+
+	// invalid balancer
+	rule := newProxyRule("/legacy", "http://localhost:8080")
+	rule.Balancer = "invalid"
+	_, err = newProxy(rule)
+	assert.NotNil(t, err)
+
+	// happy case
+	p, err := newProxy(newProxyRule("/legacy", "http://localhost:8080"))
+	assert.Nil(t, err)
+	assert.Len(t, p.upstreams, 1)
+	assert.Equal(t, 1, p.upstreams[0].weight)
+}
+
+func TestProxy_RewriteAndHeaders(t *testing.T) {
+	upstream := newUpstream("ut-upstream")
+	defer upstream.Close()
+
+	rule := newProxyRule("/legacy", upstream.URL+"/base")
+	rule.Rewrite = "/api"
+	rule.Headers.Add = map[string]string{"X-Added": "ut-value"}
+	rule.Headers.Remove = []string{"X-Removed"}
+	p, _ := newProxy(rule)
+
+	router := gin.New()
+	p.register(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/legacy/v1/user", nil)
+	req.Header.Set("X-Removed", "ut-value")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/base/api/v1/user", w.Header().Get("X-Path"))
+	assert.Equal(t, "ut-value", w.Header().Get("X-Added"))
+	assert.Empty(t, w.Header().Get("X-Removed"))
+	assert.Equal(t, upstream.Listener.Addr().String(), w.Header().Get("X-Host"))
+
+	// prefix only
+	w = serveProxy(p, http.MethodGet, "/legacy")
+	assert.Equal(t, "/base/api", w.Header().Get("X-Path"))
+
+	// without rewrite
+	p, _ = newProxy(newProxyRule("/legacy", upstream.URL))
+	w = serveProxy(p, http.MethodPost, "/legacy/v1/user")
+	assert.Equal(t, "/legacy/v1/user", w.Header().Get("X-Path"))
+}
+
+func TestProxy_Balancer(t *testing.T) {
+	upstreamA, upstreamB := newUpstream("a"), newUpstream("b")
+	defer upstreamA.Close()
+	defer upstreamB.Close()
+
+	// round-robin
+	p, _ := newProxy(newProxyRule("/legacy", upstreamA.URL, upstreamB.URL))
+	res := make([]string, 0)
+	for i := 0; i < 4; i++ {
+		res = append(res, serveProxy(p, http.MethodGet, "/legacy").Header().Get("X-Upstream"))
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, res)
+
+	// weighted
+	rule := newProxyRule("/legacy", upstreamA.URL, upstreamB.URL)
+	rule.Balancer = ProxyBalancerWeighted
+	rule.Upstreams[0].Weight = 2
+	p, _ = newProxy(rule)
+	res = make([]string, 0)
+	for i := 0; i < 3; i++ {
+		res = append(res, serveProxy(p, http.MethodGet, "/legacy").Header().Get("X-Upstream"))
+	}
+	assert.Equal(t, []string{"a", "b", "a"}, res)
+}
+
+func TestProxy_Retry(t *testing.T) {
+	upstream := newUpstream("ut-upstream")
+	defer upstream.Close()
+	closed := newUpstream("closed")
+	closed.Close()
+
+	// without retry
+	p, _ := newProxy(newProxyRule("/legacy", closed.URL, upstream.URL))
+	assert.Equal(t, http.StatusBadGateway, serveProxy(p, http.MethodGet, "/legacy").Code)
+
+	// with retry
+	rule := newProxyRule("/legacy", closed.URL, upstream.URL)
+	rule.Retry = 1
+	p, _ = newProxy(rule)
+	w := serveProxy(p, http.MethodGet, "/legacy")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ut-upstream", w.Header().Get("X-Upstream"))
+
+	// non-idempotent request would not be retried
+	p, _ = newProxy(rule)
+	assert.Equal(t, http.StatusBadGateway, serveProxy(p, http.MethodPost, "/legacy").Code)
+}
+
+func TestProxy_RetryWithBody(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		w.Write(body)
+	}))
+	defer upstream.Close()
+	closed := newUpstream("closed")
+	closed.Close()
+
+	rule := newProxyRule("/legacy", closed.URL, upstream.URL)
+	rule.Retry = 1
+	router := gin.New()
+	p, _ := newProxy(rule)
+	p.register(router)
+
+	// body would be sent again while retrying
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/legacy", strings.NewReader("ut-body")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ut-body", w.Body.String())
+
+	// large body would not be retried
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/legacy",
+		strings.NewReader(strings.Repeat("a", proxyRetryMaxBodyBytes+1))))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	// large body would be proxied intact without retry
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/legacy",
+		strings.NewReader(strings.Repeat("a", proxyRetryMaxBodyBytes+1))))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, proxyRetryMaxBodyBytes+1, w.Body.Len())
+}
+
+func TestProxy_Timeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer upstream.Close()
+
+	rule := newProxyRule("/legacy", upstream.URL)
+	rule.TimeoutMs = 10
+	p, _ := newProxy(rule)
+	assert.Equal(t, http.StatusGatewayTimeout, serveProxy(p, http.MethodGet, "/legacy").Code)
+}
+
+func TestProxy_TimeoutHeader(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Timeout", req.Header.Get(rkginctx.TimeoutHeaderKey))
+	}))
+	defer upstream.Close()
+
+	rule := newProxyRule("/legacy", upstream.URL)
+	rule.TimeoutMs = 100
+	p, _ := newProxy(rule)
+
+	// incoming request with later deadline, timeout of proxy would be propagated
+	router := gin.New()
+	p.register(router)
+	reqCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/legacy", nil).WithContext(reqCtx))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Regexp(t, `^\d{1,8}n$`, w.Header().Get("X-Timeout"))
+	remaining, err := time.ParseDuration(w.Header().Get("X-Timeout") + "s")
+	assert.Nil(t, err)
+	assert.True(t, remaining > 0 && remaining <= 100*time.Millisecond)
+}
+
+func TestRegisterGinEntryYAML_WithProxy(t *testing.T) {
+	upstream := newUpstream("ut-upstream")
+	defer upstream.Close()
+
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-proxy
+   port: 8080
+   enabled: true
+   middleware:
+     auth:
+       enabled: true
+       basic:
+         - "user:pass"
+   proxy:
+     enabled: true
+     rules:
+       - prefix: /legacy
+         upstreams:
+           - url: ` + upstream.URL + `
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-proxy"].(*GinEntry)
+	assert.Len(t, entry.ProxyRules, 1)
+
+	entry.Bootstrap(context.TODO())
+	defer entry.Interrupt(context.TODO())
+
+	// proxied requests go through middlewares
+	w := httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/legacy/v1", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/legacy/v1", nil)
+	req.SetBasicAuth("user", "pass")
+	w = httptest.NewRecorder()
+	entry.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ut-upstream", w.Header().Get("X-Upstream"))
+}
+
+func TestJoinPath(t *testing.T) {
+	assert.Equal(t, "/a", joinPath("/a", ""))
+	assert.Equal(t, "/a/b", joinPath("/a", "b"))
+	assert.Equal(t, "/a/b", joinPath("/a/", "/b"))
+	assert.Equal(t, "/a/b/", joinPath("/a", "/b/"))
+}
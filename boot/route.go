@@ -0,0 +1,269 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/cors"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/csrf"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/jwt"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/meta"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/secure"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/timeout"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/bodylimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/breaker"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/cache"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/concurrency"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/cors"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/csrf"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/gzip"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/idempotency"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/jwt"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/meta"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/secure"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/timeout"
+	"strings"
+)
+
+// RouteMethodAny matches route with any HTTP method
+const RouteMethodAny = "*"
+
+// BootGzip bootstrap config of gzip middleware.
+//
+// Level is used by every encoding unless overridden in Levels, keyed by encoding.
+// ExcludedContentTypes would be appended to default ones like image/png.
+type BootGzip struct {
+	Enabled              bool              `yaml:"enabled" json:"enabled"`
+	Ignore               []string          `yaml:"ignore" json:"ignore"`
+	Level                string            `yaml:"level" json:"level"`
+	Levels               map[string]string `yaml:"levels" json:"levels"`
+	Encodings            []string          `yaml:"encodings" json:"encodings"`
+	MinLength            int               `yaml:"minLength" json:"minLength"`
+	ContentTypes         []string          `yaml:"contentTypes" json:"contentTypes"`
+	ExcludedContentTypes []string          `yaml:"excludedContentTypes" json:"excludedContentTypes"`
+}
+
+// BootRoute bootstrap config of route level middleware.
+//
+// Path should be the same as the one registered in gin, like /v1/user/:id, since it is matched with gin.Context.FullPath().
+// Middleware configured in route replaces the one in middleware section, omitted middleware keeps global config.
+type BootRoute struct {
+	Path       string `yaml:"path" json:"path"`
+	Method     string `yaml:"method" json:"method"`
+	Middleware struct {
+		Auth        *rkmidauth.BootConfig        `yaml:"auth" json:"auth"`
+		Cors        *rkmidcors.BootConfig        `yaml:"cors" json:"cors"`
+		Meta        *rkmidmeta.BootConfig        `yaml:"meta" json:"meta"`
+		Jwt         *rkmidjwt.BootConfig         `yaml:"jwt" json:"jwt"`
+		Secure      *rkmidsec.BootConfig         `yaml:"secure" json:"secure"`
+		RateLimit   *rkginlimit.BootConfig       `yaml:"rateLimit" json:"rateLimit"`
+		Csrf        *rkmidcsrf.BootConfig        `yaml:"csrf" json:"csrf"`
+		Timeout     *rkgintout.BootConfig        `yaml:"timeout" json:"timeout"`
+		Gzip        *BootGzip                    `yaml:"gzip" json:"gzip"`
+		Breaker     *rkginbreaker.BootConfig     `yaml:"breaker" json:"breaker"`
+		Concurrency *rkginconcurrency.BootConfig `yaml:"concurrency" json:"concurrency"`
+		Cache       *rkgincache.BootConfig       `yaml:"cache" json:"cache"`
+		Idempotency *rkginidempotency.BootConfig `yaml:"idempotency" json:"idempotency"`
+		BodyLimit   *rkginbodylimit.BootConfig   `yaml:"bodyLimit" json:"bodyLimit"`
+	} `yaml:"middleware" json:"middleware"`
+}
+
+// Key of route used for matching, method would be RouteMethodAny if missing.
+func (route *BootRoute) key() (string, error) {
+	if len(route.Path) < 1 {
+		return "", errors.New("path of route is required")
+	}
+
+	method := strings.ToUpper(route.Method)
+	if len(method) < 1 {
+		method = RouteMethodAny
+	}
+
+	return routeKey(method, route.Path), nil
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// Wrap global middleware with route level overrides.
+//
+// build returns middleware of route and whether route overrides it, nil middleware means disabled in route.
+// Global middleware would be returned directly if none of routes overrides it.
+func withRoutes(routes []*BootRoute, global gin.HandlerFunc, build func(*BootRoute) (gin.HandlerFunc, bool)) (gin.HandlerFunc, error) {
+	overrides := make(map[string]gin.HandlerFunc)
+
+	for i := range routes {
+		key, err := routes[i].key()
+		if err != nil {
+			return nil, err
+		}
+
+		if mid, ok := build(routes[i]); ok {
+			overrides[key] = mid
+		}
+	}
+
+	if len(overrides) < 1 {
+		return global, nil
+	}
+
+	return func(ctx *gin.Context) {
+		mid, ok := overrides[routeKey(ctx.Request.Method, ctx.FullPath())]
+		if !ok {
+			mid, ok = overrides[routeKey(RouteMethodAny, ctx.FullPath())]
+		}
+		if !ok {
+			mid = global
+		}
+
+		// gin would continue with next handler
+		if mid != nil {
+			mid(ctx)
+		}
+	}, nil
+}
+
+func newAuthMiddleware(entryName string, config *rkmidauth.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkginauth.Middleware(rkmidauth.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newCorsMiddleware(entryName string, config *rkmidcors.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkgincors.Middleware(rkmidcors.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newMetaMiddleware(entryName string, config *rkmidmeta.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkginmeta.Middleware(rkmidmeta.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newJwtMiddleware(entryName string, config *rkmidjwt.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkginjwt.Middleware(rkmidjwt.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newSecureMiddleware(entryName string, config *rkmidsec.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkginsec.Middleware(rkmidsec.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newRateLimitMiddleware(entryName string, config *rkginlimit.BootConfig, onRelease func(func())) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	// refuse to limit all clients together silently
+	if keys := config.UnsupportedKeys(); len(keys) > 0 {
+		rkentry.ShutdownWithError(fmt.Errorf("%s of rate limit require algorithm %s or %s",
+			strings.Join(keys, ", "), rkginlimit.SlidingWindow, rkginlimit.GCRA))
+	}
+	if config.UseStore() {
+		opts := rkginlimit.ToOptions(config, entryName, GinEntryType)
+		onRelease(func() {
+			rkginlimit.Release(config)
+		})
+		return rkginlimit.StoreMiddleware(opts...)
+	}
+	return rkginlimit.Middleware(rkmidlimit.ToOptions(config.ToEntryConfig(), entryName, GinEntryType)...)
+}
+
+func newCsrfMiddleware(entryName string, config *rkmidcsrf.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkgincsrf.Middleware(rkmidcsrf.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newEntryTimeoutMiddleware(entryName string, config *rkmidtimeout.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkgintout.Middleware(rkmidtimeout.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newTimeoutMiddleware(entryName string, config *rkgintout.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	if config.UseStreaming() {
+		return rkgintout.StreamMiddleware(rkgintout.ToOptions(config, entryName, GinEntryType)...)
+	}
+	return rkgintout.BufferMiddleware(rkgintout.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newGzipMiddleware(entryName string, config *BootGzip) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	opts := []rkgingzip.Option{
+		rkgingzip.WithEntryNameAndType(entryName, GinEntryType),
+		rkgingzip.WithLevel(config.Level),
+		rkgingzip.WithEncodings(config.Encodings...),
+		rkgingzip.WithMinLength(config.MinLength),
+		rkgingzip.WithContentTypes(config.ContentTypes...),
+		rkgingzip.WithExcludedContentTypes(config.ExcludedContentTypes...),
+		rkgingzip.WithPathToIgnore(config.Ignore...),
+	}
+	for encoding, level := range config.Levels {
+		opts = append(opts, rkgingzip.WithEncodingLevel(encoding, level))
+	}
+	return rkgingzip.Middleware(opts...)
+}
+
+func newBreakerMiddleware(entryName string, config *rkginbreaker.BootConfig, registerer prometheus.Registerer) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkginbreaker.Middleware(rkginbreaker.ToOptions(config, entryName, GinEntryType, registerer)...)
+}
+
+func newConcurrencyMiddleware(entryName string, config *rkginconcurrency.BootConfig, registerer prometheus.Registerer, criticalPaths []string) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	opts := rkginconcurrency.ToOptions(config, entryName, GinEntryType, registerer)
+	return rkginconcurrency.Middleware(append(opts, rkginconcurrency.WithCriticalPaths(criticalPaths...))...)
+}
+
+func newCacheMiddleware(entryName string, config *rkgincache.BootConfig, registerer prometheus.Registerer) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkgincache.Middleware(rkgincache.ToOptions(config, entryName, GinEntryType, registerer)...)
+}
+
+func newIdempotencyMiddleware(entryName string, config *rkginidempotency.BootConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkginidempotency.Middleware(rkginidempotency.ToOptions(config, entryName, GinEntryType)...)
+}
+
+func newBodyLimitMiddleware(entryName string, config *rkginbodylimit.BootConfig, registerer prometheus.Registerer) gin.HandlerFunc {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	return rkginbodylimit.Middleware(rkginbodylimit.ToOptions(config, entryName, GinEntryType, registerer)...)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBootRoute_key(t *testing.T) {
+	// without path
+	_, err := (&BootRoute{}).key()
+	assert.NotNil(t, err)
+
+	// without method
+	key, err := (&BootRoute{Path: "/v1/user/:id"}).key()
+	assert.Nil(t, err)
+	assert.Equal(t, "* /v1/user/:id", key)
+
+	// with method
+	key, err = (&BootRoute{Path: "/v1/user/:id", Method: "get"}).key()
+	assert.Nil(t, err)
+	assert.Equal(t, "GET /v1/user/:id", key)
+}
+
+func TestWithRoutes(t *testing.T) {
+	global := func(ctx *gin.Context) {}
+
+	// without overrides
+	mid, err := withRoutes([]*BootRoute{{Path: "/ut"}}, global, func(*BootRoute) (gin.HandlerFunc, bool) {
+		return nil, false
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, mid)
+
+	// with invalid route
+	_, err = withRoutes([]*BootRoute{{}}, global, func(*BootRoute) (gin.HandlerFunc, bool) {
+		return nil, true
+	})
+	assert.NotNil(t, err)
+}
+
+func TestNewRateLimitMiddleware(t *testing.T) {
+	onRelease := func(func()) {}
+
+	// with disabled
+	assert.Nil(t, newRateLimitMiddleware("ut-entry", &rkginlimit.BootConfig{}, onRelease))
+
+	// with in-process limiter
+	assert.NotNil(t, newRateLimitMiddleware("ut-entry", &rkginlimit.BootConfig{Enabled: true}, onRelease))
+
+	// with identities which are not supported by in-process limiter
+	assert.Panics(t, func() {
+		newRateLimitMiddleware("ut-entry", &rkginlimit.BootConfig{Enabled: true, KeyBy: rkginlimit.KeyByIP}, onRelease)
+	})
+
+	// with store
+	registered := false
+	mid := newRateLimitMiddleware("ut-entry", &rkginlimit.BootConfig{
+		Enabled:   true,
+		Algorithm: rkginlimit.GCRA,
+		KeyBy:     rkginlimit.KeyByIP,
+	}, func(f func()) {
+		registered = true
+	})
+	assert.NotNil(t, mid)
+	assert.True(t, registered)
+}
+
+func TestRegisterGinEntryYAML_WithRoutes(t *testing.T) {
+	bootConfigStr := `
+---
+gin:
+ - name: greeter-routes
+   port: 8080
+   enabled: true
+   middleware:
+     auth:
+       enabled: true
+       basic:
+         - "user:pass"
+   routes:
+     - path: /v1/public/:id
+       middleware:
+         auth:
+           enabled: false
+     - path: /v1/user/:id
+       method: POST
+       middleware:
+         auth:
+           enabled: true
+           basic:
+             - "admin:pass"
+`
+	entries := RegisterGinEntryYAML([]byte(bootConfigStr))
+	entry := entries["greeter-routes"].(*GinEntry)
+
+	handler := func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	}
+	entry.Router.GET("/v1/public/:id", handler)
+	entry.Router.GET("/v1/user/:id", handler)
+	entry.Router.POST("/v1/user/:id", handler)
+
+	serve := func(method, path, user string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if len(user) > 0 {
+			req.SetBasicAuth(user, "pass")
+		}
+		w := httptest.NewRecorder()
+		entry.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// auth disabled in route
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/public/1", ""))
+
+	// global auth
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/user/1", ""))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/user/1", "user"))
+
+	// auth overridden in route with method
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/v1/user/1", "user"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/user/1", "admin"))
+}
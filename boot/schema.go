@@ -0,0 +1,162 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"encoding/json"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/csrf"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/log"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/breaker"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/concurrency"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/gzip"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/timeout"
+	"reflect"
+	"strings"
+)
+
+// SchemaDraft is the JSON Schema version of generated schema
+const SchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var (
+	gzipLevels = []string{rkgingzip.NoCompression, rkgingzip.BestSpeed, rkgingzip.BestCompression,
+		rkgingzip.DefaultCompression, rkgingzip.HuffmanOnly}
+	gzipEncodings = []string{"br", "zstd", "gzip"}
+	errorModels   = []string{"google", "amazon"}
+
+	// enums of fields keyed by type of struct and yaml key, anonymous struct are keyed by its structure
+	schemaEnums = map[reflect.Type]map[string][]string{
+		reflect.TypeOf(BootGinElement{}): {
+			"protocols": {ProtocolHttp1, ProtocolH2C, ProtocolH2, ProtocolH3},
+		},
+		reflect.TypeOf(BootGinElement{}.Middleware): {
+			"errorModel": errorModels,
+		},
+		reflect.TypeOf(BootTLS{}): {
+			"verify":     {ClientAuthNone, ClientAuthRequest, ClientAuthRequire, ClientAuthVerifyIfGiven},
+			"minVersion": {"1.0", "1.1", "1.2", "1.3"},
+		},
+		reflect.TypeOf(BootGzip{}): {
+			"level":     gzipLevels,
+			"levels":    gzipLevels,
+			"encodings": gzipEncodings,
+		},
+		reflect.TypeOf(BootProxyRule{}): {
+			"balancer": {ProxyBalancerRoundRobin, ProxyBalancerWeighted},
+		},
+		reflect.TypeOf(rkentry.BootStaticFileHandler{}): {
+			"sourceType": {"local", "embed"},
+		},
+		reflect.TypeOf(rkmidlog.BootConfig{}): {
+			"loggerEncoding": {"console", "json"},
+			"eventEncoding":  {"console", "json"},
+		},
+		reflect.TypeOf(rkmidcsrf.BootConfig{}): {
+			"cookieSameSite": {"lax", "strict", "none", "default"},
+		},
+		reflect.TypeOf(rkgintout.BootConfig{}): {
+			"mode": {rkgintout.ModeBuffered, rkgintout.ModeStreaming},
+		},
+		reflect.TypeOf(rkginlimit.BootConfig{}): {
+			"algorithm": {rkmidlimit.LeakyBucket, rkginlimit.SlidingWindow, rkginlimit.GCRA},
+		},
+		reflect.TypeOf(rkginlimit.BootConfig{}.Store): {
+			"type": {rkginlimit.StoreMemory, rkginlimit.StoreRedis},
+		},
+		reflect.TypeOf(rkginbreaker.BootConfig{}): {
+			"mode": {rkginbreaker.ModeConsecutive, rkginbreaker.ModeErrorRatio},
+		},
+		reflect.TypeOf(rkginconcurrency.BootConfig{}): {
+			"algorithm": {rkginconcurrency.AlgorithmAIMD, rkginconcurrency.AlgorithmGradient},
+		},
+	}
+
+	// enums of map keys keyed by type of struct and yaml key
+	schemaKeyEnums = map[reflect.Type]map[string][]string{
+		reflect.TypeOf(BootGzip{}): {
+			"levels": gzipEncodings,
+		},
+	}
+)
+
+// JSONSchema returns JSON Schema of gin section in boot config, which could be used by IDE and CI to validate boot.yaml.
+//
+// Keys are named as yaml tags of BootGin, sections of other entries like logger are allowed without validation.
+func JSONSchema() ([]byte, error) {
+	schema := map[string]interface{}{
+		"$schema":     SchemaDraft,
+		"title":       "rk-gin boot config",
+		"description": "Boot config of gin entries",
+	}
+
+	for k, v := range schemaOf(reflect.TypeOf(BootGin{}), nil, nil) {
+		schema[k] = v
+	}
+	schema["additionalProperties"] = true
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// Schema of type, enum would be applied to values of string, list and map, keys would be applied to keys of map.
+func schemaOf(typ reflect.Type, enum, keys []string) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if len(field.PkgPath) > 0 || key == "-" {
+				continue
+			}
+			if len(key) < 1 {
+				key = field.Name
+			}
+
+			props[key] = schemaOf(field.Type, schemaEnums[typ][key], schemaKeyEnums[typ][key])
+		}
+
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaOf(typ.Elem(), enum, nil),
+		}
+	case reflect.Map:
+		res := map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaOf(typ.Elem(), enum, nil),
+		}
+		if len(keys) > 0 {
+			res["propertyNames"] = map[string]interface{}{"enum": keys}
+		}
+		return res
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		if len(enum) > 0 {
+			return map[string]interface{}{"type": "string", "enum": enum}
+		}
+		return map[string]interface{}{"type": "string"}
+	}
+
+	return map[string]interface{}{}
+}
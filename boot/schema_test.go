@@ -0,0 +1,72 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// Find schema with path of property names, items of list are denoted with [].
+func schemaAt(schema map[string]interface{}, path string) map[string]interface{} {
+	for _, key := range strings.Split(path, ".") {
+		if key == "[]" {
+			schema, _ = schema["items"].(map[string]interface{})
+		} else {
+			props, _ := schema["properties"].(map[string]interface{})
+			schema, _ = props[key].(map[string]interface{})
+		}
+
+		if schema == nil {
+			return nil
+		}
+	}
+
+	return schema
+}
+
+func TestJSONSchema(t *testing.T) {
+	raw, err := JSONSchema()
+	assert.Nil(t, err)
+
+	schema := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(raw, &schema))
+
+	assert.Equal(t, SchemaDraft, schema["$schema"])
+	assert.Equal(t, true, schema["additionalProperties"])
+
+	// gin entry
+	element := schemaAt(schema, "gin.[]")
+	assert.Equal(t, "object", element["type"])
+	assert.Equal(t, false, element["additionalProperties"])
+	assert.Equal(t, "boolean", schemaAt(element, "strict")["type"])
+	assert.Equal(t, float64(0), schemaAt(element, "port")["minimum"])
+	assert.Len(t, schemaAt(element, "protocols.[]")["enum"], 4)
+	assert.Len(t, schemaAt(element, "tls.verify")["enum"], 4)
+	assert.Len(t, schemaAt(element, "middleware.errorModel")["enum"], 2)
+
+	// nested middleware
+	assert.Len(t, schemaAt(element, "middleware.gzip.level")["enum"], 5)
+	assert.Len(t, schemaAt(element, "middleware.gzip.encodings.[]")["enum"], 3)
+	assert.Len(t, schemaAt(element, "middleware.rateLimit.algorithm")["enum"], 3)
+	assert.Len(t, schemaAt(element, "middleware.rateLimit.store.type")["enum"], 2)
+	assert.Equal(t, "string", schemaAt(element, "middleware.rateLimit.store.redis.password")["type"])
+	assert.Equal(t, "integer", schemaAt(element, "middleware.rateLimit.reqPerSec")["type"])
+	assert.NotNil(t, schemaAt(element, "middleware.trace.exporter.jaeger.agent.host"))
+
+	// map of encoding and level
+	levels := schemaAt(element, "middleware.gzip.levels")
+	assert.Equal(t, "object", levels["type"])
+	assert.Len(t, levels["propertyNames"].(map[string]interface{})["enum"], 3)
+	assert.Len(t, levels["additionalProperties"].(map[string]interface{})["enum"], 5)
+
+	// route overrides
+	assert.Len(t, schemaAt(element, "routes.[].middleware.gzip.level")["enum"], 5)
+	assert.Len(t, schemaAt(element, "routes.[].middleware.timeout.mode")["enum"], 2)
+	assert.Len(t, schemaAt(element, "proxy.rules.[].balancer")["enum"], 2)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// ClientAuthNone do not request client certificate
+	ClientAuthNone = "none"
+	// ClientAuthRequest request client certificate without verification
+	ClientAuthRequest = "request"
+	// ClientAuthRequire require and verify client certificate
+	ClientAuthRequire = "require"
+	// ClientAuthVerifyIfGiven verify client certificate if provided
+	ClientAuthVerifyIfGiven = "verify-if-given"
+)
+
+// BootTLS bootstrap config of TLS for GinEntry.
+// Server certificate is provided by CertEntry.
+type BootTLS struct {
+	ClientCAs    []string `yaml:"clientCAs" json:"clientCAs"`
+	Verify       string   `yaml:"verify" json:"verify"`
+	MinVersion   string   `yaml:"minVersion" json:"minVersion"`
+	CipherSuites []string `yaml:"cipherSuites" json:"cipherSuites"`
+	Reload       bool     `yaml:"reload" json:"reload"`
+}
+
+// Convert BootTLS into GinEntryOption list, reload is applied with paths of certificate from boot config of CertEntry.
+func (boot *BootTLS) toOptions() ([]GinEntryOption, error) {
+	opts := make([]GinEntryOption, 0)
+
+	clientAuth, err := parseClientAuth(boot.Verify)
+	if err != nil {
+		return nil, err
+	}
+
+	var pool *x509.CertPool
+	if len(boot.ClientCAs) > 0 {
+		if pool, err = loadCertPool(boot.ClientCAs...); err != nil {
+			return nil, err
+		}
+	}
+	opts = append(opts, WithClientAuth(clientAuth, pool))
+
+	if len(boot.MinVersion) > 0 {
+		version, err := parseTLSVersion(boot.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTLSMinVersion(version))
+	}
+
+	if len(boot.CipherSuites) > 0 {
+		suites, err := parseCipherSuites(boot.CipherSuites...)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTLSCipherSuites(suites...))
+	}
+
+	return opts, nil
+}
+
+// Build tls.Config of server with certificate from CertEntry.
+func (entry *GinEntry) newTLSConfig() *tls.Config {
+	res := &tls.Config{
+		NextProtos:   entry.tlsNextProtos(),
+		ClientAuth:   entry.ClientAuth,
+		ClientCAs:    entry.ClientCAs,
+		MinVersion:   entry.TLSMinVersion,
+		CipherSuites: entry.TLSCipherSuites,
+	}
+
+	// certificate may be rotated by reloader
+	if entry.certReloader != nil {
+		res.GetCertificate = entry.certReloader.GetCertificate
+	} else {
+		res.Certificates = []tls.Certificate{*entry.CertEntry.Certificate}
+	}
+
+	return res
+}
+
+// Parse client auth type, empty string would be treated as none.
+func parseClientAuth(verify string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(verify) {
+	case "", ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.RequestClientCert, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	case ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven, nil
+	}
+
+	return tls.NoClientCert, fmt.Errorf("invalid verify mode %s, options: [%s, %s, %s, %s]",
+		verify, ClientAuthNone, ClientAuthRequest, ClientAuthRequire, ClientAuthVerifyIfGiven)
+}
+
+// Parse TLS version, options: 1.0, 1.1, 1.2, 1.3
+func parseTLSVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(version), "tls") {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("invalid TLS version %s, options: [1.0, 1.1, 1.2, 1.3]", version)
+}
+
+// Parse cipher suites with names defined in crypto/tls, like TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+func parseCipherSuites(names ...string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	res := make([]uint16, 0, len(names))
+	for i := range names {
+		id, ok := known[strings.ToUpper(names[i])]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %s", names[i])
+		}
+		res = append(res, id)
+	}
+
+	return res, nil
+}
+
+// Load PEM encoded certificate bundles into x509.CertPool.
+func loadCertPool(paths ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	for i := range paths {
+		bytes, err := os.ReadFile(paths[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if !pool.AppendCertsFromPEM(bytes) {
+			return nil, fmt.Errorf("no certificate found in %s", paths[i])
+		}
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,160 @@
+//go:build !race
+// +build !race
+
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestParseClientAuth(t *testing.T) {
+	for k, v := range map[string]tls.ClientAuthType{
+		"":                      tls.NoClientCert,
+		ClientAuthNone:          tls.NoClientCert,
+		ClientAuthRequest:       tls.RequestClientCert,
+		ClientAuthRequire:       tls.RequireAndVerifyClientCert,
+		ClientAuthVerifyIfGiven: tls.VerifyClientCertIfGiven,
+	} {
+		res, err := parseClientAuth(k)
+		assert.Nil(t, err)
+		assert.Equal(t, v, res)
+	}
+
+	_, err := parseClientAuth("invalid")
+	assert.NotNil(t, err)
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	res, err := parseTLSVersion("1.2")
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), res)
+
+	res, err = parseTLSVersion("TLS1.3")
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), res)
+
+	_, err = parseTLSVersion("2.0")
+	assert.NotNil(t, err)
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	res, err := parseCipherSuites("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	assert.Nil(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, res)
+
+	_, err = parseCipherSuites("invalid")
+	assert.NotNil(t, err)
+}
+
+func TestLoadCertPool(t *testing.T) {
+	certPem, _ := generateCerts()
+	caPath := path.Join(t.TempDir(), "ca.pem")
+	assert.Nil(t, os.WriteFile(caPath, certPem, 0644))
+
+	pool, err := loadCertPool(caPath)
+	assert.Nil(t, err)
+	assert.NotNil(t, pool)
+
+	// not exist
+	_, err = loadCertPool(path.Join(t.TempDir(), "not-exist.pem"))
+	assert.NotNil(t, err)
+
+	// not a certificate
+	invalidPath := path.Join(t.TempDir(), "invalid.pem")
+	assert.Nil(t, os.WriteFile(invalidPath, []byte("invalid"), 0644))
+	_, err = loadCertPool(invalidPath)
+	assert.NotNil(t, err)
+}
+
+func TestBootTLS_toOptions(t *testing.T) {
+	certPem, _ := generateCerts()
+	caPath := path.Join(t.TempDir(), "ca.pem")
+	assert.Nil(t, os.WriteFile(caPath, certPem, 0644))
+
+	boot := &BootTLS{
+		ClientCAs:    []string{caPath},
+		Verify:       ClientAuthRequire,
+		MinVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	}
+	opts, err := boot.toOptions()
+	assert.Nil(t, err)
+
+	entry := &GinEntry{}
+	for i := range opts {
+		opts[i](entry)
+	}
+	assert.Equal(t, tls.RequireAndVerifyClientCert, entry.ClientAuth)
+	assert.NotNil(t, entry.ClientCAs)
+	assert.Equal(t, uint16(tls.VersionTLS12), entry.TLSMinVersion)
+	assert.Len(t, entry.TLSCipherSuites, 1)
+
+	// with invalid verify mode
+	_, err = (&BootTLS{Verify: "invalid"}).toOptions()
+	assert.NotNil(t, err)
+}
+
+func TestGinEntry_MutualTLS(t *testing.T) {
+	certPem, keyPem := generateCerts()
+	certificate, _ := tls.X509KeyPair(certPem, keyPem)
+	caPath := path.Join(t.TempDir(), "ca.pem")
+	assert.Nil(t, os.WriteFile(caPath, certPem, 0644))
+
+	certEntry := rkentry.RegisterCertEntry(&rkentry.BootCert{
+		Cert: []*rkentry.BootCertE{
+			{
+				Name: "ut-cert",
+			},
+		},
+	})[0]
+	certEntry.Certificate = &certificate
+
+	pool, _ := loadCertPool(caPath)
+	entry := RegisterGinEntry(
+		WithPort(8080),
+		WithCertEntry(certEntry),
+		WithClientAuth(tls.RequireAndVerifyClientCert, pool),
+		WithTLSMinVersion(tls.VersionTLS12))
+	entry.Router.GET("/peer", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, rkginctx.GetPeerCertificate(ctx).Subject.String())
+	})
+	entry.Bootstrap(context.TODO())
+	defer entry.Interrupt(context.TODO())
+	validateServerIsUp(t, 8080, true)
+
+	// without client certificate
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	_, err := client.Get("https://localhost:8080/peer")
+	assert.NotNil(t, err)
+
+	// with client certificate
+	client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				Certificates:       []tls.Certificate{certificate},
+			},
+		},
+	}
+	resp, err := client.Get("https://localhost:8080/peer")
+	assert.Nil(t, err)
+	assert.Equal(t, "O=Fake cert.", readBody(resp))
+}
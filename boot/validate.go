@@ -0,0 +1,499 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"fmt"
+	"github.com/rookie-ninja/rk-entry/v2/entry"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/breaker"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/concurrency"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/gzip"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/timeout"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigError is a problem found in boot config, Path is YAML path like gin[0].middleware.gzip.level.
+type ConfigError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// Error returns problem with YAML path and line.
+func (e *ConfigError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (line %d): %s", e.Path, e.Line, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ConfigErrors is a list of problems found in boot config.
+type ConfigErrors []*ConfigError
+
+// Error returns all problems, one per line.
+func (errs ConfigErrors) Error() string {
+	lines := make([]string, 0, len(errs)+1)
+	lines = append(lines, fmt.Sprintf("invalid boot config of gin, %d problems found", len(errs)))
+	for i := range errs {
+		lines = append(lines, "  "+errs[i].Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Problems of gin entry at index, problems which are not bound to any entry are included.
+func (errs ConfigErrors) of(index int) ConfigErrors {
+	prefix := fmt.Sprintf("gin[%d]", index)
+
+	res := make(ConfigErrors, 0)
+	for i := range errs {
+		path := errs[i].Path
+		if !strings.HasPrefix(path, "gin[") || path == prefix || strings.HasPrefix(path, prefix+".") {
+			res = append(res, errs[i])
+		}
+	}
+
+	return res
+}
+
+// Validate boot config of gin entries, every problem would be reported with its YAML path in ConfigErrors.
+//
+// Unknown keys, invalid values, and references to entries like certEntry which are neither declared in boot config
+// nor registered are reported. Nil would be returned if no problem found.
+//
+// Validate could be used offline, like linting boot.yaml in CI.
+func Validate(raw []byte) error {
+	if errs := validate(raw); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Validate boot config, problems are sorted by line, problems without line come first.
+func validate(raw []byte) ConfigErrors {
+	v := &validator{
+		errs:  make(ConfigErrors, 0),
+		lines: make(map[string]int),
+	}
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(raw, root); err != nil {
+		v.add("", "%v", err)
+		return v.sorted()
+	}
+
+	// unknown keys
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if node := mappingValue(doc, "gin"); node != nil {
+		v.walk(node, reflect.TypeOf(BootGin{}.Gin), "gin")
+	}
+
+	config, err := decodeBootGin(raw)
+	if err != nil {
+		v.add("gin", "%v", err)
+		return v.sorted()
+	}
+
+	// entries referenced by gin entries
+	v.declared = map[string][]string{
+		"cert":   declaredNames(doc, "cert"),
+		"logger": declaredNames(doc, "logger"),
+		"event":  declaredNames(doc, "event"),
+	}
+
+	names := make(map[string]bool)
+	for i := range config.Gin {
+		element := config.Gin[i]
+		path := fmt.Sprintf("gin[%d]", i)
+
+		if len(element.Name) < 1 {
+			v.add(path+".name", "name is required")
+		} else if names[element.Name] {
+			v.add(path+".name", "duplicate name %s", element.Name)
+		}
+		names[element.Name] = true
+
+		v.validateElement(element, path)
+	}
+
+	return v.sorted()
+}
+
+// Decode boot config, invalid config would shutdown with panic.
+func decodeBootGin(raw []byte) (config *BootGin, err error) {
+	defer func() {
+		if recv := recover(); recv != nil {
+			config, err = nil, fmt.Errorf("%v", recv)
+		}
+	}()
+
+	config = &BootGin{}
+	rkentry.UnmarshalBootYAML(raw, config)
+
+	return config, nil
+}
+
+type validator struct {
+	errs     ConfigErrors
+	lines    map[string]int
+	declared map[string][]string
+}
+
+// Record a problem with line of path, line of parent would be used if path is missing in YAML.
+func (v *validator) add(path, format string, args ...interface{}) {
+	line, key := 0, strings.ToLower(path)
+	for len(key) > 0 {
+		if line = v.lines[key]; line > 0 {
+			break
+		}
+		key = key[:strings.LastIndexAny(key, ".[")+1]
+		key = strings.TrimRight(key, ".[")
+	}
+
+	v.errs = append(v.errs, &ConfigError{
+		Path:    path,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Problems sorted by line, problems of the same line keep order of recording.
+func (v *validator) sorted() ConfigErrors {
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return v.errs[i].Line < v.errs[j].Line
+	})
+
+	return v.errs
+}
+
+// Walk YAML node with type of boot config, keys are matched case-insensitively as decoding does.
+func (v *validator) walk(node *yaml.Node, typ reflect.Type, path string) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	// null value
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.add(path, "expect a mapping")
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := path + "." + key.Value
+			v.lines[strings.ToLower(child)] = key.Line
+
+			field, ok := fieldByKey(typ, key.Value)
+			if !ok {
+				v.add(child, "unknown key %s", key.Value)
+				continue
+			}
+
+			v.walk(value, field.Type, child)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			v.add(path, "expect a list")
+			return
+		}
+
+		for i := range node.Content {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			v.lines[strings.ToLower(child)] = node.Content[i].Line
+			v.walk(node.Content[i], typ.Elem(), child)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.add(path, "expect a mapping")
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := path + "." + node.Content[i].Value
+			v.lines[strings.ToLower(child)] = node.Content[i].Line
+			v.walk(node.Content[i+1], typ.Elem(), child)
+		}
+	}
+}
+
+// Field of struct matched with key, either field name or yaml tag.
+func fieldByKey(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if strings.EqualFold(field.Name, key) || (len(tag) > 0 && tag != "-" && strings.EqualFold(tag, key)) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// Value of key in mapping node, key is matched case-insensitively.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// Names of entries declared in section of boot config, like cert[].name.
+func declaredNames(doc *yaml.Node, section string) []string {
+	res := make([]string, 0)
+
+	list := mappingValue(doc, section)
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return res
+	}
+
+	for i := range list.Content {
+		if name := mappingValue(list.Content[i], "name"); name != nil {
+			res = append(res, name.Value)
+		}
+	}
+
+	return res
+}
+
+// Validate entry which is declared in boot config or registered.
+func (v *validator) validateEntryRef(path, section, name string) {
+	if len(name) < 1 {
+		return
+	}
+
+	if contains(v.declared[section], name) {
+		return
+	}
+
+	var found bool
+	switch section {
+	case "cert":
+		found = rkentry.GlobalAppCtx.GetCertEntry(name) != nil
+	case "logger":
+		found = rkentry.GlobalAppCtx.GetLoggerEntry(name) != nil
+	case "event":
+		found = rkentry.GlobalAppCtx.GetEventEntry(name) != nil
+	}
+
+	if !found {
+		v.add(path, "%s entry %s not found", section, name)
+	}
+}
+
+func (v *validator) validateElement(element *BootGinElement, path string) {
+	v.validateEntryRef(path+".certEntry", "cert", element.CertEntry)
+	v.validateEntryRef(path+".admin.certEntry", "cert", element.Admin.CertEntry)
+	v.validateEntryRef(path+".loggerEntry", "logger", element.LoggerEntry)
+	v.validateEntryRef(path+".eventEntry", "event", element.EventEntry)
+
+	for i := range element.Protocols {
+		switch strings.ToLower(element.Protocols[i]) {
+		case ProtocolHttp1, ProtocolH2C, ProtocolH2:
+		case ProtocolH3:
+			if len(element.CertEntry) < 1 {
+				v.add(fmt.Sprintf("%s.protocols[%d]", path, i), "%s requires certEntry", ProtocolH3)
+			}
+		default:
+			v.add(fmt.Sprintf("%s.protocols[%d]", path, i), "invalid protocol %s, options: [%s, %s, %s, %s]",
+				element.Protocols[i], ProtocolHttp1, ProtocolH2C, ProtocolH2, ProtocolH3)
+		}
+	}
+
+	if _, err := element.TLS.toOptions(); err != nil {
+		v.add(path+".tls", "%v", err)
+	}
+
+	if element.Reload.Enabled && len(element.Reload.Path) < 1 {
+		v.add(path+".reload.path", "path is required if reload enabled")
+	}
+
+	switch strings.ToLower(element.Middleware.ErrorModel) {
+	case "", "google", "amazon":
+	default:
+		v.add(path+".middleware.errorModel", "invalid error model %s, options: [%s]",
+			element.Middleware.ErrorModel, strings.Join(errorModels, ", "))
+	}
+
+	v.validateGzip(path+".middleware.gzip", &element.Middleware.Gzip)
+	v.validateRateLimit(path+".middleware.rateLimit", &element.Middleware.RateLimit)
+	v.validateBreaker(path+".middleware.breaker", &element.Middleware.Breaker)
+	v.validateConcurrency(path+".middleware.concurrency", &element.Middleware.Concurrency)
+
+	routes := make(map[string]bool)
+	for i := range element.Routes {
+		route := element.Routes[i]
+		routePath := fmt.Sprintf("%s.routes[%d]", path, i)
+
+		key, err := route.key()
+		if err != nil {
+			v.add(routePath+".path", "%v", err)
+		} else if routes[key] {
+			v.add(routePath, "duplicate route %s", key)
+		}
+		routes[key] = true
+
+		v.validateGzip(routePath+".middleware.gzip", route.Middleware.Gzip)
+		v.validateTimeout(routePath+".middleware.timeout", route.Middleware.Timeout)
+		v.validateRateLimit(routePath+".middleware.rateLimit", route.Middleware.RateLimit)
+		v.validateBreaker(routePath+".middleware.breaker", route.Middleware.Breaker)
+		v.validateConcurrency(routePath+".middleware.concurrency", route.Middleware.Concurrency)
+	}
+
+	if element.Proxy.Enabled {
+		for i := range element.Proxy.Rules {
+			if _, err := newProxy(element.Proxy.Rules[i]); err != nil {
+				v.add(fmt.Sprintf("%s.proxy.rules[%d]", path, i), "%v", err)
+			}
+		}
+	}
+}
+
+func (v *validator) validateGzip(path string, config *BootGzip) {
+	if config == nil {
+		return
+	}
+
+	levels := strings.Join(gzipLevels, ", ")
+	encodings := strings.Join(gzipEncodings, ", ")
+
+	if !rkgingzip.IsValidLevel(config.Level) {
+		v.add(path+".level", "invalid level %s, options: [%s]", config.Level, levels)
+	}
+
+	for encoding, level := range config.Levels {
+		if !rkgingzip.IsSupportedEncoding(encoding) {
+			v.add(path+".levels."+encoding, "unsupported encoding %s, options: [%s]", encoding, encodings)
+		} else if !rkgingzip.IsValidLevel(level) {
+			v.add(path+".levels."+encoding, "invalid level %s, options: [%s]", level, levels)
+		}
+	}
+
+	for i := range config.Encodings {
+		if !rkgingzip.IsSupportedEncoding(config.Encodings[i]) {
+			v.add(fmt.Sprintf("%s.encodings[%d]", path, i), "unsupported encoding %s, options: [%s]",
+				config.Encodings[i], encodings)
+		}
+	}
+}
+
+func (v *validator) validateTimeout(path string, config *rkgintout.BootConfig) {
+	if config == nil {
+		return
+	}
+
+	switch strings.ToLower(config.Mode) {
+	case "", rkgintout.ModeBuffered, rkgintout.ModeStreaming:
+	default:
+		v.add(path+".mode", "invalid mode %s, options: [%s, %s]",
+			config.Mode, rkgintout.ModeBuffered, rkgintout.ModeStreaming)
+	}
+}
+
+func (v *validator) validateRateLimit(path string, config *rkginlimit.BootConfig) {
+	if config == nil {
+		return
+	}
+
+	algorithm := config.Algorithm
+	switch algorithm {
+	case "":
+		algorithm = rkmidlimit.LeakyBucket
+	case rkmidlimit.LeakyBucket, rkginlimit.SlidingWindow, rkginlimit.GCRA:
+	default:
+		v.add(path+".algorithm", "invalid algorithm %s, options: [%s, %s, %s]",
+			algorithm, rkmidlimit.LeakyBucket, rkginlimit.SlidingWindow, rkginlimit.GCRA)
+		return
+	}
+
+	if !rkginlimit.IsValidKeyBy(config.KeyBy) {
+		v.add(path+".keyBy", "invalid keyBy %s, options: [%s, %s, %s, %s<name>, %s<claim>]", config.KeyBy,
+			rkginlimit.KeyByIP, rkginlimit.KeyByRoute, rkginlimit.KeyByAuth, rkginlimit.KeyByHeaderPrefix, rkginlimit.KeyByJwtPrefix)
+	}
+	if !rkginlimit.IsValidKeyBy(config.TierBy) {
+		v.add(path+".tierBy", "invalid tierBy %s, options: [%s, %s, %s, %s<name>, %s<claim>]", config.TierBy,
+			rkginlimit.KeyByIP, rkginlimit.KeyByRoute, rkginlimit.KeyByAuth, rkginlimit.KeyByHeaderPrefix, rkginlimit.KeyByJwtPrefix)
+	}
+
+	switch config.Store.Type {
+	case "", rkginlimit.StoreMemory, rkginlimit.StoreRedis:
+	default:
+		v.add(path+".store.type", "invalid type %s, options: [%s, %s]",
+			config.Store.Type, rkginlimit.StoreMemory, rkginlimit.StoreRedis)
+	}
+
+	if !config.Enabled {
+		return
+	}
+
+	keys := config.UnsupportedKeys()
+	for i := range keys {
+		v.add(path+"."+keys[i], "%s is not supported by algorithm %s, options: [%s, %s]",
+			keys[i], algorithm, rkginlimit.SlidingWindow, rkginlimit.GCRA)
+	}
+}
+
+func (v *validator) validateBreaker(path string, config *rkginbreaker.BootConfig) {
+	if config == nil {
+		return
+	}
+
+	switch config.Mode {
+	case "", rkginbreaker.ModeConsecutive, rkginbreaker.ModeErrorRatio:
+	default:
+		v.add(path+".mode", "invalid mode %s, options: [%s, %s]",
+			config.Mode, rkginbreaker.ModeConsecutive, rkginbreaker.ModeErrorRatio)
+	}
+}
+
+func (v *validator) validateConcurrency(path string, config *rkginconcurrency.BootConfig) {
+	if config == nil {
+		return
+	}
+
+	switch config.Algorithm {
+	case "", rkginconcurrency.AlgorithmAIMD, rkginconcurrency.AlgorithmGradient:
+	default:
+		v.add(path+".algorithm", "invalid algorithm %s, options: [%s, %s]",
+			config.Algorithm, rkginconcurrency.AlgorithmAIMD, rkginconcurrency.AlgorithmGradient)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgin
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const invalidBootConfig = `
+---
+cert:
+  - name: my-cert
+gin:
+  - name: greeter-invalid
+    port: 8080
+    enabled: true
+    unknown: value
+    certEntry: my-cert
+    loggerEntry: missing-logger
+    protocols: [http1, http4]
+    reload:
+      enabled: true
+    middleware:
+      errorModel: facebook
+      gzip:
+        level: fastest
+        encodings: [deflate]
+      timeout:
+        mode: async
+      rateLimit:
+        enabled: true
+        keyBy: ip
+    routes:
+      - method: GET
+      - path: /v1/greeter
+        method: GET
+        middleware:
+          gzip:
+            levels:
+              lz4: bestSpeed
+          timeout:
+            mode: async
+      - path: /v1/greeter
+        method: GET
+        middleware:
+          breaker:
+            mode: Consecutive
+          concurrency:
+            algorithm: vegas
+          rateLimit:
+            algorithm: gcra
+            keyBy: cookie:session
+            store:
+              type: etcd
+  - name: greeter-invalid
+    port: 8081
+`
+
+func TestValidate(t *testing.T) {
+	// with invalid YAML
+	err := Validate([]byte("gin: ["))
+	assert.NotNil(t, err)
+
+	// with valid config
+	assert.Nil(t, Validate([]byte(`
+---
+gin:
+  - name: greeter
+    port: 8080
+    enabled: true
+    protocols: [http1, h2c]
+    middleware:
+      gzip:
+        level: bestSpeed
+    routes:
+      - path: /v1/greeter
+        middleware:
+          timeout:
+            mode: streaming
+`)))
+
+	// with invalid config
+	err = Validate([]byte(invalidBootConfig))
+	assert.NotNil(t, err)
+
+	errs, ok := err.(ConfigErrors)
+	assert.True(t, ok)
+
+	paths := make(map[string]int)
+	for i := range errs {
+		paths[errs[i].Path] = errs[i].Line
+	}
+
+	expected := map[string]int{
+		"gin[0].unknown":                                    9,
+		"gin[0].loggerEntry":                                11,
+		"gin[0].protocols[1]":                               12,
+		"gin[0].reload.path":                                13,
+		"gin[0].middleware.errorModel":                      16,
+		"gin[0].middleware.gzip.level":                      18,
+		"gin[0].middleware.gzip.encodings[0]":               19,
+		"gin[0].middleware.timeout.mode":                    21,
+		"gin[0].middleware.rateLimit.keyBy":                 24,
+		"gin[0].routes[0].path":                             26,
+		"gin[0].routes[1].middleware.gzip.levels.lz4":       32,
+		"gin[0].routes[1].middleware.timeout.mode":          34,
+		"gin[0].routes[2]":                                  35,
+		"gin[0].routes[2].middleware.breaker.mode":          39,
+		"gin[0].routes[2].middleware.concurrency.algorithm": 41,
+		"gin[0].routes[2].middleware.rateLimit.keyBy":       44,
+		"gin[0].routes[2].middleware.rateLimit.store.type":  46,
+		"gin[1].name":                                       47,
+	}
+	assert.Len(t, errs, len(expected))
+	for k, v := range expected {
+		line, ok := paths[k]
+		assert.True(t, ok, k)
+		assert.Equal(t, v, line, k)
+	}
+
+	// sorted by line
+	for i := 1; i < len(errs); i++ {
+		assert.LessOrEqual(t, errs[i-1].Line, errs[i].Line)
+	}
+
+	assert.Contains(t, err.Error(), "18 problems found")
+	assert.Contains(t, err.Error(), "gin[0].middleware.timeout.mode (line 21): unknown key mode")
+	assert.Contains(t, err.Error(), "gin[0].routes[1].middleware.timeout.mode (line 34): invalid mode async")
+	assert.Contains(t, err.Error(), "gin[0].routes[2].middleware.breaker.mode (line 39): invalid mode Consecutive")
+}
+
+func TestConfigErrors_Of(t *testing.T) {
+	errs := ConfigErrors{
+		{Path: ""},
+		{Path: "gin"},
+		{Path: "gin[0].name"},
+		{Path: "gin[1]"},
+		{Path: "gin[10].name"},
+	}
+
+	assert.Len(t, errs.of(0), 3)
+	assert.Len(t, errs.of(1), 3)
+	assert.Len(t, errs.of(10), 3)
+	assert.Len(t, errs.of(2), 2)
+}
+
+func TestRegisterGinEntryYAML_WithStrict(t *testing.T) {
+	config := `
+---
+gin:
+  - name: greeter-strict
+    port: 8080
+    enabled: true
+    strict: %s
+    middleware:
+      gzip:
+        level: fastest
+`
+	// without strict, problems would be logged only
+	assert.NotPanics(t, func() {
+		RegisterGinEntryYAML([]byte(fmt.Sprintf(config, "false")))
+	})
+
+	// with strict
+	assert.Panics(t, func() {
+		RegisterGinEntryYAML([]byte(fmt.Sprintf(config, "true")))
+	})
+}
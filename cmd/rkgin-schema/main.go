@@ -0,0 +1,83 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Command rkgin-schema prints JSON Schema of gin section in boot config, or validates boot config files.
+//
+//	rkgin-schema -o boot.schema.json
+//	rkgin-schema -validate boot.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/rookie-ninja/rk-gin/v2/boot"
+	"io"
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run command with args, exit code would be returned.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rkgin-schema", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "", "write JSON Schema to file instead of stdout")
+	validate := flags.Bool("validate", false, "validate boot config files in args instead of printing JSON Schema")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *validate {
+		return validateFiles(flags.Args(), stdout, stderr)
+	}
+
+	schema, err := rkgin.JSONSchema()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if len(*output) > 0 {
+		if err := os.WriteFile(*output, append(schema, '\n'), 0644); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintln(stdout, string(schema))
+	return 0
+}
+
+// Validate boot config files, problems would be printed with file name.
+func validateFiles(files []string, stdout, stderr io.Writer) int {
+	if len(files) < 1 {
+		fmt.Fprintln(stderr, "boot config file is required")
+		return 2
+	}
+
+	code := 0
+	for i := range files {
+		raw, err := os.ReadFile(files[i])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = 1
+			continue
+		}
+
+		if err := rkgin.Validate(raw); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", files[i], err)
+			code = 1
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s: ok\n", files[i])
+	}
+
+	return code
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	// with invalid flag
+	assert.Equal(t, 2, run([]string{"-unknown"}, stdout, stderr))
+
+	// print to stdout
+	assert.Equal(t, 0, run([]string{}, stdout, stderr))
+	assert.True(t, json.Valid(stdout.Bytes()))
+
+	// write to file
+	output := path.Join(t.TempDir(), "boot.schema.json")
+	assert.Equal(t, 0, run([]string{"-o", output}, stdout, stderr))
+	raw, err := os.ReadFile(output)
+	assert.Nil(t, err)
+	assert.True(t, json.Valid(raw))
+
+	// write to invalid path
+	assert.Equal(t, 1, run([]string{"-o", path.Join(t.TempDir(), "missing", "boot.schema.json")}, stdout, stderr))
+}
+
+func TestRun_WithValidate(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	dir := t.TempDir()
+
+	valid := path.Join(dir, "valid.yaml")
+	assert.Nil(t, os.WriteFile(valid, []byte("gin:\n  - name: greeter\n    port: 8080\n"), 0644))
+	invalid := path.Join(dir, "invalid.yaml")
+	assert.Nil(t, os.WriteFile(invalid, []byte("gin:\n  - name: greeter\n    port: 8080\n    unknown: true\n"), 0644))
+
+	// without files
+	assert.Equal(t, 2, run([]string{"-validate"}, stdout, stderr))
+
+	// with valid file
+	assert.Equal(t, 0, run([]string{"-validate", valid}, stdout, stderr))
+	assert.Contains(t, stdout.String(), "valid.yaml: ok")
+
+	// with invalid and missing file
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"-validate", invalid, path.Join(dir, "missing.yaml")}, stdout, stderr))
+	assert.Contains(t, stderr.String(), "gin[0].unknown (line 4): unknown key unknown")
+	assert.Contains(t, stderr.String(), "missing.yaml")
+}
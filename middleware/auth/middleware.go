@@ -7,15 +7,27 @@
 package rkginauth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/gin-gonic/gin"
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rookie-ninja/rk-entry/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+)
+
+const (
+	// prefix of principal of API key, distinguish from basic auth user
+	apiKeyPrincipalPrefix = "apikey:"
+	// length of hex encoded SHA-256 prefix in principal of API key
+	apiKeyPrincipalLen = 16
 )
 
 // Middleware validate bellow authorization.
 //
 // 1: Basic Auth: The client sends HTTP requests with the Authorization header that contains the word Basic, followed by a space and a base64-encoded(non-encrypted) string username: password.
 // 2: API key: An API key is a token that a client provides when making API calls. With API key auth, you send a key-value pair to the API in the request headers.
+//
+// Authenticated basic auth user or principal of API key could be read with rkginctx.GetAuthPrincipal().
 func Middleware(opts ...rkmidauth.Option) gin.HandlerFunc {
 	set := rkmidauth.NewOptionSet(opts...)
 
@@ -36,6 +48,44 @@ func Middleware(opts ...rkmidauth.Option) gin.HandlerFunc {
 		}
 
 		// case 2: authorized, call next
+		if principal := getPrincipal(set, beforeCtx, ctx); len(principal) > 0 {
+			ctx.Set(rkginctx.AuthPrincipalKey, principal)
+		}
+
 		ctx.Next()
 	}
 }
+
+// Get basic auth user or principal of API key which passed authorization, basic auth has higher priority.
+func getPrincipal(set rkmidauth.OptionSetInterface, beforeCtx *rkmidauth.BeforeCtx, ctx *gin.Context) string {
+	if set.ShouldIgnore(beforeCtx.Input.UrlPath) {
+		return ""
+	}
+
+	// basic auth is validated first, make sure it passed since API key might be the one authorized
+	if user, _, ok := ctx.Request.BasicAuth(); ok {
+		basicCtx := rkmidauth.NewBeforeCtx()
+		basicCtx.Input.UrlPath = beforeCtx.Input.UrlPath
+		basicCtx.Input.BasicAuthHeader = beforeCtx.Input.BasicAuthHeader
+		set.Before(basicCtx)
+
+		if basicCtx.Output.ErrResp == nil {
+			return user
+		}
+	}
+
+	if len(beforeCtx.Input.ApiKeyHeader) < 1 {
+		return ""
+	}
+
+	return ApiKeyPrincipal(beforeCtx.Input.ApiKeyHeader)
+}
+
+// ApiKeyPrincipal returns principal of API key, which is apikey: followed by prefix of hex encoded SHA-256 of key.
+//
+// API key itself is a secret, principal is used instead wherever identity is visible,
+// like rate limit tiers, keys in redis and idempotency keys.
+func ApiKeyPrincipal(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return apiKeyPrincipalPrefix + hex.EncodeToString(sum[:])[:apiKeyPrincipalLen]
+}
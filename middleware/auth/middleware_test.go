@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	rkmid "github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rookie-ninja/rk-entry/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -43,6 +44,37 @@ func TestInterceptor(t *testing.T) {
 	assert.Equal(t, http.StatusOK, ctx.Writer.Status())
 }
 
+func TestMiddleware_WithPrincipal(t *testing.T) {
+	inter := Middleware(rkmidauth.WithBasicAuth("", "ut-user:ut-pass"), rkmidauth.WithApiKeyAuth("ut-key"))
+
+	// with basic auth
+	ctx := newCtx()
+	ctx.Request.SetBasicAuth("ut-user", "ut-pass")
+	inter(ctx)
+	assert.False(t, ctx.IsAborted())
+	assert.Equal(t, "ut-user", rkginctx.GetAuthPrincipal(ctx))
+
+	// with API key and invalid basic auth
+	ctx = newCtx()
+	ctx.Request.SetBasicAuth("ut-user", "ut-wrong")
+	ctx.Request.Header.Set(rkmid.HeaderApiKey, "ut-key")
+	inter(ctx)
+	assert.False(t, ctx.IsAborted())
+	assert.Equal(t, ApiKeyPrincipal("ut-key"), rkginctx.GetAuthPrincipal(ctx))
+	assert.NotContains(t, rkginctx.GetAuthPrincipal(ctx), "ut-key")
+
+	// without auth enabled
+	ctx = newCtx()
+	Middleware()(ctx)
+	assert.Empty(t, rkginctx.GetAuthPrincipal(ctx))
+}
+
+func TestApiKeyPrincipal(t *testing.T) {
+	// echo -n ut-key | sha256sum
+	assert.Equal(t, "apikey:ca084a11a659646b", ApiKeyPrincipal("ut-key"))
+	assert.NotEqual(t, ApiKeyPrincipal("ut-key"), ApiKeyPrincipal("ut-other"))
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.ReleaseMode)
 	os.Exit(m.Run())
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package rkginbodylimit is a request body size limit middleware for gin framework
+package rkginbodylimit
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"io"
+	"net/http"
+)
+
+// ErrBodyTooLarge is returned while reading request body exceeded limit
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// Middleware Add body limit interceptors.
+//
+// Requests with Content-Length larger than limit would be rejected with 413 directly,
+// otherwise reading body would fail with ErrBodyTooLarge once limit exceeded,
+// and 413 would be returned if handler has not written response.
+//
+// Middleware which decompresses body, like rkgingzip, should respect limit with GetLimit and Abort.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) {
+			ctx.Next()
+			return
+		}
+
+		ctx.Set(optionSetKey, set)
+
+		if ctx.Request.ContentLength > set.MaxBytes {
+			Abort(ctx)
+			return
+		}
+
+		var reader *limitedReader
+		if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody {
+			reader = &limitedReader{ReadCloser: ctx.Request.Body, remaining: set.MaxBytes}
+			ctx.Request.Body = reader
+		}
+
+		ctx.Next()
+
+		if reader != nil && reader.exceeded && !ctx.IsAborted() {
+			if ctx.Writer.Written() {
+				set.reject()
+				return
+			}
+			Abort(ctx)
+		}
+	}
+}
+
+// GetLimit returns max size of request body in bytes, zero if body limit middleware not enabled.
+func GetLimit(ctx *gin.Context) int64 {
+	if set := getOptionSet(ctx); set != nil {
+		return set.MaxBytes
+	}
+
+	return 0
+}
+
+// Abort request with 413 and record rejection.
+func Abort(ctx *gin.Context) {
+	if set := getOptionSet(ctx); set != nil {
+		set.reject()
+	}
+
+	resp := rkmid.GetErrorBuilder().New(http.StatusRequestEntityTooLarge, "Request body too large")
+	ctx.AbortWithStatusJSON(resp.Code(), resp)
+}
+
+func getOptionSet(ctx *gin.Context) *optionSet {
+	if ctx == nil {
+		return nil
+	}
+
+	if raw, ok := ctx.Get(optionSetKey); ok {
+		if set, ok := raw.(*optionSet); ok {
+			return set
+		}
+	}
+
+	return nil
+}
+
+// Record rejection.
+func (set *optionSet) reject() {
+	if set.rejected != nil {
+		set.rejected.WithLabelValues(set.EntryName).Inc()
+	}
+}
+
+// limitedReader returns ErrBodyTooLarge once more than remaining bytes read
+type limitedReader struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+// Read reads at most remaining bytes from body.
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+
+	// read one more byte to detect exceeding
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	if int64(n) > r.remaining {
+		r.exceeded = true
+		n = int(r.remaining)
+		r.remaining = 0
+		return n, ErrBodyTooLarge
+	}
+
+	r.remaining -= int64(n)
+	return n, err
+}
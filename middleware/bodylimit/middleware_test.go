@@ -0,0 +1,109 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbodylimit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// body without Content-Length
+type chunkedReader struct {
+	io.Reader
+}
+
+func TestMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithMaxBytes(5),
+		WithRegisterer(prometheus.NewRegistry())))
+	router.POST("/ut-path", func(ctx *gin.Context) {
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			// leave response to middleware
+			return
+		}
+		ctx.String(http.StatusOK, string(body))
+	})
+	router.POST("/ut-written", func(ctx *gin.Context) {
+		if _, err := io.ReadAll(ctx.Request.Body); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+		}
+	})
+	set := optionsMap["ut-entry"]
+
+	// 1: within limit
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ut-path", strings.NewReader("12345")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "12345", w.Body.String())
+
+	// 2: Content-Length exceeded limit
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ut-path", strings.NewReader("123456")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, float64(1), testutil.ToFloat64(set.rejected.WithLabelValues("ut-entry")))
+
+	// 3: body without Content-Length exceeded limit
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/ut-path", chunkedReader{strings.NewReader("123456")})
+	assert.Equal(t, int64(-1), req.ContentLength)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, float64(2), testutil.ToFloat64(set.rejected.WithLabelValues("ut-entry")))
+
+	// 4: response written by handler would be kept
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ut-written", chunkedReader{strings.NewReader("123456")}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ErrBodyTooLarge.Error(), w.Body.String())
+	assert.Equal(t, float64(3), testutil.ToFloat64(set.rejected.WithLabelValues("ut-entry")))
+}
+
+func TestMiddleware_WithSkipper(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/ut-path", strings.NewReader("123456"))
+	Middleware(
+		WithMaxBytes(5),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}))(ctx)
+
+	assert.False(t, ctx.IsAborted())
+	body, err := io.ReadAll(ctx.Request.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "123456", string(body))
+}
+
+func TestGetLimit(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Equal(t, int64(0), GetLimit(ctx))
+
+	ctx.Set(optionSetKey, newOptionSet(WithMaxBytes(5)))
+	assert.Equal(t, int64(5), GetLimit(ctx))
+}
+
+func TestAbort(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	Abort(ctx)
+	assert.True(t, ctx.IsAborted())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.ReleaseMode)
+	os.Exit(m.Run())
+}
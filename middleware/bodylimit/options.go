@@ -0,0 +1,159 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbodylimit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rs/xid"
+	"strings"
+)
+
+const (
+	defaultMaxBytes = 4 << 20
+
+	// key of optionSet in gin.Context
+	optionSetKey = "rkBodyLimit"
+)
+
+// Interceptor would distinguish body limit set based on.
+var (
+	optionsMap     = make(map[string]*optionSet)
+	defaultSkipper = func(*gin.Context) bool {
+		return false
+	}
+)
+
+// BootConfig for YAML
+type BootConfig struct {
+	Enabled  bool     `yaml:"enabled" json:"enabled"`
+	Ignore   []string `yaml:"ignore" json:"ignore"`
+	MaxBytes int64    `yaml:"maxBytes" json:"maxBytes"`
+}
+
+// ToOptions convert BootConfig into Option list
+func ToOptions(config *BootConfig, entryName, entryType string, registerer prometheus.Registerer) []Option {
+	opts := make([]Option, 0)
+
+	if config.Enabled {
+		opts = append(opts,
+			WithEntryNameAndType(entryName, entryType),
+			WithMaxBytes(config.MaxBytes),
+			WithPathToIgnore(config.Ignore...),
+			WithRegisterer(registerer))
+	}
+
+	return opts
+}
+
+// Create new optionSet with options.
+func newOptionSet(opts ...Option) *optionSet {
+	set := &optionSet{
+		EntryName:    xid.New().String(),
+		EntryType:    "",
+		Skipper:      defaultSkipper,
+		MaxBytes:     defaultMaxBytes,
+		ignorePrefix: make([]string, 0),
+	}
+
+	for i := range opts {
+		opts[i](set)
+	}
+
+	if set.registerer != nil {
+		set.rejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rk",
+			Subsystem: "body_limit",
+			Name:      "rejected_total",
+			Help:      "Total number of requests rejected since body exceeded limit",
+		}, []string{"entryName"})
+
+		if err := set.registerer.Register(set.rejected); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				set.rejected = are.ExistingCollector.(*prometheus.CounterVec)
+			} else {
+				set.rejected = nil
+			}
+		}
+	}
+
+	if _, ok := optionsMap[set.EntryName]; !ok {
+		optionsMap[set.EntryName] = set
+	}
+
+	return set
+}
+
+// Options which is used while initializing extension interceptor
+type optionSet struct {
+	EntryName    string
+	EntryType    string
+	Skipper      Skipper
+	MaxBytes     int64
+	ignorePrefix []string
+	registerer   prometheus.Registerer
+	rejected     *prometheus.CounterVec
+}
+
+// ShouldIgnore determine whether body limit should be ignored based on path
+func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		for i := range set.ignorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, set.ignorePrefix[i]) {
+				return true
+			}
+		}
+
+		return rkmid.ShouldIgnoreGlobal(ctx.Request.URL.Path)
+	}
+
+	return false
+}
+
+// Option if for middleware options while creating middleware
+type Option func(*optionSet)
+
+// WithEntryNameAndType provide entry name and entry type.
+func WithEntryNameAndType(entryName, entryType string) Option {
+	return func(opt *optionSet) {
+		opt.EntryName = entryName
+		opt.EntryType = entryType
+	}
+}
+
+// WithSkipper provide skipper.
+func WithSkipper(skip Skipper) Option {
+	return func(opt *optionSet) {
+		opt.Skipper = skip
+	}
+}
+
+// WithPathToIgnore provide path prefix to ignore middleware
+func WithPathToIgnore(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.ignorePrefix = append(opt.ignorePrefix, prefix...)
+	}
+}
+
+// WithMaxBytes provide max size of request body in bytes, 4MB would be used if missing.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(opt *optionSet) {
+		if maxBytes > 0 {
+			opt.MaxBytes = maxBytes
+		}
+	}
+}
+
+// WithRegisterer provide prometheus.Registerer to export rejections.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(opt *optionSet) {
+		opt.registerer = registerer
+	}
+}
+
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbodylimit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewOptionSet(t *testing.T) {
+	// without options
+	set := newOptionSet()
+	assert.NotEmpty(t, set.EntryName)
+	assert.Equal(t, int64(defaultMaxBytes), set.MaxBytes)
+	assert.Nil(t, set.rejected)
+
+	// with options
+	set = newOptionSet(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithMaxBytes(10),
+		WithRegisterer(prometheus.NewRegistry()),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}))
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "ut-type", set.EntryType)
+	assert.Equal(t, int64(10), set.MaxBytes)
+	assert.NotNil(t, set.rejected)
+	assert.True(t, set.Skipper(nil))
+
+	// with invalid max bytes
+	set = newOptionSet(WithMaxBytes(-1))
+	assert.Equal(t, int64(defaultMaxBytes), set.MaxBytes)
+}
+
+func TestNewOptionSet_WithSameRegisterer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	set1 := newOptionSet(WithRegisterer(registry))
+	set2 := newOptionSet(WithRegisterer(registry))
+	assert.Equal(t, set1.rejected, set2.rejected)
+}
+
+func TestOptionSet_ShouldIgnore(t *testing.T) {
+	set := newOptionSet(WithPathToIgnore("/ut-ignore"))
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/ut-ignore", nil)
+	assert.True(t, set.ShouldIgnore(ctx))
+
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/ut-path", nil)
+	assert.False(t, set.ShouldIgnore(ctx))
+}
+
+func TestToOptions(t *testing.T) {
+	config := &BootConfig{
+		Enabled:  false,
+		MaxBytes: 10,
+	}
+
+	// with disabled
+	assert.Empty(t, ToOptions(config, "", "", nil))
+
+	// with enabled
+	config.Enabled = true
+	set := newOptionSet(ToOptions(config, "ut-entry", "ut-type", prometheus.NewRegistry())...)
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, int64(10), set.MaxBytes)
+	assert.NotNil(t, set.rejected)
+}
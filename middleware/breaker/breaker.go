@@ -0,0 +1,147 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// StateClosed requests are allowed
+	StateClosed = 0
+	// StateOpen requests are rejected
+	StateOpen = 1
+	// StateHalfOpen limited probes are allowed
+	StateHalfOpen = 2
+)
+
+// breaker of one path.
+type breaker struct {
+	set         *optionSet
+	path        string
+	state       int
+	openedAt    time.Time
+	windowStart time.Time
+	total       int
+	failures    int
+	consecutive int
+	probes      int
+	successes   int
+	lock        sync.Mutex
+}
+
+func newBreaker(set *optionSet, path string) *breaker {
+	b := &breaker{
+		set:         set,
+		path:        path,
+		windowStart: set.now(),
+	}
+	b.observe()
+
+	return b
+}
+
+// Allow determine whether request could go through, returns remaining duration of open state if rejected.
+func (b *breaker) allow() (bool, time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.set.now()
+
+	switch b.state {
+	case StateOpen:
+		if remaining := b.openedAt.Add(b.set.OpenTimeout).Sub(now); remaining > 0 {
+			return false, remaining
+		}
+		b.transit(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.set.HalfOpenRequests {
+			return false, 0
+		}
+		b.probes++
+		return true, 0
+	}
+
+	if now.Sub(b.windowStart) >= b.set.Window {
+		b.resetCounts(now)
+	}
+
+	return true, 0
+}
+
+// Record result of request.
+func (b *breaker) done(failed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.transit(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.set.HalfOpenRequests {
+			b.transit(StateClosed)
+		}
+		return
+	case StateOpen:
+		// requests allowed before breaker tripped
+		return
+	}
+
+	b.total++
+	if failed {
+		b.failures++
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+
+	if b.shouldTrip() {
+		b.transit(StateOpen)
+	}
+}
+
+func (b *breaker) shouldTrip() bool {
+	if b.set.Mode == ModeErrorRatio {
+		return b.total >= b.set.MinRequests && float64(b.failures)/float64(b.total) >= b.set.ErrorRatio
+	}
+
+	return b.consecutive >= b.set.ConsecutiveFailures
+}
+
+// Move to state, counters would be reset.
+func (b *breaker) transit(state int) {
+	now := b.set.now()
+
+	b.state = state
+	b.probes = 0
+	b.successes = 0
+	b.resetCounts(now)
+
+	if state == StateOpen {
+		b.openedAt = now
+	}
+
+	b.observe()
+}
+
+func (b *breaker) resetCounts(now time.Time) {
+	b.windowStart = now
+	b.total = 0
+	b.failures = 0
+	b.consecutive = 0
+}
+
+// Export state into prometheus.
+func (b *breaker) observe() {
+	if b.set.state != nil {
+		b.set.state.WithLabelValues(b.set.EntryName, b.path).Set(float64(b.state))
+	}
+}
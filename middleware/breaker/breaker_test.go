@@ -0,0 +1,104 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbreaker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// Create breaker with fake clock.
+func newTestBreaker(opts ...Option) (*breaker, *time.Time) {
+	now := time.Now()
+	set := newOptionSet(opts...)
+	set.now = func() time.Time {
+		return now
+	}
+
+	return newBreaker(set, "/ut-path"), &now
+}
+
+func TestBreaker_Consecutive(t *testing.T) {
+	b, now := newTestBreaker(WithConsecutiveFailures(2), WithOpenTimeout(time.Second))
+
+	// success resets consecutive failures
+	b.done(true)
+	b.done(false)
+	b.done(true)
+	assert.Equal(t, StateClosed, b.state)
+
+	// trip
+	b.done(true)
+	assert.Equal(t, StateOpen, b.state)
+
+	ok, retryAfter := b.allow()
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// half-open after open timeout, only one probe allowed
+	*now = now.Add(time.Second)
+	ok, _ = b.allow()
+	assert.True(t, ok)
+	assert.Equal(t, StateHalfOpen, b.state)
+	ok, _ = b.allow()
+	assert.False(t, ok)
+
+	// failed probe opens breaker again
+	b.done(true)
+	assert.Equal(t, StateOpen, b.state)
+
+	// succeeded probe closes breaker
+	*now = now.Add(time.Second)
+	ok, _ = b.allow()
+	assert.True(t, ok)
+	b.done(false)
+	assert.Equal(t, StateClosed, b.state)
+}
+
+func TestBreaker_ErrorRatio(t *testing.T) {
+	b, now := newTestBreaker(WithMode(ModeErrorRatio), WithErrorRatio(0.5, 4), WithWindow(time.Second))
+
+	// not enough requests
+	b.done(true)
+	b.done(true)
+	b.done(true)
+	assert.Equal(t, StateClosed, b.state)
+
+	// window expired
+	*now = now.Add(time.Second)
+	ok, _ := b.allow()
+	assert.True(t, ok)
+	assert.Equal(t, 0, b.total)
+
+	// ratio reached
+	b.done(false)
+	b.done(false)
+	b.done(true)
+	assert.Equal(t, StateClosed, b.state)
+	b.done(true)
+	assert.Equal(t, StateOpen, b.state)
+}
+
+func TestBreaker_HalfOpenRequests(t *testing.T) {
+	b, now := newTestBreaker(WithConsecutiveFailures(1), WithHalfOpenRequests(2))
+
+	b.done(true)
+	assert.Equal(t, StateOpen, b.state)
+
+	*now = now.Add(defaultOpenTimeout)
+	ok, _ := b.allow()
+	assert.True(t, ok)
+	ok, _ = b.allow()
+	assert.True(t, ok)
+	ok, _ = b.allow()
+	assert.False(t, ok)
+
+	b.done(false)
+	assert.Equal(t, StateHalfOpen, b.state)
+	b.done(false)
+	assert.Equal(t, StateClosed, b.state)
+}
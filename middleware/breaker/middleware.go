@@ -0,0 +1,52 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package rkginbreaker is a circuit breaker middleware for gin framework
+package rkginbreaker
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// Middleware Add circuit breaker interceptors.
+//
+// Every path would have its own breaker, requests would be rejected with 503 while breaker is open.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) {
+			ctx.Next()
+			return
+		}
+
+		b := set.getBreaker(ctx)
+
+		if ok, retryAfter := b.allow(); !ok {
+			if retryAfter > 0 {
+				ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			}
+			resp := rkmid.GetErrorBuilder().New(http.StatusServiceUnavailable, "Circuit breaker is open")
+			ctx.AbortWithStatusJSON(resp.Code(), resp)
+			return
+		}
+
+		// treat panic as failure
+		failed := true
+		defer func() {
+			b.done(failed)
+		}()
+
+		ctx.Next()
+
+		failed = set.IsFailure(ctx.Writer.Status())
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbreaker
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithConsecutiveFailures(2),
+		WithRegisterer(prometheus.NewRegistry())))
+	router.GET("/ut-path/:id", func(ctx *gin.Context) {
+		ctx.Status(http.StatusInternalServerError)
+	})
+	router.GET("/ut-other", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	// parameterised routes share the same breaker
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-path/1").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-path/2").Code)
+
+	// open
+	w := serve(router, "/ut-path/3")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Circuit breaker is open")
+
+	set := optionsMap["ut-entry"]
+	assert.Equal(t, float64(StateOpen), testutil.ToFloat64(set.state.WithLabelValues("ut-entry", "/ut-path/:id")))
+
+	// other paths are not affected
+	assert.Equal(t, http.StatusOK, serve(router, "/ut-other").Code)
+}
+
+func TestMiddleware_WithTimeout(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(WithConsecutiveFailures(2)))
+	router.GET("/ut-path", func(ctx *gin.Context) {
+		// as timeout middleware does
+		ctx.Status(http.StatusRequestTimeout)
+	})
+
+	// timed out requests are treated as failure
+	assert.Equal(t, http.StatusRequestTimeout, serve(router, "/ut-path").Code)
+	assert.Equal(t, http.StatusRequestTimeout, serve(router, "/ut-path").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(router, "/ut-path").Code)
+}
+
+func TestMiddleware_WithIgnore(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(WithConsecutiveFailures(1), WithPathToIgnore("/ut-path")))
+	router.GET("/ut-path/:id", func(ctx *gin.Context) {
+		ctx.Status(http.StatusInternalServerError)
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-path/1").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-path/1").Code)
+}
+
+func TestMiddleware_WithUnmatchedRoute(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(WithConsecutiveFailures(2), WithFailureStatusCodes(http.StatusNotFound)))
+	router.GET("/ut-other", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	// unmatched paths share the same breaker
+	assert.Equal(t, http.StatusNotFound, serve(router, "/ut-unknown-1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, "/ut-unknown-2").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(router, "/ut-unknown-3").Code)
+
+	// matched paths are not affected
+	assert.Equal(t, http.StatusOK, serve(router, "/ut-other").Code)
+}
+
+func TestMiddleware_WithPanic(t *testing.T) {
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(ctx *gin.Context, err interface{}) {
+		ctx.AbortWithStatus(http.StatusOK)
+	}))
+	router.Use(Middleware(WithConsecutiveFailures(1)))
+	router.GET("/ut-panic", func(ctx *gin.Context) {
+		panic("ut-panic")
+	})
+
+	// panic is treated as failure even if recovered with 200
+	assert.Equal(t, http.StatusOK, serve(router, "/ut-panic").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(router, "/ut-panic").Code)
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.ReleaseMode)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbreaker
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rs/xid"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ModeConsecutive trip breaker once consecutive failures reached threshold
+	ModeConsecutive = "consecutive"
+	// ModeErrorRatio trip breaker once ratio of failures in window reached threshold
+	ModeErrorRatio = "errorRatio"
+
+	defaultConsecutiveFailures = 5
+	defaultErrorRatio          = 0.5
+	defaultMinRequests         = 20
+	defaultWindow              = 10 * time.Second
+	defaultOpenTimeout         = 30 * time.Second
+	defaultHalfOpenRequests    = 1
+
+	// key of breaker shared by unmatched routes
+	unmatchedPath = "*"
+)
+
+// Interceptor would distinguish breaker set based on.
+var (
+	optionsMap     = make(map[string]*optionSet)
+	defaultSkipper = func(*gin.Context) bool {
+		return false
+	}
+)
+
+// BootConfig for YAML
+type BootConfig struct {
+	Enabled             bool     `yaml:"enabled" json:"enabled"`
+	Ignore              []string `yaml:"ignore" json:"ignore"`
+	Mode                string   `yaml:"mode" json:"mode"`
+	ConsecutiveFailures int      `yaml:"consecutiveFailures" json:"consecutiveFailures"`
+	ErrorRatio          float64  `yaml:"errorRatio" json:"errorRatio"`
+	MinRequests         int      `yaml:"minRequests" json:"minRequests"`
+	WindowMs            int      `yaml:"windowMs" json:"windowMs"`
+	OpenTimeoutMs       int      `yaml:"openTimeoutMs" json:"openTimeoutMs"`
+	HalfOpenRequests    int      `yaml:"halfOpenRequests" json:"halfOpenRequests"`
+	FailureStatusCodes  []int    `yaml:"failureStatusCodes" json:"failureStatusCodes"`
+}
+
+// ToOptions convert BootConfig into Option list
+func ToOptions(config *BootConfig, entryName, entryType string, registerer prometheus.Registerer) []Option {
+	opts := make([]Option, 0)
+
+	if config.Enabled {
+		opts = append(opts,
+			WithEntryNameAndType(entryName, entryType),
+			WithMode(config.Mode),
+			WithConsecutiveFailures(config.ConsecutiveFailures),
+			WithErrorRatio(config.ErrorRatio, config.MinRequests),
+			WithWindow(time.Duration(config.WindowMs)*time.Millisecond),
+			WithOpenTimeout(time.Duration(config.OpenTimeoutMs)*time.Millisecond),
+			WithHalfOpenRequests(config.HalfOpenRequests),
+			WithFailureStatusCodes(config.FailureStatusCodes...),
+			WithPathToIgnore(config.Ignore...),
+			WithRegisterer(registerer))
+	}
+
+	return opts
+}
+
+// Create new optionSet with options.
+func newOptionSet(opts ...Option) *optionSet {
+	set := &optionSet{
+		EntryName:           xid.New().String(),
+		EntryType:           "",
+		Skipper:             defaultSkipper,
+		Mode:                ModeConsecutive,
+		ConsecutiveFailures: defaultConsecutiveFailures,
+		ErrorRatio:          defaultErrorRatio,
+		MinRequests:         defaultMinRequests,
+		Window:              defaultWindow,
+		OpenTimeout:         defaultOpenTimeout,
+		HalfOpenRequests:    defaultHalfOpenRequests,
+		failureCodes:        make(map[int]bool),
+		ignorePrefix:        make([]string, 0),
+		breakers:            make(map[string]*breaker),
+		now:                 time.Now,
+	}
+
+	for i := range opts {
+		opts[i](set)
+	}
+
+	if set.registerer != nil {
+		set.state = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "rk",
+			Subsystem: "breaker",
+			Name:      "state",
+			Help:      "State of circuit breaker, 0: closed, 1: open, 2: half-open",
+		}, []string{"entryName", "path"})
+
+		if err := set.registerer.Register(set.state); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				set.state = are.ExistingCollector.(*prometheus.GaugeVec)
+			} else {
+				set.state = nil
+			}
+		}
+	}
+
+	if _, ok := optionsMap[set.EntryName]; !ok {
+		optionsMap[set.EntryName] = set
+	}
+
+	return set
+}
+
+// Options which is used while initializing extension interceptor
+type optionSet struct {
+	EntryName           string
+	EntryType           string
+	Skipper             Skipper
+	Mode                string
+	ConsecutiveFailures int
+	ErrorRatio          float64
+	MinRequests         int
+	Window              time.Duration
+	OpenTimeout         time.Duration
+	HalfOpenRequests    int
+	failureCodes        map[int]bool
+	ignorePrefix        []string
+	registerer          prometheus.Registerer
+	state               *prometheus.GaugeVec
+	breakers            map[string]*breaker
+	now                 func() time.Time
+	lock                sync.Mutex
+}
+
+// ShouldIgnore determine whether breaker should be ignored based on path
+func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		for i := range set.ignorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, set.ignorePrefix[i]) {
+				return true
+			}
+		}
+
+		return rkmid.ShouldIgnoreGlobal(ctx.Request.URL.Path)
+	}
+
+	return false
+}
+
+// IsFailure determine whether status code should be treated as failure,
+// 5xx and 408 returned by timeout middleware would be used by default.
+func (set *optionSet) IsFailure(code int) bool {
+	if len(set.failureCodes) < 1 {
+		return code >= http.StatusInternalServerError || code == http.StatusRequestTimeout
+	}
+
+	return set.failureCodes[code]
+}
+
+// Get breaker of path, parameterised routes share the same breaker, so do unmatched routes.
+func (set *optionSet) getBreaker(ctx *gin.Context) *breaker {
+	path := ctx.FullPath()
+	if len(path) < 1 {
+		path = unmatchedPath
+	}
+
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	if b, ok := set.breakers[path]; ok {
+		return b
+	}
+
+	b := newBreaker(set, path)
+	set.breakers[path] = b
+	return b
+}
+
+// Option if for middleware options while creating middleware
+type Option func(*optionSet)
+
+// WithEntryNameAndType provide entry name and entry type.
+func WithEntryNameAndType(entryName, entryType string) Option {
+	return func(opt *optionSet) {
+		opt.EntryName = entryName
+		opt.EntryType = entryType
+	}
+}
+
+// WithSkipper provide skipper.
+func WithSkipper(skip Skipper) Option {
+	return func(opt *optionSet) {
+		opt.Skipper = skip
+	}
+}
+
+// WithPathToIgnore provide path prefix to ignore middleware
+func WithPathToIgnore(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.ignorePrefix = append(opt.ignorePrefix, prefix...)
+	}
+}
+
+// WithMode provide trip mode, options: consecutive, errorRatio
+func WithMode(mode string) Option {
+	return func(opt *optionSet) {
+		switch mode {
+		case ModeConsecutive, ModeErrorRatio:
+			opt.Mode = mode
+		}
+	}
+}
+
+// WithConsecutiveFailures provide number of consecutive failures to trip breaker in consecutive mode.
+func WithConsecutiveFailures(failures int) Option {
+	return func(opt *optionSet) {
+		if failures > 0 {
+			opt.ConsecutiveFailures = failures
+		}
+	}
+}
+
+// WithErrorRatio provide ratio of failures and minimum requests in window to trip breaker in errorRatio mode.
+func WithErrorRatio(ratio float64, minRequests int) Option {
+	return func(opt *optionSet) {
+		if ratio > 0 && ratio <= 1 {
+			opt.ErrorRatio = ratio
+		}
+		if minRequests > 0 {
+			opt.MinRequests = minRequests
+		}
+	}
+}
+
+// WithWindow provide window of counting requests in errorRatio mode.
+func WithWindow(window time.Duration) Option {
+	return func(opt *optionSet) {
+		if window > 0 {
+			opt.Window = window
+		}
+	}
+}
+
+// WithOpenTimeout provide duration of open state before half-open probes allowed.
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(opt *optionSet) {
+		if timeout > 0 {
+			opt.OpenTimeout = timeout
+		}
+	}
+}
+
+// WithHalfOpenRequests provide number of probes allowed in half-open state,
+// breaker would be closed once all of them succeed.
+func WithHalfOpenRequests(requests int) Option {
+	return func(opt *optionSet) {
+		if requests > 0 {
+			opt.HalfOpenRequests = requests
+		}
+	}
+}
+
+// WithFailureStatusCodes provide status codes treated as failure, 5xx and 408 would be used if missing.
+func WithFailureStatusCodes(codes ...int) Option {
+	return func(opt *optionSet) {
+		for i := range codes {
+			opt.failureCodes[codes[i]] = true
+		}
+	}
+}
+
+// WithRegisterer provide prometheus.Registerer to export state of breakers.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(opt *optionSet) {
+		opt.registerer = registerer
+	}
+}
+
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
@@ -0,0 +1,104 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginbreaker
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewOptionSet(t *testing.T) {
+	// without options
+	set := newOptionSet()
+	assert.NotEmpty(t, set.EntryName)
+	assert.Equal(t, ModeConsecutive, set.Mode)
+	assert.Equal(t, defaultConsecutiveFailures, set.ConsecutiveFailures)
+	assert.Equal(t, defaultErrorRatio, set.ErrorRatio)
+	assert.Equal(t, defaultMinRequests, set.MinRequests)
+	assert.Equal(t, defaultWindow, set.Window)
+	assert.Equal(t, defaultOpenTimeout, set.OpenTimeout)
+	assert.Equal(t, defaultHalfOpenRequests, set.HalfOpenRequests)
+	assert.Nil(t, set.state)
+
+	// with options
+	set = newOptionSet(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithMode(ModeErrorRatio),
+		WithConsecutiveFailures(3),
+		WithErrorRatio(0.2, 10),
+		WithWindow(time.Second),
+		WithOpenTimeout(time.Second),
+		WithHalfOpenRequests(2),
+		WithFailureStatusCodes(http.StatusTooManyRequests),
+		WithPathToIgnore("/ut-ignore"),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}),
+		WithRegisterer(prometheus.NewRegistry()))
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "ut-type", set.EntryType)
+	assert.Equal(t, ModeErrorRatio, set.Mode)
+	assert.Equal(t, 3, set.ConsecutiveFailures)
+	assert.Equal(t, 0.2, set.ErrorRatio)
+	assert.Equal(t, 10, set.MinRequests)
+	assert.Equal(t, time.Second, set.Window)
+	assert.Equal(t, time.Second, set.OpenTimeout)
+	assert.Equal(t, 2, set.HalfOpenRequests)
+	assert.True(t, set.IsFailure(http.StatusTooManyRequests))
+	assert.False(t, set.IsFailure(http.StatusInternalServerError))
+	assert.True(t, set.Skipper(nil))
+	assert.NotNil(t, set.state)
+
+	// with invalid mode
+	set = newOptionSet(WithMode("invalid"))
+	assert.Equal(t, ModeConsecutive, set.Mode)
+}
+
+func TestOptionSet_IsFailure(t *testing.T) {
+	set := newOptionSet()
+	assert.True(t, set.IsFailure(http.StatusInternalServerError))
+	assert.True(t, set.IsFailure(http.StatusServiceUnavailable))
+	assert.True(t, set.IsFailure(http.StatusRequestTimeout))
+	assert.False(t, set.IsFailure(http.StatusOK))
+	assert.False(t, set.IsFailure(http.StatusNotFound))
+}
+
+func TestOptionSet_GetBreaker(t *testing.T) {
+	set := newOptionSet()
+	newCtx := func(path string) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		return ctx
+	}
+
+	// unmatched paths without full path share the same breaker
+	b := set.getBreaker(newCtx("/ut-unknown-1"))
+	assert.Same(t, b, set.getBreaker(newCtx("/ut-unknown-2")))
+	assert.Equal(t, unmatchedPath, b.path)
+}
+
+func TestToOptions(t *testing.T) {
+	config := &BootConfig{
+		Enabled:             false,
+		Mode:                ModeErrorRatio,
+		ConsecutiveFailures: 3,
+	}
+
+	// with disabled
+	assert.Empty(t, ToOptions(config, "", "", nil))
+
+	// with enabled
+	config.Enabled = true
+	set := newOptionSet(ToOptions(config, "ut-entry", "ut-type", nil)...)
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, ModeErrorRatio, set.Mode)
+	assert.Equal(t, 3, set.ConsecutiveFailures)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgincache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Cache stores responses with key, implementation should be safe for concurrent use.
+type Cache interface {
+	// Get entry of key
+	Get(key string) (*Entry, bool)
+	// Set entry of key
+	Set(key string, entry *Entry)
+	// Delete entry of key
+	Delete(key string)
+}
+
+// Entry is a cached response.
+//
+// Entry with Vary would not contain response, it records request headers which response varies on.
+type Entry struct {
+	Status       int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+	Created      time.Time
+	Expires      time.Time
+	Vary         []string
+}
+
+// Approximate size of entry in bytes.
+func (e *Entry) size() int {
+	res := len(e.Body) + len(e.ETag)
+	for k, vv := range e.Header {
+		res += len(k)
+		for i := range vv {
+			res += len(vv[i])
+		}
+	}
+	for i := range e.Vary {
+		res += len(e.Vary[i])
+	}
+
+	return res
+}
+
+// NewMemoryCache create in-memory LRU Cache bounded by total size of entries in bytes.
+func NewMemoryCache(maxBytes int) Cache {
+	return &memoryCache{
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Element of lru list.
+type memoryItem struct {
+	key   string
+	entry *Entry
+	size  int
+}
+
+// memoryCache evicts least recently used entries once size exceeds maxBytes.
+type memoryCache struct {
+	maxBytes int
+	bytes    int
+	items    map[string]*list.Element
+	lru      *list.List
+	lock     sync.Mutex
+}
+
+// Get implements Cache.
+func (c *memoryCache) Get(key string) (*Entry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*memoryItem).entry, true
+}
+
+// Set implements Cache, entry larger than maxBytes would be ignored.
+func (c *memoryCache) Set(key string, entry *Entry) {
+	size := len(key) + entry.size()
+	if size > c.maxBytes {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+
+	c.items[key] = c.lru.PushFront(&memoryItem{key: key, entry: entry, size: size})
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Delete implements Cache.
+func (c *memoryCache) Delete(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *memoryCache) remove(elem *list.Element) {
+	item := elem.Value.(*memoryItem)
+	c.lru.Remove(elem)
+	delete(c.items, item.key)
+	c.bytes -= item.size
+}
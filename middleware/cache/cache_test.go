@@ -0,0 +1,46 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgincache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(20).(*memoryCache)
+
+	// set and get
+	cache.Set("a", &Entry{Body: []byte("12345")})
+	entry, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("12345"), entry.Body)
+
+	// replace
+	cache.Set("a", &Entry{Body: []byte("1234")})
+	assert.Equal(t, 5, cache.bytes)
+
+	// least recently used would be evicted
+	cache.Set("b", &Entry{Body: []byte("1234")})
+	cache.Get("a")
+	cache.Set("c", &Entry{Body: []byte("123456789012")})
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 18, cache.bytes)
+
+	// entry larger than cache is ignored
+	cache.Set("d", &Entry{Body: make([]byte, 30)})
+	_, ok = cache.Get("d")
+	assert.False(t, ok)
+
+	// delete
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 13, cache.bytes)
+}
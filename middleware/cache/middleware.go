@@ -0,0 +1,288 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package rkgincache is a response cache middleware for gin framework
+package rkgincache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Middleware Add response cache interceptors.
+//
+// Successful responses of GET requests would be cached with strong ETag, honouring Cache-Control and Vary.
+// Conditional requests with If-None-Match or If-Modified-Since would be answered with 304 if not modified.
+// Responses of requests with Authorization, Cookie or auth principal would not be stored unless marked public or s-maxage.
+// Responses exceeding max entry size, flushed or hijacked would be passed through without being buffered or stored.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) || ctx.Request.Method != http.MethodGet {
+			ctx.Next()
+			return
+		}
+
+		reqDirectives := parseCacheControl(ctx.Request.Header.Get("Cache-Control"))
+		if _, ok := reqDirectives["no-store"]; ok {
+			ctx.Next()
+			return
+		}
+
+		key := ctx.Request.URL.RequestURI()
+
+		// no-cache requires response from origin
+		if _, ok := reqDirectives["no-cache"]; !ok {
+			if entry := set.lookup(ctx.Request, key); entry != nil {
+				set.observe(resultHit)
+				set.serve(ctx, entry, true)
+				ctx.Abort()
+				return
+			}
+		}
+		set.observe(resultMiss)
+
+		// buffer response, switch back to original writer even if panic occurs
+		oldW := ctx.Writer
+		w := newWriter(oldW, set.MaxEntrySize)
+		ctx.Writer = w
+		defer func() {
+			ctx.Writer = oldW
+		}()
+
+		ctx.Next()
+
+		ctx.Writer = oldW
+
+		// response exceeded max size, or streamed to client
+		if w.passThrough {
+			return
+		}
+
+		// nothing written, keep default behaviour of gin, like 404 of unmatched routes
+		if !w.Written() {
+			for k, vv := range w.headers {
+				oldW.Header()[k] = vv
+			}
+			oldW.WriteHeader(w.code)
+			return
+		}
+
+		entry := set.newEntry(w)
+		if ttl := set.getTTL(ctx, entry); ttl > 0 {
+			entry.Expires = entry.Created.Add(ttl)
+			set.store(ctx.Request, key, entry)
+		}
+
+		set.serve(ctx, entry, false)
+	}
+}
+
+// Create entry from buffered response, strong ETag would be assigned to successful response if missing.
+func (set *optionSet) newEntry(w *writer) *Entry {
+	entry := &Entry{
+		Status:  w.code,
+		Header:  w.headers,
+		Body:    w.body.Bytes(),
+		ETag:    w.headers.Get("ETag"),
+		Created: set.now(),
+	}
+
+	if entry.Status == http.StatusOK {
+		if len(entry.ETag) < 1 {
+			sum := sha256.Sum256(entry.Body)
+			entry.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+			entry.Header.Set("ETag", entry.ETag)
+		}
+
+		if t, err := http.ParseTime(entry.Header.Get("Last-Modified")); err == nil {
+			entry.LastModified = t
+		}
+	}
+
+	for _, v := range entry.Header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				entry.Vary = append(entry.Vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return entry
+}
+
+// Get time to live of entry, zero would be returned if response should not be stored.
+func (set *optionSet) getTTL(ctx *gin.Context, entry *Entry) time.Duration {
+	if entry.Status != http.StatusOK || len(entry.Body) > set.MaxEntrySize || len(entry.Header.Values("Set-Cookie")) > 0 {
+		return 0
+	}
+
+	for i := range entry.Vary {
+		if entry.Vary[i] == "*" {
+			return 0
+		}
+	}
+
+	directives := parseCacheControl(entry.Header.Get("Cache-Control"))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[d]; ok {
+			return 0
+		}
+	}
+
+	// responses of requests with identity are private unless marked explicitly
+	_, public := directives["public"]
+	_, sMaxAge := directives["s-maxage"]
+	if isPrivate(ctx) && !public && !sMaxAge {
+		return 0
+	}
+
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if v, ok := directives[d]; ok {
+			sec, err := strconv.Atoi(v)
+			if err != nil || sec < 0 {
+				return 0
+			}
+			return time.Duration(sec) * time.Second
+		}
+	}
+
+	return set.TTL
+}
+
+// Whether request carries identity of client, like Authorization, Cookie or principal authenticated by auth middleware.
+func isPrivate(ctx *gin.Context) bool {
+	return len(ctx.Request.Header.Get("Authorization")) > 0 ||
+		len(ctx.Request.Header.Get("Cookie")) > 0 ||
+		len(rkginctx.GetAuthPrincipal(ctx)) > 0
+}
+
+// Store entry, an entry with Vary would be stored with key and variant would be stored with values of headers.
+func (set *optionSet) store(req *http.Request, key string, entry *Entry) {
+	if len(entry.Vary) > 0 {
+		set.cache.Set(key, &Entry{Vary: entry.Vary, Created: entry.Created, Expires: entry.Expires})
+		key = variantKey(req, key, entry.Vary)
+	}
+
+	set.cache.Set(key, entry)
+}
+
+// Lookup fresh entry of request.
+func (set *optionSet) lookup(req *http.Request, key string) *Entry {
+	entry := set.get(key)
+	// entry without status records headers which response varies on
+	if entry != nil && entry.Status == 0 {
+		entry = set.get(variantKey(req, key, entry.Vary))
+	}
+
+	return entry
+}
+
+// Get fresh entry of key, expired entry would be deleted.
+func (set *optionSet) get(key string) *Entry {
+	entry, ok := set.cache.Get(key)
+	if !ok {
+		return nil
+	}
+
+	if !set.now().Before(entry.Expires) {
+		set.cache.Delete(key)
+		return nil
+	}
+
+	return entry
+}
+
+// Write entry to client, 304 would be returned if conditional request matches.
+func (set *optionSet) serve(ctx *gin.Context, entry *Entry, hit bool) {
+	dst := ctx.Writer.Header()
+	for k, vv := range entry.Header {
+		dst[k] = append([]string(nil), vv...)
+	}
+
+	if hit {
+		dst.Set("Age", strconv.Itoa(int(set.now().Sub(entry.Created).Seconds())))
+	}
+
+	if entry.Status == http.StatusOK && notModified(ctx.Request, entry) {
+		dst.Del("Content-Type")
+		dst.Del("Content-Length")
+		ctx.Writer.WriteHeader(http.StatusNotModified)
+		ctx.Writer.WriteHeaderNow()
+		return
+	}
+
+	ctx.Writer.WriteHeader(entry.Status)
+	ctx.Writer.WriteHeaderNow()
+	ctx.Writer.Write(entry.Body)
+}
+
+// Check conditional request, If-Modified-Since would be ignored if If-None-Match exists.
+func notModified(req *http.Request, entry *Entry) bool {
+	if inm := req.Header.Get("If-None-Match"); len(inm) > 0 {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(entry.ETag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := req.Header.Get("If-Modified-Since"); len(ims) > 0 && !entry.LastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !entry.LastModified.Truncate(time.Second).After(t)
+		}
+	}
+
+	return false
+}
+
+// Key of variant with values of request headers.
+func variantKey(req *http.Request, key string, vary []string) string {
+	names := append([]string(nil), vary...)
+	sort.Strings(names)
+
+	builder := strings.Builder{}
+	builder.WriteString(key)
+	for i := range names {
+		builder.WriteString("\n")
+		builder.WriteString(names[i])
+		builder.WriteString(":")
+		builder.WriteString(strings.Join(req.Header.Values(names[i]), ","))
+	}
+
+	return builder.String()
+}
+
+// Parse directives of Cache-Control into map with lower case names.
+func parseCacheControl(header string) map[string]string {
+	res := make(map[string]string)
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(directive)
+		if len(directive) < 1 {
+			continue
+		}
+
+		name, value := directive, ""
+		if i := strings.Index(directive, "="); i > 0 {
+			name, value = directive[:i], strings.Trim(directive[i+1:], `"`)
+		}
+		res[strings.ToLower(name)] = value
+	}
+
+	return res
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgincache
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func serve(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware(WithEntryNameAndType("ut-entry", "ut-type"), WithRegisterer(prometheus.NewRegistry())))
+	router.GET("/ut-path", func(ctx *gin.Context) {
+		calls++
+		ctx.String(http.StatusOK, "ut-body")
+	})
+	set := optionsMap["ut-entry"]
+
+	// miss
+	w := serve(router, "/ut-path", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ut-body", w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Empty(t, w.Header().Get("Age"))
+
+	// hit
+	w = serve(router, "/ut-path", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ut-body", w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, "0", w.Header().Get("Age"))
+	assert.Equal(t, 1, calls)
+
+	// conditional request
+	w = serve(router, "/ut-path", map[string]string{"If-None-Match": `"other", ` + etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// request with no-cache goes to handler
+	serve(router, "/ut-path", map[string]string{"Cache-Control": "no-cache"})
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(set.requests.WithLabelValues("ut-entry", resultHit)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(set.requests.WithLabelValues("ut-entry", resultMiss)))
+}
+
+func TestMiddleware_WithCacheControl(t *testing.T) {
+	calls := 0
+	now := time.Unix(200, 0)
+	router := gin.New()
+	router.Use(Middleware(WithEntryNameAndType("ut-entry-cc", "ut-type")))
+	router.GET("/ut-path", func(ctx *gin.Context) {
+		calls++
+		ctx.String(http.StatusOK, "ut-body")
+	})
+	router.GET("/ut-private", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("Cache-Control", "private")
+		ctx.String(http.StatusOK, "ut-body")
+	})
+	router.GET("/ut-max-age", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("Cache-Control", "max-age=1")
+		ctx.Header("Last-Modified", time.Unix(100, 0).UTC().Format(http.TimeFormat))
+		ctx.String(http.StatusOK, "ut-body")
+	})
+	router.GET("/ut-error", func(ctx *gin.Context) {
+		calls++
+		ctx.String(http.StatusInternalServerError, "ut-error")
+	})
+	set := optionsMap["ut-entry-cc"]
+	set.now = func() time.Time { return now }
+
+	// private response is not stored
+	serve(router, "/ut-private", nil)
+	serve(router, "/ut-private", nil)
+	assert.Equal(t, 2, calls)
+
+	// error response is not stored
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-error", nil).Code)
+	serve(router, "/ut-error", nil)
+	assert.Equal(t, 4, calls)
+
+	// authorized request is not stored
+	serve(router, "/ut-path", map[string]string{"Authorization": "Basic ut"})
+	serve(router, "/ut-path", map[string]string{"Authorization": "Basic ut"})
+	assert.Equal(t, 6, calls)
+
+	// max-age and If-Modified-Since
+	serve(router, "/ut-max-age", nil)
+	w := serve(router, "/ut-max-age", map[string]string{"If-Modified-Since": time.Unix(100, 0).UTC().Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = serve(router, "/ut-max-age", map[string]string{"If-Modified-Since": time.Unix(99, 0).UTC().Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 7, calls)
+
+	// expired
+	now = now.Add(time.Second)
+	serve(router, "/ut-max-age", nil)
+	assert.Equal(t, 8, calls)
+}
+
+func TestMiddleware_WithApiKey(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(rkginauth.Middleware(rkmidauth.WithApiKeyAuth("ut-key-a", "ut-key-b")), Middleware())
+	router.GET("/ut-path", func(ctx *gin.Context) {
+		calls++
+		ctx.String(http.StatusOK, rkginctx.GetAuthPrincipal(ctx))
+	})
+	router.GET("/ut-public", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("Cache-Control", "public, max-age=60")
+		ctx.String(http.StatusOK, "ut-public")
+	})
+
+	// responses of different API keys on the same URI are not shared
+	w := serve(router, "/ut-path", map[string]string{rkmid.HeaderApiKey: "ut-key-a"})
+	assert.Equal(t, rkginauth.ApiKeyPrincipal("ut-key-a"), w.Body.String())
+	w = serve(router, "/ut-path", map[string]string{rkmid.HeaderApiKey: "ut-key-b"})
+	assert.Equal(t, rkginauth.ApiKeyPrincipal("ut-key-b"), w.Body.String())
+	assert.Equal(t, 2, calls)
+
+	// response marked as public is shared
+	serve(router, "/ut-public", map[string]string{rkmid.HeaderApiKey: "ut-key-a"})
+	w = serve(router, "/ut-public", map[string]string{rkmid.HeaderApiKey: "ut-key-b"})
+	assert.Equal(t, "ut-public", w.Body.String())
+	assert.Equal(t, "0", w.Header().Get("Age"))
+	assert.Equal(t, 3, calls)
+}
+
+func TestMiddleware_WithCookie(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ut-path", func(ctx *gin.Context) {
+		calls++
+		ctx.String(http.StatusOK, ctx.GetHeader("Cookie"))
+	})
+
+	assert.Equal(t, "session=a", serve(router, "/ut-path", map[string]string{"Cookie": "session=a"}).Body.String())
+	assert.Equal(t, "session=b", serve(router, "/ut-path", map[string]string{"Cookie": "session=b"}).Body.String())
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddleware_WithVary(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ut-vary", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("Vary", "Accept-Language")
+		ctx.String(http.StatusOK, ctx.GetHeader("Accept-Language"))
+	})
+
+	assert.Equal(t, "en", serve(router, "/ut-vary", map[string]string{"Accept-Language": "en"}).Body.String())
+	assert.Equal(t, "fr", serve(router, "/ut-vary", map[string]string{"Accept-Language": "fr"}).Body.String())
+	assert.Equal(t, "en", serve(router, "/ut-vary", map[string]string{"Accept-Language": "en"}).Body.String())
+	assert.Equal(t, "fr", serve(router, "/ut-vary", map[string]string{"Accept-Language": "fr"}).Body.String())
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddleware_WithUnmatchedRoute(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware())
+
+	w := serve(router, "/ut-unknown", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "404 page not found", w.Body.String())
+}
+
+func TestMiddleware_WithPanic(t *testing.T) {
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, err interface{}) {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(Middleware())
+	router.GET("/ut-panic", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "partial")
+		panic("ut-panic")
+	})
+
+	w := serve(router, "/ut-panic", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestMiddleware_WithLargeBody(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware(WithMaxEntrySize(8)))
+	router.GET("/ut-large", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("X-Ut", "ut-value")
+		ctx.Status(http.StatusOK)
+		ctx.Writer.WriteString("ut-")
+		ctx.Writer.WriteString("large-body")
+	})
+
+	// passed through once exceeded max size, without being stored
+	for i := 1; i <= 2; i++ {
+		w := serve(router, "/ut-large", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ut-large-body", w.Body.String())
+		assert.Equal(t, "ut-value", w.Header().Get("X-Ut"))
+		assert.Empty(t, w.Header().Get("ETag"))
+		assert.Equal(t, i, calls)
+	}
+}
+
+func TestMiddleware_WithFlush(t *testing.T) {
+	calls := 0
+	w := httptest.NewRecorder()
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ut-stream", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("Content-Type", "text/event-stream")
+		ctx.Writer.WriteString("data: 1\n\n")
+		ctx.Writer.Flush()
+
+		// event is sent to client before handler finished
+		assert.True(t, w.Flushed)
+		assert.Equal(t, "data: 1\n\n", w.Body.String())
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+		ctx.Writer.WriteString("data: 2\n\n")
+	})
+
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ut-stream", nil))
+	assert.Equal(t, "data: 1\n\ndata: 2\n\n", w.Body.String())
+
+	// streaming response is not stored
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ut-stream", nil))
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddleware_WithHijack(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ut-hijack", func(ctx *gin.Context) {
+		calls++
+		conn, rw, err := ctx.Writer.Hijack()
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		rw.Flush()
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for i := 1; i <= 2; i++ {
+		resp, err := http.Get(server.URL + "/ut-hijack")
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "ok", string(body))
+		assert.Equal(t, i, calls)
+	}
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.ReleaseMode)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgincache
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rs/xid"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTTL          = time.Minute
+	defaultMaxSize      = 64 << 20
+	defaultMaxEntrySize = 1 << 20
+
+	resultHit  = "hit"
+	resultMiss = "miss"
+)
+
+// Interceptor would distinguish cache set based on.
+var (
+	optionsMap     = make(map[string]*optionSet)
+	defaultSkipper = func(*gin.Context) bool {
+		return false
+	}
+)
+
+// BootConfig for YAML
+type BootConfig struct {
+	Enabled           bool     `yaml:"enabled" json:"enabled"`
+	Ignore            []string `yaml:"ignore" json:"ignore"`
+	TtlMs             int      `yaml:"ttlMs" json:"ttlMs"`
+	MaxSizeBytes      int      `yaml:"maxSizeBytes" json:"maxSizeBytes"`
+	MaxEntrySizeBytes int      `yaml:"maxEntrySizeBytes" json:"maxEntrySizeBytes"`
+}
+
+// ToOptions convert BootConfig into Option list
+func ToOptions(config *BootConfig, entryName, entryType string, registerer prometheus.Registerer) []Option {
+	opts := make([]Option, 0)
+
+	if config.Enabled {
+		opts = append(opts,
+			WithEntryNameAndType(entryName, entryType),
+			WithTTL(time.Duration(config.TtlMs)*time.Millisecond),
+			WithMaxEntrySize(config.MaxEntrySizeBytes),
+			WithPathToIgnore(config.Ignore...),
+			WithRegisterer(registerer))
+
+		if config.MaxSizeBytes > 0 {
+			opts = append(opts, WithCache(NewMemoryCache(config.MaxSizeBytes)))
+		}
+	}
+
+	return opts
+}
+
+// Create new optionSet with options.
+func newOptionSet(opts ...Option) *optionSet {
+	set := &optionSet{
+		EntryName:    xid.New().String(),
+		EntryType:    "",
+		Skipper:      defaultSkipper,
+		TTL:          defaultTTL,
+		MaxEntrySize: defaultMaxEntrySize,
+		ignorePrefix: make([]string, 0),
+		now:          time.Now,
+	}
+
+	for i := range opts {
+		opts[i](set)
+	}
+
+	if set.cache == nil {
+		set.cache = NewMemoryCache(defaultMaxSize)
+	}
+
+	if set.registerer != nil {
+		set.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rk",
+			Subsystem: "cache",
+			Name:      "requests_total",
+			Help:      "Total number of requests served by response cache, result: hit, miss",
+		}, []string{"entryName", "result"})
+
+		if err := set.registerer.Register(set.requests); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				set.requests = are.ExistingCollector.(*prometheus.CounterVec)
+			} else {
+				set.requests = nil
+			}
+		}
+	}
+
+	if _, ok := optionsMap[set.EntryName]; !ok {
+		optionsMap[set.EntryName] = set
+	}
+
+	return set
+}
+
+// Options which is used while initializing extension interceptor
+type optionSet struct {
+	EntryName    string
+	EntryType    string
+	Skipper      Skipper
+	TTL          time.Duration
+	MaxEntrySize int
+	cache        Cache
+	ignorePrefix []string
+	registerer   prometheus.Registerer
+	requests     *prometheus.CounterVec
+	now          func() time.Time
+}
+
+// ShouldIgnore determine whether cache should be ignored based on path
+func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		for i := range set.ignorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, set.ignorePrefix[i]) {
+				return true
+			}
+		}
+
+		return rkmid.ShouldIgnoreGlobal(ctx.Request.URL.Path)
+	}
+
+	return false
+}
+
+// Record result of lookup.
+func (set *optionSet) observe(result string) {
+	if set.requests != nil {
+		set.requests.WithLabelValues(set.EntryName, result).Inc()
+	}
+}
+
+// Option if for middleware options while creating middleware
+type Option func(*optionSet)
+
+// WithEntryNameAndType provide entry name and entry type.
+func WithEntryNameAndType(entryName, entryType string) Option {
+	return func(opt *optionSet) {
+		opt.EntryName = entryName
+		opt.EntryType = entryType
+	}
+}
+
+// WithSkipper provide skipper.
+func WithSkipper(skip Skipper) Option {
+	return func(opt *optionSet) {
+		opt.Skipper = skip
+	}
+}
+
+// WithPathToIgnore provide path prefix to ignore middleware
+func WithPathToIgnore(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.ignorePrefix = append(opt.ignorePrefix, prefix...)
+	}
+}
+
+// WithCache provide Cache of responses, in-memory LRU cache bounded by 64MB would be used if missing.
+func WithCache(cache Cache) Option {
+	return func(opt *optionSet) {
+		if cache != nil {
+			opt.cache = cache
+		}
+	}
+}
+
+// WithTTL provide time to live of responses without max-age or s-maxage in Cache-Control.
+func WithTTL(ttl time.Duration) Option {
+	return func(opt *optionSet) {
+		if ttl > 0 {
+			opt.TTL = ttl
+		}
+	}
+}
+
+// WithMaxEntrySize provide max size of response body in bytes to be cached.
+func WithMaxEntrySize(size int) Option {
+	return func(opt *optionSet) {
+		if size > 0 {
+			opt.MaxEntrySize = size
+		}
+	}
+}
+
+// WithRegisterer provide prometheus.Registerer to export hits and misses.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(opt *optionSet) {
+		opt.registerer = registerer
+	}
+}
+
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
@@ -0,0 +1,69 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgincache
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewOptionSet(t *testing.T) {
+	// without options
+	set := newOptionSet()
+	assert.NotEmpty(t, set.EntryName)
+	assert.Equal(t, defaultTTL, set.TTL)
+	assert.Equal(t, defaultMaxEntrySize, set.MaxEntrySize)
+	assert.Equal(t, defaultMaxSize, set.cache.(*memoryCache).maxBytes)
+	assert.Nil(t, set.requests)
+
+	// with options
+	cache := NewMemoryCache(10)
+	set = newOptionSet(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithCache(cache),
+		WithTTL(time.Second),
+		WithMaxEntrySize(5),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}),
+		WithRegisterer(prometheus.NewRegistry()))
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "ut-type", set.EntryType)
+	assert.Equal(t, cache, set.cache)
+	assert.Equal(t, time.Second, set.TTL)
+	assert.Equal(t, 5, set.MaxEntrySize)
+	assert.True(t, set.Skipper(nil))
+	assert.NotNil(t, set.requests)
+}
+
+func TestToOptions(t *testing.T) {
+	config := &BootConfig{
+		Enabled:      false,
+		TtlMs:        1000,
+		MaxSizeBytes: 100,
+	}
+
+	// with disabled
+	assert.Empty(t, ToOptions(config, "", "", nil))
+
+	// with enabled
+	config.Enabled = true
+	set := newOptionSet(ToOptions(config, "ut-entry", "ut-type", nil)...)
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, time.Second, set.TTL)
+	assert.Equal(t, 100, set.cache.(*memoryCache).maxBytes)
+}
+
+func TestParseCacheControl(t *testing.T) {
+	res := parseCacheControl(`public, Max-Age=60, no-cache="Set-Cookie", ,`)
+	assert.Len(t, res, 3)
+	assert.Equal(t, "", res["public"])
+	assert.Equal(t, "60", res["max-age"])
+	assert.Equal(t, "Set-Cookie", res["no-cache"])
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgincache
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+)
+
+// writer is a writer with memory buffer, response would be written to original writer by middleware.
+//
+// Writer stops buffering and passes response through once body exceeds max size, or response is flushed or hijacked,
+// response passed through would not be cached.
+type writer struct {
+	gin.ResponseWriter
+	body         *bytes.Buffer
+	headers      http.Header
+	wroteHeaders bool
+	code         int
+	maxSize      int
+	passThrough  bool
+}
+
+// newWriter will return a buffered writer pointer
+func newWriter(w gin.ResponseWriter, maxSize int) *writer {
+	return &writer{ResponseWriter: w, body: &bytes.Buffer{}, headers: make(http.Header), code: w.Status(), maxSize: maxSize}
+}
+
+// Write will write data to response body, buffered body would be written to original writer if exceeds max size
+func (w *writer) Write(data []byte) (int, error) {
+	if !w.passThrough && w.body.Len()+len(data) > w.maxSize {
+		if err := w.bypass(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.passThrough {
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.wroteHeaders = true
+	return w.body.Write(data)
+}
+
+// WriteHeader will write http status code
+func (w *writer) WriteHeader(code int) {
+	if w.passThrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if code > 0 && !w.wroteHeaders {
+		w.code = code
+	}
+}
+
+// WriteHeaderNow will mark headers as written
+func (w *writer) WriteHeaderNow() {
+	if w.passThrough {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+
+	w.wroteHeaders = true
+}
+
+// Header will get response headers
+func (w *writer) Header() http.Header {
+	if w.passThrough {
+		return w.ResponseWriter.Header()
+	}
+
+	return w.headers
+}
+
+// WriteString will write string to response body
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status will return buffered http status code
+func (w *writer) Status() int {
+	if w.passThrough {
+		return w.ResponseWriter.Status()
+	}
+
+	return w.code
+}
+
+// Size will return size of buffered body, -1 if nothing written
+func (w *writer) Size() int {
+	if w.passThrough {
+		return w.ResponseWriter.Size()
+	}
+
+	if !w.wroteHeaders {
+		return -1
+	}
+	return w.body.Len()
+}
+
+// Written will return true if headers marked as written
+func (w *writer) Written() bool {
+	if w.passThrough {
+		return w.ResponseWriter.Written()
+	}
+
+	return w.wroteHeaders
+}
+
+// Flush will write buffered response to original writer and flush it, streaming response would not be cached
+func (w *writer) Flush() {
+	w.bypass()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack will hijack connection of original writer, response would not be cached
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.passThrough {
+		w.passThrough = true
+		w.copyHeaders()
+	}
+
+	return w.ResponseWriter.Hijack()
+}
+
+// Stop buffering and write buffered response to original writer.
+func (w *writer) bypass() error {
+	if w.passThrough {
+		return nil
+	}
+
+	w.passThrough = true
+	w.copyHeaders()
+	w.ResponseWriter.WriteHeader(w.code)
+	w.ResponseWriter.WriteHeaderNow()
+
+	if w.body.Len() < 1 {
+		return nil
+	}
+
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+	return err
+}
+
+// Copy buffered headers to original writer.
+func (w *writer) copyHeaders() {
+	dst := w.ResponseWriter.Header()
+	for k, vv := range w.headers {
+		dst[k] = vv
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginconcurrency
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// gradient limiter would not be adjusted if less than half of limit is used
+	appLimitedRatio = 0.5
+	// weight of new sample while calculating long term latency
+	longLatencyWeight = 0.01
+)
+
+// limiter limits number of in-flight requests with adaptive limit.
+type limiter interface {
+	// Acquire a slot if in-flight requests are less than ratio of limit.
+	acquire(ratio float64) bool
+	// Release slot with observed latency.
+	release(latency time.Duration)
+	// Current limit and in-flight requests.
+	stats() (int, int)
+}
+
+// Create limiter based on algorithm.
+func newLimiter(set *optionSet) limiter {
+	if set.Algorithm == AlgorithmGradient {
+		return &gradientLimiter{
+			set:   set,
+			limit: float64(set.InitialLimit),
+		}
+	}
+
+	return &aimdLimiter{
+		set:   set,
+		limit: float64(set.InitialLimit),
+	}
+}
+
+// aimdLimiter increases limit by one on fast responses and multiplies limit with backoff ratio on slow responses.
+type aimdLimiter struct {
+	set      *optionSet
+	limit    float64
+	inflight int
+	lock     sync.Mutex
+}
+
+func (l *aimdLimiter) acquire(ratio float64) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.inflight >= int(math.Max(1, l.limit*ratio)) {
+		return false
+	}
+	l.inflight++
+
+	return true
+}
+
+func (l *aimdLimiter) release(latency time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+
+	if latency > l.set.LatencyThreshold {
+		l.limit = l.set.clamp(l.limit * l.set.BackoffRatio)
+		return
+	}
+
+	// only increase limit if it is actually used
+	if float64(inflight)*2 >= l.limit {
+		l.limit = l.set.clamp(l.limit + 1)
+	}
+}
+
+func (l *aimdLimiter) stats() (int, int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return int(l.limit), l.inflight
+}
+
+// gradientLimiter adjusts limit with gradient of long term latency and observed latency.
+//
+// newLimit = limit * (longLatency / latency) + sqrt(limit)
+//
+// Gradient is in range of [0.5, 1], sqrt(limit) leaves room for queueing, new limit is smoothed with previous one.
+type gradientLimiter struct {
+	set         *optionSet
+	limit       float64
+	inflight    int
+	longLatency float64
+	lock        sync.Mutex
+}
+
+func (l *gradientLimiter) acquire(ratio float64) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.inflight >= int(math.Max(1, l.limit*ratio)) {
+		return false
+	}
+	l.inflight++
+
+	return true
+}
+
+func (l *gradientLimiter) release(latency time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+
+	sample := float64(latency)
+	if sample <= 0 {
+		return
+	}
+
+	if l.longLatency == 0 {
+		l.longLatency = sample
+	} else {
+		l.longLatency = l.longLatency*(1-longLatencyWeight) + sample*longLatencyWeight
+	}
+
+	// application is not saturated, latency tells nothing about limit
+	if float64(inflight) < l.limit*appLimitedRatio {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1.0, l.longLatency/sample))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit = l.set.clamp(l.limit*(1-l.set.Smoothing) + newLimit*l.set.Smoothing)
+}
+
+func (l *gradientLimiter) stats() (int, int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return int(l.limit), l.inflight
+}
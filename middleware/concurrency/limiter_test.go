@@ -0,0 +1,80 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginconcurrency
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewLimiter(t *testing.T) {
+	_, ok := newLimiter(newOptionSet()).(*aimdLimiter)
+	assert.True(t, ok)
+
+	_, ok = newLimiter(newOptionSet(WithAlgorithm(AlgorithmGradient))).(*gradientLimiter)
+	assert.True(t, ok)
+}
+
+func TestAimdLimiter(t *testing.T) {
+	l := newLimiter(newOptionSet(WithLimits(2, 1, 3), WithLatencyThreshold(time.Second)))
+
+	// reach limit
+	assert.True(t, l.acquire(1))
+	assert.True(t, l.acquire(1))
+	assert.False(t, l.acquire(1))
+	limit, inflight := l.stats()
+	assert.Equal(t, 2, limit)
+	assert.Equal(t, 2, inflight)
+
+	// increase on fast response
+	l.release(time.Millisecond)
+	limit, inflight = l.stats()
+	assert.Equal(t, 3, limit)
+	assert.Equal(t, 1, inflight)
+
+	// never exceed max
+	l.acquire(1)
+	l.release(time.Millisecond)
+	limit, _ = l.stats()
+	assert.Equal(t, 3, limit)
+
+	// decrease on slow response
+	l.release(2 * time.Second)
+	limit, inflight = l.stats()
+	assert.Equal(t, 2, limit)
+	assert.Equal(t, 0, inflight)
+
+	// low priority requests are limited with ratio
+	assert.True(t, l.acquire(0.5))
+	assert.False(t, l.acquire(0.5))
+}
+
+func TestGradientLimiter(t *testing.T) {
+	l := newLimiter(newOptionSet(WithAlgorithm(AlgorithmGradient), WithLimits(4, 1, 100), WithSmoothing(1)))
+
+	// app limited, limit would not change
+	l.acquire(1)
+	l.release(10 * time.Millisecond)
+	limit, _ := l.stats()
+	assert.Equal(t, 4, limit)
+
+	// stable latency increases limit with queue size
+	for i := 0; i < 4; i++ {
+		l.acquire(1)
+	}
+	l.release(10 * time.Millisecond)
+	limit, _ = l.stats()
+	assert.Equal(t, 6, limit)
+
+	// latency spike decreases limit
+	for i := 0; i < 3; i++ {
+		l.acquire(1)
+	}
+	l.release(time.Second)
+	limit, _ = l.stats()
+	assert.True(t, limit < 6)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package rkginconcurrency is a middleware of gin framework for limiting in-flight requests with adaptive limit
+package rkginconcurrency
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Middleware Add adaptive concurrency limit interceptors.
+//
+// Requests exceeding limit would be shed with 503 and Retry-After header, critical requests would never be shed.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) {
+			ctx.Next()
+			return
+		}
+
+		ratio := 1.0
+		switch set.GetPriority(ctx) {
+		case PriorityCritical:
+			ctx.Next()
+			return
+		case PriorityLow:
+			ratio = set.LowPriorityRatio
+		}
+
+		l, path := set.getLimiter(ctx)
+		if !l.acquire(ratio) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(set.RetryAfter.Seconds()))))
+			resp := rkmid.GetErrorBuilder().New(http.StatusServiceUnavailable, "Too many in-flight requests")
+			ctx.AbortWithStatusJSON(resp.Code(), resp)
+			return
+		}
+		set.observe(l, path)
+
+		// release slot even if panic occurs
+		start := time.Now()
+		defer func() {
+			l.release(time.Since(start))
+			set.observe(l, path)
+		}()
+
+		ctx.Next()
+	}
+}
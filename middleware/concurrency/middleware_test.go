@@ -0,0 +1,140 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginconcurrency
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Serve requests in background and wait until all of them are in-flight or returned.
+func serveAsync(router *gin.Engine, paths ...string) (chan *httptest.ResponseRecorder, *sync.WaitGroup) {
+	res := make(chan *httptest.ResponseRecorder, len(paths))
+	wg := &sync.WaitGroup{}
+
+	for i := range paths {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			res <- w
+		}(paths[i])
+	}
+
+	return res, wg
+}
+
+func TestMiddleware(t *testing.T) {
+	// requests would be in-flight until release closed
+	release := make(chan struct{})
+	handler := func(ctx *gin.Context) {
+		<-release
+		ctx.Status(http.StatusOK)
+	}
+	router := gin.New()
+	router.Use(Middleware(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithLimits(1, 1, 1),
+		WithCriticalPaths("/rk/v1"),
+		WithRegisterer(prometheus.NewRegistry())))
+	router.GET("/v1/user/:id", handler)
+	router.GET("/v1/order", handler)
+	router.GET("/rk/v1/ready", handler)
+	set := optionsMap["ut-entry"]
+
+	// occupy the only slot
+	res, wg := serveAsync(router, "/v1/user/1")
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(set.inflightGauge.WithLabelValues("ut-entry", globalPath)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// shed
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/order", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Too many in-flight requests")
+
+	// critical paths are never shed
+	critical, criticalWg := serveAsync(router, "/rk/v1/ready")
+
+	close(release)
+	wg.Wait()
+	criticalWg.Wait()
+	assert.Equal(t, http.StatusOK, (<-res).Code)
+	assert.Equal(t, http.StatusOK, (<-critical).Code)
+	assert.Equal(t, float64(0), testutil.ToFloat64(set.inflightGauge.WithLabelValues("ut-entry", globalPath)))
+}
+
+func TestMiddleware_PerRoute(t *testing.T) {
+	release := make(chan struct{})
+	handler := func(ctx *gin.Context) {
+		<-release
+		ctx.Status(http.StatusOK)
+	}
+	router := gin.New()
+	router.Use(Middleware(
+		WithEntryNameAndType("ut-entry-route", "ut-type"),
+		WithLimits(1, 1, 1),
+		WithPerRoute(true),
+		WithRegisterer(prometheus.NewRegistry())))
+	router.GET("/v1/user/:id", handler)
+	router.GET("/v1/order", handler)
+	set := optionsMap["ut-entry-route"]
+
+	res, wg := serveAsync(router, "/v1/user/1")
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(set.inflightGauge.WithLabelValues("ut-entry-route", "/v1/user/:id")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// parameterised route shares the same limiter
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/user/2", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// other routes are not affected
+	other, otherWg := serveAsync(router, "/v1/order")
+
+	close(release)
+	wg.Wait()
+	otherWg.Wait()
+	assert.Equal(t, http.StatusOK, (<-res).Code)
+	assert.Equal(t, http.StatusOK, (<-other).Code)
+}
+
+func TestMiddleware_WithIgnore(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(
+		WithEntryNameAndType("ut-entry-ignore", "ut-type"),
+		WithLimits(1, 1, 1),
+		WithPathToIgnore("/v1"),
+		WithRegisterer(prometheus.NewRegistry())))
+	set := optionsMap["ut-entry-ignore"]
+
+	// ignored request is not counted as in-flight
+	router.GET("/v1/order", func(ctx *gin.Context) {
+		assert.Equal(t, float64(0), testutil.ToFloat64(set.inflightGauge.WithLabelValues("ut-entry-ignore", globalPath)))
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/order", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.ReleaseMode)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,393 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginconcurrency
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rs/xid"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AlgorithmAIMD additive increase and multiplicative decrease based on latency threshold
+	AlgorithmAIMD = "aimd"
+	// AlgorithmGradient adjust limit based on gradient of long term latency and observed latency
+	AlgorithmGradient = "gradient"
+
+	// label of entry level limiter
+	globalPath = "*"
+
+	defaultInitialLimit     = 20
+	defaultMinLimit         = 1
+	defaultMaxLimit         = 1000
+	defaultLatencyThreshold = time.Second
+	defaultBackoffRatio     = 0.9
+	defaultSmoothing        = 0.2
+	defaultRetryAfter       = time.Second
+	defaultLowPriorityRatio = 0.8
+)
+
+const (
+	// PriorityLow requests would be shed once in-flight requests reached LowPriorityRatio of limit
+	PriorityLow Priority = iota
+	// PriorityNormal requests would be shed once in-flight requests reached limit
+	PriorityNormal
+	// PriorityCritical requests would never be shed
+	PriorityCritical
+)
+
+// Interceptor would distinguish concurrency set based on.
+var (
+	optionsMap     = make(map[string]*optionSet)
+	defaultSkipper = func(*gin.Context) bool {
+		return false
+	}
+)
+
+// Priority of request, decides when request would be shed.
+type Priority int
+
+// BootConfig for YAML
+type BootConfig struct {
+	Enabled            bool     `yaml:"enabled" json:"enabled"`
+	Ignore             []string `yaml:"ignore" json:"ignore"`
+	Algorithm          string   `yaml:"algorithm" json:"algorithm"`
+	PerRoute           bool     `yaml:"perRoute" json:"perRoute"`
+	InitialLimit       int      `yaml:"initialLimit" json:"initialLimit"`
+	MinLimit           int      `yaml:"minLimit" json:"minLimit"`
+	MaxLimit           int      `yaml:"maxLimit" json:"maxLimit"`
+	LatencyThresholdMs int      `yaml:"latencyThresholdMs" json:"latencyThresholdMs"`
+	BackoffRatio       float64  `yaml:"backoffRatio" json:"backoffRatio"`
+	Smoothing          float64  `yaml:"smoothing" json:"smoothing"`
+	RetryAfterSec      int      `yaml:"retryAfterSec" json:"retryAfterSec"`
+	CriticalPaths      []string `yaml:"criticalPaths" json:"criticalPaths"`
+	LowPriorityPaths   []string `yaml:"lowPriorityPaths" json:"lowPriorityPaths"`
+	LowPriorityRatio   float64  `yaml:"lowPriorityRatio" json:"lowPriorityRatio"`
+}
+
+// ToOptions convert BootConfig into Option list
+func ToOptions(config *BootConfig, entryName, entryType string, registerer prometheus.Registerer) []Option {
+	opts := make([]Option, 0)
+
+	if config.Enabled {
+		opts = append(opts,
+			WithEntryNameAndType(entryName, entryType),
+			WithAlgorithm(config.Algorithm),
+			WithPerRoute(config.PerRoute),
+			WithLimits(config.InitialLimit, config.MinLimit, config.MaxLimit),
+			WithLatencyThreshold(time.Duration(config.LatencyThresholdMs)*time.Millisecond),
+			WithBackoffRatio(config.BackoffRatio),
+			WithSmoothing(config.Smoothing),
+			WithRetryAfter(time.Duration(config.RetryAfterSec)*time.Second),
+			WithCriticalPaths(config.CriticalPaths...),
+			WithLowPriorityPaths(config.LowPriorityPaths...),
+			WithLowPriorityRatio(config.LowPriorityRatio),
+			WithPathToIgnore(config.Ignore...),
+			WithRegisterer(registerer))
+	}
+
+	return opts
+}
+
+// Create new optionSet with options.
+func newOptionSet(opts ...Option) *optionSet {
+	set := &optionSet{
+		EntryName:        xid.New().String(),
+		EntryType:        "",
+		Skipper:          defaultSkipper,
+		Algorithm:        AlgorithmAIMD,
+		InitialLimit:     defaultInitialLimit,
+		MinLimit:         defaultMinLimit,
+		MaxLimit:         defaultMaxLimit,
+		LatencyThreshold: defaultLatencyThreshold,
+		BackoffRatio:     defaultBackoffRatio,
+		Smoothing:        defaultSmoothing,
+		RetryAfter:       defaultRetryAfter,
+		LowPriorityRatio: defaultLowPriorityRatio,
+		ignorePrefix:     make([]string, 0),
+		criticalPrefix:   make([]string, 0),
+		lowPrefix:        make([]string, 0),
+		limiters:         make(map[string]limiter),
+	}
+
+	for i := range opts {
+		opts[i](set)
+	}
+
+	if set.MinLimit > set.MaxLimit {
+		set.MinLimit = set.MaxLimit
+	}
+	set.InitialLimit = int(set.clamp(float64(set.InitialLimit)))
+
+	if set.registerer != nil {
+		set.limitGauge = registerGauge(set.registerer, "limit", "Adaptive limit of in-flight requests")
+		set.inflightGauge = registerGauge(set.registerer, "inflight", "Number of in-flight requests")
+	}
+
+	if _, ok := optionsMap[set.EntryName]; !ok {
+		optionsMap[set.EntryName] = set
+	}
+
+	return set
+}
+
+// Register gauge or reuse existing one, nil would be returned if failed.
+func registerGauge(registerer prometheus.Registerer, name, help string) *prometheus.GaugeVec {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "rk",
+		Subsystem: "concurrency",
+		Name:      name,
+		Help:      help,
+	}, []string{"entryName", "path"})
+
+	if err := registerer.Register(gauge); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prometheus.GaugeVec)
+		}
+		return nil
+	}
+
+	return gauge
+}
+
+// Options which is used while initializing extension interceptor
+type optionSet struct {
+	EntryName        string
+	EntryType        string
+	Skipper          Skipper
+	Algorithm        string
+	PerRoute         bool
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	LatencyThreshold time.Duration
+	BackoffRatio     float64
+	Smoothing        float64
+	RetryAfter       time.Duration
+	LowPriorityRatio float64
+	PriorityFunc     PriorityFunc
+	ignorePrefix     []string
+	criticalPrefix   []string
+	lowPrefix        []string
+	registerer       prometheus.Registerer
+	limitGauge       *prometheus.GaugeVec
+	inflightGauge    *prometheus.GaugeVec
+	limiters         map[string]limiter
+	lock             sync.Mutex
+}
+
+// ShouldIgnore determine whether concurrency limit should be ignored based on path
+func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		for i := range set.ignorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, set.ignorePrefix[i]) {
+				return true
+			}
+		}
+
+		return rkmid.ShouldIgnoreGlobal(ctx.Request.URL.Path)
+	}
+
+	return false
+}
+
+// GetPriority returns priority of request, PriorityFunc would be used if provided.
+func (set *optionSet) GetPriority(ctx *gin.Context) Priority {
+	if set.PriorityFunc != nil {
+		return set.PriorityFunc(ctx)
+	}
+
+	for i := range set.criticalPrefix {
+		if strings.HasPrefix(ctx.Request.URL.Path, set.criticalPrefix[i]) {
+			return PriorityCritical
+		}
+	}
+
+	for i := range set.lowPrefix {
+		if strings.HasPrefix(ctx.Request.URL.Path, set.lowPrefix[i]) {
+			return PriorityLow
+		}
+	}
+
+	return PriorityNormal
+}
+
+// Get limiter of entry or route if PerRoute enabled.
+func (set *optionSet) getLimiter(ctx *gin.Context) (limiter, string) {
+	// unmatched routes share entry level limiter
+	path := globalPath
+	if set.PerRoute && len(ctx.FullPath()) > 0 {
+		path = ctx.FullPath()
+	}
+
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	l, ok := set.limiters[path]
+	if !ok {
+		l = newLimiter(set)
+		set.limiters[path] = l
+	}
+
+	return l, path
+}
+
+// Export limit and in-flight requests into prometheus.
+func (set *optionSet) observe(l limiter, path string) {
+	if set.limitGauge == nil || set.inflightGauge == nil {
+		return
+	}
+
+	limit, inflight := l.stats()
+	set.limitGauge.WithLabelValues(set.EntryName, path).Set(float64(limit))
+	set.inflightGauge.WithLabelValues(set.EntryName, path).Set(float64(inflight))
+}
+
+// Keep limit in range of [MinLimit, MaxLimit].
+func (set *optionSet) clamp(limit float64) float64 {
+	return math.Max(float64(set.MinLimit), math.Min(float64(set.MaxLimit), limit))
+}
+
+// Option if for middleware options while creating middleware
+type Option func(*optionSet)
+
+// WithEntryNameAndType provide entry name and entry type.
+func WithEntryNameAndType(entryName, entryType string) Option {
+	return func(opt *optionSet) {
+		opt.EntryName = entryName
+		opt.EntryType = entryType
+	}
+}
+
+// WithSkipper provide skipper.
+func WithSkipper(skip Skipper) Option {
+	return func(opt *optionSet) {
+		opt.Skipper = skip
+	}
+}
+
+// WithPathToIgnore provide path prefix to ignore middleware
+func WithPathToIgnore(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.ignorePrefix = append(opt.ignorePrefix, prefix...)
+	}
+}
+
+// WithAlgorithm provide algorithm of adaptive limit, options: aimd, gradient
+func WithAlgorithm(algorithm string) Option {
+	return func(opt *optionSet) {
+		switch algorithm {
+		case AlgorithmAIMD, AlgorithmGradient:
+			opt.Algorithm = algorithm
+		}
+	}
+}
+
+// WithPerRoute limit in-flight requests per route instead of per entry.
+func WithPerRoute(perRoute bool) Option {
+	return func(opt *optionSet) {
+		opt.PerRoute = perRoute
+	}
+}
+
+// WithLimits provide initial, minimum and maximum limit of in-flight requests, zero value would be ignored.
+func WithLimits(initial, min, max int) Option {
+	return func(opt *optionSet) {
+		if initial > 0 {
+			opt.InitialLimit = initial
+		}
+		if min > 0 {
+			opt.MinLimit = min
+		}
+		if max > 0 {
+			opt.MaxLimit = max
+		}
+	}
+}
+
+// WithLatencyThreshold provide latency treated as overloaded in aimd algorithm.
+func WithLatencyThreshold(threshold time.Duration) Option {
+	return func(opt *optionSet) {
+		if threshold > 0 {
+			opt.LatencyThreshold = threshold
+		}
+	}
+}
+
+// WithBackoffRatio provide ratio multiplied with limit once overloaded in aimd algorithm.
+func WithBackoffRatio(ratio float64) Option {
+	return func(opt *optionSet) {
+		if ratio > 0 && ratio < 1 {
+			opt.BackoffRatio = ratio
+		}
+	}
+}
+
+// WithSmoothing provide weight of new limit in gradient algorithm.
+func WithSmoothing(smoothing float64) Option {
+	return func(opt *optionSet) {
+		if smoothing > 0 && smoothing <= 1 {
+			opt.Smoothing = smoothing
+		}
+	}
+}
+
+// WithRetryAfter provide value of Retry-After header of shed requests.
+func WithRetryAfter(retryAfter time.Duration) Option {
+	return func(opt *optionSet) {
+		if retryAfter > 0 {
+			opt.RetryAfter = retryAfter
+		}
+	}
+}
+
+// WithCriticalPaths provide path prefix which would never be shed, like health check and admin paths.
+func WithCriticalPaths(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.criticalPrefix = append(opt.criticalPrefix, prefix...)
+	}
+}
+
+// WithLowPriorityPaths provide path prefix which would be shed first.
+func WithLowPriorityPaths(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.lowPrefix = append(opt.lowPrefix, prefix...)
+	}
+}
+
+// WithLowPriorityRatio provide ratio of limit after which low priority requests would be shed.
+func WithLowPriorityRatio(ratio float64) Option {
+	return func(opt *optionSet) {
+		if ratio > 0 && ratio <= 1 {
+			opt.LowPriorityRatio = ratio
+		}
+	}
+}
+
+// WithPriorityFunc provide function to decide priority of request, paths of priority would be ignored.
+func WithPriorityFunc(f PriorityFunc) Option {
+	return func(opt *optionSet) {
+		opt.PriorityFunc = f
+	}
+}
+
+// WithRegisterer provide prometheus.Registerer to export limit and in-flight requests.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(opt *optionSet) {
+		opt.registerer = registerer
+	}
+}
+
+// PriorityFunc decides priority of request
+type PriorityFunc func(*gin.Context) Priority
+
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
@@ -0,0 +1,105 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginconcurrency
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCtx(path string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, path, nil)
+	return ctx
+}
+
+func TestNewOptionSet(t *testing.T) {
+	// without options
+	set := newOptionSet()
+	assert.NotEmpty(t, set.EntryName)
+	assert.Equal(t, AlgorithmAIMD, set.Algorithm)
+	assert.False(t, set.PerRoute)
+	assert.Equal(t, defaultInitialLimit, set.InitialLimit)
+	assert.Equal(t, defaultMinLimit, set.MinLimit)
+	assert.Equal(t, defaultMaxLimit, set.MaxLimit)
+	assert.Equal(t, defaultLatencyThreshold, set.LatencyThreshold)
+	assert.Equal(t, defaultBackoffRatio, set.BackoffRatio)
+	assert.Equal(t, defaultSmoothing, set.Smoothing)
+	assert.Equal(t, defaultRetryAfter, set.RetryAfter)
+	assert.Equal(t, defaultLowPriorityRatio, set.LowPriorityRatio)
+	assert.Nil(t, set.limitGauge)
+
+	// with options
+	set = newOptionSet(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithAlgorithm(AlgorithmGradient),
+		WithPerRoute(true),
+		WithLimits(10, 2, 50),
+		WithLatencyThreshold(time.Millisecond),
+		WithBackoffRatio(0.5),
+		WithSmoothing(0.1),
+		WithRetryAfter(2*time.Second),
+		WithLowPriorityRatio(0.5),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}),
+		WithRegisterer(prometheus.NewRegistry()))
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "ut-type", set.EntryType)
+	assert.Equal(t, AlgorithmGradient, set.Algorithm)
+	assert.True(t, set.PerRoute)
+	assert.Equal(t, 10, set.InitialLimit)
+	assert.Equal(t, 2, set.MinLimit)
+	assert.Equal(t, 50, set.MaxLimit)
+	assert.Equal(t, time.Millisecond, set.LatencyThreshold)
+	assert.Equal(t, 0.5, set.BackoffRatio)
+	assert.Equal(t, 0.1, set.Smoothing)
+	assert.Equal(t, 2*time.Second, set.RetryAfter)
+	assert.Equal(t, 0.5, set.LowPriorityRatio)
+	assert.True(t, set.Skipper(nil))
+	assert.NotNil(t, set.limitGauge)
+	assert.NotNil(t, set.inflightGauge)
+
+	// initial limit is clamped
+	set = newOptionSet(WithLimits(100, 1, 10))
+	assert.Equal(t, 10, set.InitialLimit)
+}
+
+func TestOptionSet_GetPriority(t *testing.T) {
+	set := newOptionSet(WithCriticalPaths("/rk/v1"), WithLowPriorityPaths("/v1/report"))
+	assert.Equal(t, PriorityCritical, set.GetPriority(newCtx("/rk/v1/ready")))
+	assert.Equal(t, PriorityLow, set.GetPriority(newCtx("/v1/report/daily")))
+	assert.Equal(t, PriorityNormal, set.GetPriority(newCtx("/v1/user")))
+
+	// with priority func
+	set = newOptionSet(WithPriorityFunc(func(*gin.Context) Priority {
+		return PriorityLow
+	}))
+	assert.Equal(t, PriorityLow, set.GetPriority(newCtx("/rk/v1/ready")))
+}
+
+func TestToOptions(t *testing.T) {
+	config := &BootConfig{
+		Enabled:       false,
+		Algorithm:     AlgorithmGradient,
+		CriticalPaths: []string{"/rk/v1"},
+	}
+
+	// with disabled
+	assert.Empty(t, ToOptions(config, "", "", nil))
+
+	// with enabled
+	config.Enabled = true
+	set := newOptionSet(ToOptions(config, "ut-entry", "ut-type", nil)...)
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, AlgorithmGradient, set.Algorithm)
+	assert.Equal(t, []string{"/rk/v1"}, set.criticalPrefix)
+}
@@ -8,6 +8,7 @@ package rkginctx
 
 import (
 	"context"
+	"crypto/x509"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/rookie-ninja/rk-entry/v2/cursor"
@@ -19,6 +20,20 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// AuthPrincipalKey is key of principal authenticated by auth middleware in gin.Context
+	AuthPrincipalKey = "rkAuthPrincipal"
+
+	// TimeoutHeaderKey is header of remaining timeout propagated to outgoing requests, in format of grpc-timeout,
+	// which could be recognized by grpc-gateway.
+	TimeoutHeaderKey = "Grpc-Timeout"
+
+	// max value of grpc-timeout, at most 8 digits
+	maxTimeoutValue = 100000000 - 1
 )
 
 var (
@@ -206,10 +221,77 @@ func InjectSpanToHttpRequest(ctx *gin.Context, req *http.Request) {
 		return
 	}
 
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+
 	newCtx := trace.ContextWithRemoteSpanContext(req.Context(), GetTraceSpan(ctx).SpanContext())
 	if propagator := GetTracerPropagator(ctx); propagator != nil {
 		propagator.Inject(newCtx, propagation.HeaderCarrier(req.Header))
 	}
+
+	// deadline of outgoing request, like timeout of proxy, would be used if earlier than the incoming one
+	remaining, ok := GetRemainingTimeout(ctx)
+	if deadline, exist := req.Context().Deadline(); exist {
+		if outgoing := time.Until(deadline); !ok || outgoing < remaining {
+			remaining, ok = outgoing, true
+		}
+	}
+
+	if ok {
+		req.Header.Set(TimeoutHeaderKey, encodeTimeout(remaining))
+	}
+}
+
+// GetRemainingTimeout return remaining time before deadline of request set by timeout middleware,
+// false would be returned if no deadline exists.
+func GetRemainingTimeout(ctx *gin.Context) (time.Duration, bool) {
+	if ctx == nil || ctx.Request == nil {
+		return 0, false
+	}
+
+	deadline, ok := ctx.Request.Context().Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	if remaining := time.Until(deadline); remaining > 0 {
+		return remaining, true
+	}
+
+	return 0, true
+}
+
+// Encode timeout in format of grpc-timeout, value would be rounded up to fit in 8 digits.
+func encodeTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "0n"
+	}
+
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Nanosecond, "n"},
+		{time.Microsecond, "u"},
+		{time.Millisecond, "m"},
+		{time.Second, "S"},
+		{time.Minute, "M"},
+		{time.Hour, "H"},
+	}
+
+	for i := range units {
+		value := int64(timeout / units[i].unit)
+		if timeout%units[i].unit > 0 {
+			value++
+		}
+
+		if value <= maxTimeoutValue {
+			return strconv.FormatInt(value, 10) + units[i].name
+		}
+	}
+
+	return strconv.FormatInt(maxTimeoutValue, 10) + "H"
 }
 
 // NewTraceSpan start a new span
@@ -247,6 +329,16 @@ func GetJwtToken(ctx *gin.Context) *jwt.Token {
 	return nil
 }
 
+// GetAuthPrincipal return basic auth user or principal of API key authenticated by auth middleware if exists,
+// principal of API key is a prefix of its SHA-256 instead of key itself, see rkginauth.ApiKeyPrincipal.
+func GetAuthPrincipal(ctx *gin.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	return ctx.GetString(AuthPrincipalKey)
+}
+
 // GetCsrfToken return csrf token if exists
 func GetCsrfToken(ctx *gin.Context) string {
 	if ctx == nil {
@@ -261,3 +353,35 @@ func GetCsrfToken(ctx *gin.Context) string {
 
 	return ""
 }
+
+// GetPeerCertificate return verified client certificate if mutual TLS enabled.
+// Subject, SANs and SPIFFE ID of peer could be extracted from it.
+func GetPeerCertificate(ctx *gin.Context) *x509.Certificate {
+	if ctx == nil || ctx.Request == nil || ctx.Request.TLS == nil {
+		return nil
+	}
+
+	// only return certificate which has been verified
+	chains := ctx.Request.TLS.VerifiedChains
+	if len(chains) < 1 || len(chains[0]) < 1 {
+		return nil
+	}
+
+	return chains[0][0]
+}
+
+// GetPeerSpiffeId return SPIFFE ID in URI SANs of verified client certificate if exists.
+func GetPeerSpiffeId(ctx *gin.Context) string {
+	cert := GetPeerCertificate(ctx)
+	if cert == nil {
+		return ""
+	}
+
+	for i := range cert.URIs {
+		if cert.URIs[i].Scheme == "spiffe" {
+			return cert.URIs[i].String()
+		}
+	}
+
+	return ""
+}
@@ -6,6 +6,9 @@
 package rkginctx
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	rkcursor "github.com/rookie-ninja/rk-entry/v2/cursor"
@@ -20,6 +23,7 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestGetIncomingHeaders(t *testing.T) {
@@ -35,6 +39,34 @@ func TestGetIncomingHeaders(t *testing.T) {
 	assert.Equal(t, header, GetIncomingHeaders(ctx))
 }
 
+func TestGetPeerCertificate(t *testing.T) {
+	// with nil context
+	assert.Nil(t, GetPeerCertificate(nil))
+	assert.Empty(t, GetPeerSpiffeId(nil))
+
+	// without TLS
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+	assert.Nil(t, GetPeerCertificate(ctx))
+
+	// with unverified certificate
+	spiffeId, _ := url.Parse("spiffe://ut-domain/ut-service")
+	cert := &x509.Certificate{
+		DNSNames: []string{"ut-service"},
+		URIs:     []*url.URL{spiffeId},
+	}
+	ctx.Request.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+	}
+	assert.Nil(t, GetPeerCertificate(ctx))
+	assert.Empty(t, GetPeerSpiffeId(ctx))
+
+	// with verified certificate
+	ctx.Request.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	assert.Equal(t, cert, GetPeerCertificate(ctx))
+	assert.Equal(t, "spiffe://ut-domain/ut-service", GetPeerSpiffeId(ctx))
+}
+
 func TestGormCtx(t *testing.T) {
 	assert.NotNil(t, GormCtx(&gin.Context{}))
 }
@@ -219,6 +251,60 @@ func TestInjectSpanToHttpRequest(t *testing.T) {
 	InjectSpanToHttpRequest(ctx, &http.Request{
 		Header: http.Header{},
 	})
+
+	// With deadline
+	reqCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	req := &http.Request{}
+	InjectSpanToHttpRequest(ctx, req)
+	assert.Regexp(t, `^\d{1,8}[num]$`, req.Header.Get(TimeoutHeaderKey))
+
+	// With earlier deadline of outgoing request
+	outCtx, outCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer outCancel()
+	req = (&http.Request{}).WithContext(outCtx)
+	InjectSpanToHttpRequest(ctx, req)
+	assert.Regexp(t, `^\d{1,8}n$`, req.Header.Get(TimeoutHeaderKey))
+}
+
+func TestGetRemainingTimeout(t *testing.T) {
+	// With nil context
+	remaining, ok := GetRemainingTimeout(nil)
+	assert.False(t, ok)
+	assert.Zero(t, remaining)
+
+	// Without deadline
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok = GetRemainingTimeout(ctx)
+	assert.False(t, ok)
+
+	// With deadline
+	reqCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+	remaining, ok = GetRemainingTimeout(ctx)
+	assert.True(t, ok)
+	assert.True(t, remaining > 0 && remaining <= time.Minute)
+
+	// With deadline exceeded
+	reqCtx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+	remaining, ok = GetRemainingTimeout(ctx)
+	assert.True(t, ok)
+	assert.Zero(t, remaining)
+}
+
+func TestEncodeTimeout(t *testing.T) {
+	assert.Equal(t, "0n", encodeTimeout(0))
+	assert.Equal(t, "1500n", encodeTimeout(1500*time.Nanosecond))
+	assert.Equal(t, "99999999n", encodeTimeout(99999999*time.Nanosecond))
+	assert.Equal(t, "100000u", encodeTimeout(100*time.Millisecond))
+	assert.Equal(t, "100001u", encodeTimeout(100*time.Millisecond+time.Nanosecond))
+	assert.Equal(t, "200000m", encodeTimeout(200*time.Second))
+	assert.Equal(t, "2562048H", encodeTimeout(time.Duration(1<<63-1)))
 }
 
 func TestNewTraceSpan(t *testing.T) {
@@ -256,6 +342,21 @@ func TestGetJwtToken(t *testing.T) {
 	assert.NotNil(t, GetJwtToken(ctx))
 }
 
+func TestGetAuthPrincipal(t *testing.T) {
+	defer assertNotPanic(t)
+
+	// with nil
+	assert.Empty(t, GetAuthPrincipal(nil))
+
+	// With failure
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Empty(t, GetAuthPrincipal(ctx))
+
+	// With success
+	ctx.Set(AuthPrincipalKey, "ut-user")
+	assert.Equal(t, "ut-user", GetAuthPrincipal(ctx))
+}
+
 func TestGetCsrfToken(t *testing.T) {
 	defer assertNotPanic(t)
 
@@ -7,19 +7,30 @@ package rkgingzip
 
 import (
 	"bytes"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/bodylimit"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
-// Middleware Add gzip compress and decompress interceptors.
+// Middleware Add compress and decompress interceptors, supports br, zstd and gzip.
 //
 // Mainly copied from bellow.
 // https://github.com/labstack/echo/blob/master/middleware/decompress.go
 // https://github.com/labstack/echo/blob/master/middleware/compress.go
+//
+// Encoding of response is negotiated with q-values in Accept-Encoding header, response would be compressed
+// only if size reached MinLength, Content-Type is not excluded and Content-Encoding is not set by handler.
+// Requests expecting server-sent events or upgrading connection would never be compressed,
+// and encoder would be flushed while response flushed, so that streaming response would not stall.
+//
+// Decompressed request body is limited by rkginbodylimit if enabled in front of this middleware,
+// requests exceeded limit would be rejected with 413.
 func Middleware(opts ...Option) gin.HandlerFunc {
 	set := newOptionSet(opts...)
 
@@ -32,14 +43,14 @@ func Middleware(opts ...Option) gin.HandlerFunc {
 		}
 
 		// deal with request decompression
-		switch ctx.Request.Header.Get(headerContentEncoding) {
-		case gzipEncoding:
-			gzipReader := set.decompressPool.Get()
+		encoding := strings.ToLower(strings.TrimSpace(ctx.Request.Header.Get(headerContentEncoding)))
+		if pool, ok := set.decompressPools[encoding]; ok {
+			reader := pool.Get()
 
-			// make gzipReader to read from original request body
-			if err := gzipReader.Reset(ctx.Request.Body); err != nil {
+			// make reader to read from original request body
+			if err := reader.Reset(ctx.Request.Body); err != nil {
 				// return reader back to sync.Pool
-				set.decompressPool.Put(gzipReader)
+				pool.Put(reader)
 
 				// body is empty, keep on going
 				if err == io.EOF {
@@ -47,22 +58,48 @@ func Middleware(opts ...Option) gin.HandlerFunc {
 					return
 				}
 
+				if errors.Is(err, rkginbodylimit.ErrBodyTooLarge) {
+					rkginbodylimit.Abort(ctx)
+					return
+				}
+
 				ctx.AbortWithStatusJSON(http.StatusInternalServerError, rkmid.GetErrorBuilder().New(http.StatusInternalServerError, "Failed to read request body", err))
 
 				return
 			}
 
-			// create a buffer and copy decompressed data into it via gzipReader
+			// create a buffer and copy decompressed data into it via reader,
+			// read one more byte than limit to protect against zip bomb
+			var src io.Reader = reader
+			limit := rkginbodylimit.GetLimit(ctx)
+			if limit > 0 {
+				src = io.LimitReader(reader, limit+1)
+			}
+
 			var buf bytes.Buffer
-			if _, err := io.Copy(&buf, gzipReader); err != nil {
+			if _, err := io.Copy(&buf, src); err != nil {
+				pool.Put(reader)
+				if errors.Is(err, rkginbodylimit.ErrBodyTooLarge) {
+					rkginbodylimit.Abort(ctx)
+					return
+				}
+
 				ctx.AbortWithStatusJSON(http.StatusInternalServerError, rkmid.GetErrorBuilder().New(http.StatusInternalServerError, "Failed to copy request body", err))
 				return
 			}
 
-			// close both gzipReader and original reader in request body
-			gzipReader.Close()
+			if limit > 0 && int64(buf.Len()) > limit {
+				pool.Put(reader)
+				rkginbodylimit.Abort(ctx)
+				return
+			}
+
+			// close both reader and original reader in request body
+			if closer, ok := reader.(io.Closer); ok && encoding == gzipEncoding {
+				closer.Close()
+			}
 			ctx.Request.Body.Close()
-			set.decompressPool.Put(gzipReader)
+			pool.Put(reader)
 
 			// assign decompressed buffer to request
 			ctx.Request.Body = ioutil.NopCloser(&buf)
@@ -70,44 +107,100 @@ func Middleware(opts ...Option) gin.HandlerFunc {
 
 		// deal with response compression
 		ctx.Writer.Header().Add(headerVary, headerAcceptEncoding)
-		// gzip is one of expected encoding type from request
-		if strings.Contains(ctx.Request.Header.Get(headerAcceptEncoding), gzipEncoding) {
-			// set to response header
-			ctx.Writer.Header().Set(headerContentEncoding, gzipEncoding)
-
-			// create gzip writer
-			gzipWriter := set.compressPool.Get()
+		// response of HEAD request has no body, and streaming or upgraded connection should never be compressed
+		if ctx.Request.Method == http.MethodHead || isStreaming(ctx.Request) {
+			ctx.Next()
+			return
+		}
 
-			// reset writer of gzip writer to original writer from response
+		// one of expected encoding type from request
+		if encoding := negotiate(ctx.Request.Header.Get(headerAcceptEncoding), set.encodings); len(encoding) > 0 {
 			originalWriter := ctx.Writer
-			gzipWriter.Reset(originalWriter)
+			compressWriter := newGzipResponseWriter(set, encoding, originalWriter)
 
-			// defer func
 			defer func() {
-				if ctx.Writer.Size() == -1 {
-					// remove encoding header if response is empty
-					if ctx.Writer.Header().Get(headerContentEncoding) == gzipEncoding {
-						ctx.Writer.Header().Del(headerContentEncoding)
-					}
-					// we have to reset response to it's pristine state when
-					// nothing is written to body or error is returned.
-					ctx.Writer = originalWriter
-
-					// reset to empty
-					gzipWriter.Reset(ioutil.Discard)
-				}
+				// write buffered body and close encoder
+				compressWriter.finish()
 
-				// close gzipWriter
-				gzipWriter.Close()
-
-				// put gzipWriter back to pool
-				set.compressPool.Put(gzipWriter)
+				// reset response to it's pristine state
+				ctx.Writer = originalWriter
 			}()
 
 			// assign new writer to response
-			ctx.Writer = newGzipResponseWriter(gzipWriter, originalWriter)
+			ctx.Writer = compressWriter
 		}
 
 		ctx.Next()
 	}
 }
+
+// Whether request expects server-sent events or upgrades connection, like websocket.
+func isStreaming(req *http.Request) bool {
+	if strings.Contains(strings.ToLower(req.Header.Get(headerAccept)), eventStream) {
+		return true
+	}
+
+	if len(req.Header.Get(headerUpgrade)) > 0 {
+		return true
+	}
+
+	for _, token := range strings.Split(req.Header.Get(headerConnection), ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Negotiate encoding of response with q-values in Accept-Encoding header.
+//
+// Encoding with the highest q-value would be picked, ties are broken by order of encodings.
+// Empty string would be returned if none of encodings is acceptable.
+func negotiate(acceptEncoding string, encodings []string) string {
+	if len(strings.TrimSpace(acceptEncoding)) < 1 {
+		return ""
+	}
+
+	qValues := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		tokens := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(tokens[0]))
+		if len(name) < 1 {
+			continue
+		}
+
+		q, valid := 1.0, true
+		for _, param := range tokens[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) < 2 || !strings.EqualFold(param[:2], "q=") {
+				continue
+			}
+
+			v, err := strconv.ParseFloat(strings.TrimSpace(param[2:]), 64)
+			if err != nil || v < 0 || v > 1 {
+				valid = false
+				break
+			}
+			q = v
+		}
+
+		if valid {
+			qValues[name] = q
+		}
+	}
+
+	res, best := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := qValues[encoding]
+		if !ok {
+			q = qValues["*"]
+		}
+
+		if q > best {
+			res, best = encoding, q
+		}
+	}
+
+	return res
+}
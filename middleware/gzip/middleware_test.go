@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/bodylimit"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"net/http"
@@ -102,6 +103,101 @@ func TestInterceptor(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.Code)
 }
 
+func TestInterceptor_WithEncodings(t *testing.T) {
+	defer assertNotPanic(t)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.POST("/post", func(ctx *gin.Context) {
+		buf := new(strings.Builder)
+		io.Copy(buf, ctx.Request.Body)
+		ctx.String(http.StatusOK, buf.String())
+	})
+
+	for _, encoding := range defaultEncodings {
+		// compress request body with encoding
+		body := new(bytes.Buffer)
+		writer := newEncoderPool(encoding, DefaultCompression).Get()
+		writer.Reset(body)
+		writer.Write([]byte("ut-string"))
+		writer.Close()
+
+		resp := performRequest(router, http.MethodPost, "/post", body,
+			header{headerContentEncoding, encoding},
+			header{headerAcceptEncoding, encoding})
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, encoding, resp.Header().Get(headerContentEncoding))
+
+		reader := newDecoderPool(encoding).Get()
+		assert.Nil(t, reader.Reset(resp.Body))
+		res, _ := io.ReadAll(reader)
+		assert.Equal(t, "ut-string", string(res))
+	}
+
+	// without acceptable encoding
+	resp := performRequest(router, http.MethodPost, "/post", getBody(false),
+		header{headerAcceptEncoding, "deflate, gzip;q=0"})
+	assert.Empty(t, resp.Header().Get(headerContentEncoding))
+	assert.Equal(t, "ut-string", resp.Body.String())
+}
+
+func TestNegotiate(t *testing.T) {
+	encodings := []string{brotliEncoding, zstdEncoding, gzipEncoding}
+
+	// without header
+	assert.Empty(t, negotiate("", encodings))
+
+	// with single encoding
+	assert.Equal(t, gzipEncoding, negotiate("gzip", encodings))
+	assert.Equal(t, gzipEncoding, negotiate("deflate, GZIP", encodings))
+
+	// ties are broken by order of encodings
+	assert.Equal(t, brotliEncoding, negotiate("gzip, deflate, br, zstd", encodings))
+	assert.Equal(t, zstdEncoding, negotiate("gzip, zstd", encodings))
+
+	// with q-values
+	assert.Equal(t, gzipEncoding, negotiate("br;q=0.5, gzip;q=0.8, zstd;q=0.1", encodings))
+	assert.Equal(t, zstdEncoding, negotiate("br;q=0, zstd", encodings))
+	assert.Empty(t, negotiate("gzip;q=0", encodings))
+
+	// with wildcard
+	assert.Equal(t, brotliEncoding, negotiate("*", encodings))
+	assert.Equal(t, gzipEncoding, negotiate("*;q=0.1, gzip;q=0.5", encodings))
+	assert.Equal(t, zstdEncoding, negotiate("*, br;q=0", encodings))
+
+	// with invalid q-value
+	assert.Equal(t, gzipEncoding, negotiate("br;q=abc, gzip", encodings))
+	assert.Empty(t, negotiate("gzip;q=2", encodings))
+}
+
+func TestInterceptor_WithBodyLimit(t *testing.T) {
+	defer assertNotPanic(t)
+
+	// compress 1MB of zeros into a few KB
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+	assert.True(t, buf.Len() < 1<<12)
+
+	router := gin.New()
+	router.Use(rkginbodylimit.Middleware(rkginbodylimit.WithMaxBytes(1<<12)), Middleware())
+	router.POST("/post", func(ctx *gin.Context) {
+		io.Copy(io.Discard, ctx.Request.Body)
+		ctx.String(http.StatusOK, "")
+	})
+
+	// 1: decompressed body exceeded limit
+	resp := performRequest(router, http.MethodPost, "/post", bytes.NewReader(buf.Bytes()),
+		header{headerContentEncoding, gzipEncoding})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+
+	// 2: decompressed body within limit
+	resp = performRequest(router, http.MethodPost, "/post", getBody(true),
+		header{headerContentEncoding, gzipEncoding})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
 func performRequest(r http.Handler, method, path string, body io.Reader, headers ...header) *httptest.ResponseRecorder {
 	req := httptest.NewRequest(method, path, body)
 	for _, h := range headers {
@@ -8,10 +8,14 @@ package rkgingzip
 import (
 	"bytes"
 	"compress/gzip"
+	"github.com/andybalholm/brotli"
 	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rs/xid"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"strings"
 	"sync"
 )
@@ -19,6 +23,10 @@ import (
 const (
 	// GzipEncoding encoding type of gzip
 	gzipEncoding = "gzip"
+	// BrotliEncoding encoding type of brotli
+	brotliEncoding = "br"
+	// ZstdEncoding encoding type of zstd
+	zstdEncoding = "zstd"
 	// NoCompression copied from gzip.NoCompression
 	NoCompression = "noCompression"
 	// BestSpeed copied from gzip.BestSpeed
@@ -34,6 +42,10 @@ const (
 	headerContentType     = "Content-Type"
 	headerVary            = "Vary"
 	headerAcceptEncoding  = "Accept-Encoding"
+	headerAccept          = "Accept"
+	headerConnection      = "Connection"
+	headerUpgrade         = "Upgrade"
+	eventStream           = "text/event-stream"
 )
 
 // Interceptor would distinguish auth set based on.
@@ -42,25 +54,64 @@ var (
 	defaultSkipper = func(*gin.Context) bool {
 		return false
 	}
+	// supported encodings, in order of preference while q-values are equal
+	defaultEncodings = []string{brotliEncoding, zstdEncoding, gzipEncoding}
+	// content types which are compressed already
+	defaultExcludedContentTypes = []string{
+		"text/event-stream",
+		"image/png",
+		"image/jpeg",
+		"image/gif",
+		"image/webp",
+		"image/avif",
+		"video/*",
+		"audio/*",
+		"font/woff",
+		"font/woff2",
+		"application/zip",
+		"application/gzip",
+		"application/x-gzip",
+		"application/zstd",
+		"application/x-7z-compressed",
+		"application/x-rar-compressed",
+	}
 )
 
 // Create new optionSet with rpc type nad options.
 func newOptionSet(opts ...Option) *optionSet {
 	set := &optionSet{
-		EntryName:      xid.New().String(),
-		EntryType:      "",
-		Skipper:        defaultSkipper,
-		Level:          DefaultCompression,
-		decompressPool: newDecompressPool(),
-		ignorePrefix:   make([]string, 0),
+		EntryName:       xid.New().String(),
+		EntryType:       "",
+		Skipper:         defaultSkipper,
+		Level:           DefaultCompression,
+		levels:          make(map[string]string),
+		excludedTypes:   append([]string{}, defaultExcludedContentTypes...),
+		compressPools:   make(map[string]*compressPool),
+		decompressPools: make(map[string]*decompressPool),
+		ignorePrefix:    make([]string, 0),
 	}
 
 	for i := range opts {
 		opts[i](set)
 	}
 
-	// create a new compressPool
-	set.compressPool = newCompressPool(set.Level)
+	if len(set.encodings) < 1 {
+		set.encodings = defaultEncodings
+	}
+
+	// create compress pool of each enabled encoding, level of encoding would override default level
+	for _, encoding := range set.encodings {
+		level := set.Level
+		if v, ok := set.levels[encoding]; ok && len(v) > 0 {
+			level = v
+		}
+		set.compressPools[encoding] = newEncoderPool(encoding, level)
+	}
+
+	// request decompression supports every encoding
+	for _, encoding := range defaultEncodings {
+		set.decompressPools[encoding] = newDecoderPool(encoding)
+	}
 
 	if _, ok := optionsMap[set.EntryName]; !ok {
 		optionsMap[set.EntryName] = set
@@ -71,13 +122,18 @@ func newOptionSet(opts ...Option) *optionSet {
 
 // Options which is used while initializing extension interceptor
 type optionSet struct {
-	EntryName      string
-	EntryType      string
-	Skipper        Skipper
-	Level          string
-	decompressPool *decompressPool
-	compressPool   *compressPool
-	ignorePrefix   []string
+	EntryName       string
+	EntryType       string
+	Skipper         Skipper
+	Level           string
+	MinLength       int
+	levels          map[string]string
+	contentTypes    []string
+	excludedTypes   []string
+	encodings       []string
+	compressPools   map[string]*compressPool
+	decompressPools map[string]*decompressPool
+	ignorePrefix    []string
 }
 
 // ShouldIgnore determine whether auth should be ignored based on path
@@ -95,6 +151,30 @@ func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
 	return false
 }
 
+// Whether response should be compressed based on status, size, Content-Type and Content-Encoding.
+func (set *optionSet) shouldCompress(status, size int, header http.Header) bool {
+	// response without body
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+
+	if size < set.MinLength {
+		return false
+	}
+
+	// encoded by handler already
+	if len(header.Get(headerContentEncoding)) > 0 {
+		return false
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(header.Get(headerContentType), ";")[0]))
+	if len(set.contentTypes) > 0 && !matchContentType(set.contentTypes, mediaType) {
+		return false
+	}
+
+	return !matchContentType(set.excludedTypes, mediaType)
+}
+
 // Option if for middleware options while creating middleware
 type Option func(*optionSet)
 
@@ -106,13 +186,67 @@ func WithEntryNameAndType(entryName, entryType string) Option {
 	}
 }
 
-// WithLevel provide level of compressing.
+// WithLevel provide level of compressing, used by every encoding unless overridden by WithEncodingLevel.
+//
+// Level would be mapped to the closest level of each encoding, HuffmanOnly is only meaningful to gzip.
 func WithLevel(level string) Option {
 	return func(opt *optionSet) {
 		opt.Level = level
 	}
 }
 
+// WithEncodingLevel provide level of compressing for one of encoding, options: gzip, br, zstd.
+func WithEncodingLevel(encoding, level string) Option {
+	return func(opt *optionSet) {
+		opt.levels[strings.ToLower(encoding)] = level
+	}
+}
+
+// WithEncodings provide encodings of response compression in order of preference, options: br, zstd, gzip.
+//
+// All of them would be enabled if missing, unknown encodings would be ignored.
+func WithEncodings(encodings ...string) Option {
+	return func(opt *optionSet) {
+		for i := range encodings {
+			encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+			if isSupported(encoding) && !contains(opt.encodings, encoding) {
+				opt.encodings = append(opt.encodings, encoding)
+			}
+		}
+	}
+}
+
+// WithMinLength provide minimum size of response body in bytes to compress, response would be buffered until reached.
+func WithMinLength(minLength int) Option {
+	return func(opt *optionSet) {
+		if minLength > 0 {
+			opt.MinLength = minLength
+		}
+	}
+}
+
+// WithContentTypes provide content types to compress, like application/json or text/*.
+//
+// All content types would be compressed if missing, except excluded ones.
+func WithContentTypes(contentTypes ...string) Option {
+	return func(opt *optionSet) {
+		for i := range contentTypes {
+			opt.contentTypes = append(opt.contentTypes, strings.ToLower(strings.TrimSpace(contentTypes[i])))
+		}
+	}
+}
+
+// WithExcludedContentTypes provide content types which would never be compressed, like image/*.
+//
+// Common compressed content types like image/png and application/zip are excluded by default.
+func WithExcludedContentTypes(contentTypes ...string) Option {
+	return func(opt *optionSet) {
+		for i := range contentTypes {
+			opt.excludedTypes = append(opt.excludedTypes, strings.ToLower(strings.TrimSpace(contentTypes[i])))
+		}
+	}
+}
+
 // WithSkipper provide skipper.
 func WithSkipper(skip Skipper) Option {
 	return func(opt *optionSet) {
@@ -127,57 +261,119 @@ func WithPathToIgnore(prefix ...string) Option {
 	}
 }
 
+// IsValidLevel returns true if level is one of noCompression, bestSpeed, bestCompression, defaultCompression
+// and huffmanOnly regardless of case, empty level is valid since defaultCompression would be used.
+func IsValidLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "", strings.ToLower(NoCompression), strings.ToLower(BestSpeed), strings.ToLower(BestCompression),
+		strings.ToLower(DefaultCompression), strings.ToLower(HuffmanOnly):
+		return true
+	}
+
+	return false
+}
+
+// IsSupportedEncoding returns true if encoding is one of br, zstd and gzip regardless of case.
+func IsSupportedEncoding(encoding string) bool {
+	return isSupported(strings.ToLower(strings.TrimSpace(encoding)))
+}
+
+// Whether encoding is supported.
+func isSupported(encoding string) bool {
+	return contains(defaultEncodings, encoding)
+}
+
+// Match media type with patterns, pattern like text/* matches any subtype.
+func matchContentType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		if pattern == mediaType {
+			return true
+		}
+
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encoder is implemented by gzip.Writer, brotli.Writer and zstd.Encoder
+type encoder interface {
+	io.WriteCloser
+	Reset(io.Writer)
+	Flush() error
+}
+
+// decoder is implemented by gzip.Reader, brotli.Reader and zstd.Decoder
+type decoder interface {
+	io.Reader
+	Reset(io.Reader) error
+}
+
 // sync.Pool is the delegate of this pool
 type compressPool struct {
 	delegate *sync.Pool
 }
 
-// Create a new compress pool
-func newCompressPool(level string) *compressPool {
-	levelLowerCase := strings.ToLower(level)
+// Create a new compress pool of encoding
+func newEncoderPool(encoding, level string) *compressPool {
+	var newFunc func() interface{}
 
-	levelInt := gzip.DefaultCompression
-
-	switch levelLowerCase {
-	case strings.ToLower(NoCompression):
-		levelInt = gzip.NoCompression
-	case strings.ToLower(BestSpeed):
-		levelInt = gzip.BestSpeed
-	case strings.ToLower(BestCompression):
-		levelInt = gzip.BestCompression
-	case strings.ToLower(DefaultCompression):
-		levelInt = gzip.DefaultCompression
-	case strings.ToLower(HuffmanOnly):
-		levelInt = gzip.HuffmanOnly
+	switch encoding {
+	case brotliEncoding:
+		levelInt := toBrotliLevel(level)
+		newFunc = func() interface{} {
+			return brotli.NewWriterLevel(ioutil.Discard, levelInt)
+		}
+	case zstdEncoding:
+		encoderLevel := toZstdLevel(level)
+		newFunc = func() interface{} {
+			// Ok to ignore error since options are valid
+			writer, _ := zstd.NewWriter(ioutil.Discard,
+				zstd.WithEncoderLevel(encoderLevel),
+				zstd.WithEncoderConcurrency(1))
+			return writer
+		}
 	default:
-		levelInt = gzip.DefaultCompression
+		levelInt := toGzipLevel(level)
+		newFunc = func() interface{} {
+			// Ok to ignore error because of toGzipLevel
+			writer, _ := gzip.NewWriterLevel(ioutil.Discard, levelInt)
+			return writer
+		}
 	}
 
 	return &compressPool{
 		delegate: &sync.Pool{
-			New: func() interface{} {
-				// Ok to ignore error because of above switch statement
-				writer, _ := gzip.NewWriterLevel(ioutil.Discard, levelInt)
-				return writer
-			},
+			New: newFunc,
 		},
 	}
 }
 
-// Get item gzip.Writer from pool
-func (p *compressPool) Get() *gzip.Writer {
+// Get item encoder from pool
+func (p *compressPool) Get() encoder {
 	// assert no error
 	raw := p.delegate.Get()
 
-	switch raw.(type) {
-	case *gzip.Writer:
-		return raw.(*gzip.Writer)
+	if w, ok := raw.(encoder); ok {
+		return w
 	}
 
 	return nil
 }
 
-// Put item gzip.Writer back to pool
+// Put item encoder back to pool
 func (p *compressPool) Put(x interface{}) {
 	p.delegate.Put(x)
 }
@@ -187,10 +383,23 @@ type decompressPool struct {
 	delegate *sync.Pool
 }
 
-// Create a new decompress pool
-func newDecompressPool() *decompressPool {
-	pool := &sync.Pool{
-		New: func() interface{} {
+// Create a new decompress pool of encoding
+func newDecoderPool(encoding string) *decompressPool {
+	var newFunc func() interface{}
+
+	switch encoding {
+	case brotliEncoding:
+		newFunc = func() interface{} {
+			return brotli.NewReader(nil)
+		}
+	case zstdEncoding:
+		newFunc = func() interface{} {
+			// decode synchronously, so that no goroutine is leaked while decoder dropped by pool
+			reader, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+			return reader
+		}
+	default:
+		newFunc = func() interface{} {
 			// In order to create a gzip.Reader, we need to pass a bytes with format gzip.
 			// Create a gzip.Writer is the easiest way to achieve this goal.
 			writer, _ := gzip.NewWriterLevel(ioutil.Discard, gzip.DefaultCompression)
@@ -202,65 +411,72 @@ func newDecompressPool() *decompressPool {
 			// Create a reader, ignoring error since we created a empty writer
 			reader, _ := gzip.NewReader(bytes.NewReader(b.Bytes()))
 			return reader
-		},
+		}
 	}
 
 	return &decompressPool{
-		delegate: pool,
+		delegate: &sync.Pool{
+			New: newFunc,
+		},
 	}
 }
 
-// Get item gzip.Reader from pool
-func (p *decompressPool) Get() *gzip.Reader {
+// Get item decoder from pool
+func (p *decompressPool) Get() decoder {
 	// assert no error
 	raw := p.delegate.Get()
 
-	switch raw.(type) {
-	case *gzip.Reader:
-		return raw.(*gzip.Reader)
+	if r, ok := raw.(decoder); ok {
+		return r
 	}
 
 	return nil
 }
 
-// Put item gzip.Reader back to pool
+// Put item decoder back to pool
 func (p *decompressPool) Put(x interface{}) {
 	p.delegate.Put(x)
 }
 
-// Skipper default skipper will always return false
-type Skipper func(*gin.Context) bool
-
-// Copied from https://github.com/labstack/echo/blob/master/middleware/compress.go
-//
-// Why not use middleware.GzipWithConfig directly?
-//
-// rk-echo support multi-entries of echo framework. In order to match rk-echo architecture,
-// we need to modify some of logic in middleware.
-type gzipResponseWriter struct {
-	gin.ResponseWriter
-	writer *gzip.Writer
-}
-
-func newGzipResponseWriter(w *gzip.Writer, rw gin.ResponseWriter) *gzipResponseWriter {
-	return &gzipResponseWriter{
-		writer:         w,
-		ResponseWriter: rw,
+// Map level to gzip level.
+func toGzipLevel(level string) int {
+	switch strings.ToLower(level) {
+	case strings.ToLower(NoCompression):
+		return gzip.NoCompression
+	case strings.ToLower(BestSpeed):
+		return gzip.BestSpeed
+	case strings.ToLower(BestCompression):
+		return gzip.BestCompression
+	case strings.ToLower(HuffmanOnly):
+		return gzip.HuffmanOnly
+	default:
+		return gzip.DefaultCompression
 	}
 }
 
-func (g *gzipResponseWriter) WriteString(s string) (int, error) {
-	g.ResponseWriter.Header().Del("Content-Length")
-	return g.writer.Write([]byte(s))
+// Map level to brotli level, brotli has no level without compression, so BestSpeed would be used instead.
+func toBrotliLevel(level string) int {
+	switch strings.ToLower(level) {
+	case strings.ToLower(NoCompression), strings.ToLower(BestSpeed), strings.ToLower(HuffmanOnly):
+		return brotli.BestSpeed
+	case strings.ToLower(BestCompression):
+		return brotli.BestCompression
+	default:
+		return brotli.DefaultCompression
+	}
 }
 
-func (g *gzipResponseWriter) Write(data []byte) (int, error) {
-	g.ResponseWriter.Header().Del("Content-Length")
-	return g.writer.Write(data)
+// Map level to zstd level, zstd has no level without compression, so SpeedFastest would be used instead.
+func toZstdLevel(level string) zstd.EncoderLevel {
+	switch strings.ToLower(level) {
+	case strings.ToLower(NoCompression), strings.ToLower(BestSpeed), strings.ToLower(HuffmanOnly):
+		return zstd.SpeedFastest
+	case strings.ToLower(BestCompression):
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
 }
 
-// Fix: https://github.com/mholt/caddy/issues/38
-func (g *gzipResponseWriter) WriteHeader(code int) {
-	g.ResponseWriter.Header().Del("Content-Length")
-	g.ResponseWriter.WriteHeader(code)
-}
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
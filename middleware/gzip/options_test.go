@@ -7,9 +7,9 @@ package rkgingzip
 
 import (
 	"bytes"
-	"compress/gzip"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"net/http"
 	"testing"
 )
@@ -52,8 +52,11 @@ func TestNewOptionSet(t *testing.T) {
 	assert.NotEmpty(t, set.EntryName)
 	assert.False(t, set.Skipper(ctx))
 	assert.Equal(t, DefaultCompression, set.Level)
-	assert.NotNil(t, set.decompressPool)
-	assert.NotNil(t, set.compressPool)
+	assert.Equal(t, defaultEncodings, set.encodings)
+	for _, encoding := range defaultEncodings {
+		assert.NotNil(t, set.decompressPools[encoding])
+		assert.NotNil(t, set.compressPools[encoding])
+	}
 
 	// with level
 	set = newOptionSet(
@@ -63,47 +66,64 @@ func TestNewOptionSet(t *testing.T) {
 			return true
 		}))
 	assert.Equal(t, NoCompression, set.Level)
-}
-
-func TestNewCompressPool(t *testing.T) {
-	// with DefaultCompression
-	pool := newCompressPool(DefaultCompression)
-	assert.NotNil(t, pool.delegate.Get())
-
-	// with NoCompression
-	pool = newCompressPool(NoCompression)
-	assert.NotNil(t, pool.delegate.Get())
 
-	// with DefaultCompression
-	pool = newCompressPool(BestSpeed)
-	assert.NotNil(t, pool.delegate.Get())
-
-	// with DefaultCompression
-	pool = newCompressPool(BestCompression)
-	assert.NotNil(t, pool.delegate.Get())
+	// with encodings
+	set = newOptionSet(
+		WithEncodings("GZIP", "invalid", "zstd", "gzip"),
+		WithEncodingLevel("zstd", BestSpeed))
+	assert.Equal(t, []string{gzipEncoding, zstdEncoding}, set.encodings)
+	assert.Equal(t, BestSpeed, set.levels[zstdEncoding])
+	assert.Len(t, set.compressPools, 2)
+	assert.Len(t, set.decompressPools, 3)
+}
 
-	// with DefaultCompression
-	pool = newCompressPool(DefaultCompression)
-	assert.NotNil(t, pool.delegate.Get())
+func TestIsValidLevel(t *testing.T) {
+	assert.True(t, IsValidLevel(""))
+	assert.True(t, IsValidLevel(BestSpeed))
+	assert.True(t, IsValidLevel("HUFFMANONLY"))
+	assert.False(t, IsValidLevel("fastest"))
+}
 
-	// with DefaultCompression
-	pool = newCompressPool(HuffmanOnly)
-	assert.NotNil(t, pool.delegate.Get())
+func TestIsSupportedEncoding(t *testing.T) {
+	assert.True(t, IsSupportedEncoding(" BR "))
+	assert.True(t, IsSupportedEncoding("gzip"))
+	assert.False(t, IsSupportedEncoding("deflate"))
+}
 
-	// with DefaultCompression
-	pool = newCompressPool("invalid")
-	assert.NotNil(t, pool.delegate.Get())
+func TestNewEncoderPool(t *testing.T) {
+	for _, encoding := range defaultEncodings {
+		for _, level := range []string{NoCompression, BestSpeed, BestCompression, DefaultCompression, HuffmanOnly, "invalid"} {
+			pool := newEncoderPool(encoding, level)
+			writer := pool.Get()
+			assert.NotNil(t, writer)
+
+			// round trip with decoder of the same encoding
+			buf := new(bytes.Buffer)
+			writer.Reset(buf)
+			writer.Write([]byte("ut-message"))
+			writer.Close()
+			pool.Put(writer)
+
+			reader := newDecoderPool(encoding).Get()
+			assert.Nil(t, reader.Reset(buf))
+			res, err := io.ReadAll(reader)
+			assert.Nil(t, err)
+			assert.Equal(t, "ut-message", string(res))
+		}
+	}
 }
 
 func TestCompressPool_Get(t *testing.T) {
-	pool := newCompressPool(DefaultCompression)
-	assert.NotNil(t, pool.Get())
+	for _, encoding := range defaultEncodings {
+		pool := newEncoderPool(encoding, DefaultCompression)
+		assert.NotNil(t, pool.Get(), encoding)
+	}
 }
 
 func TestCompressPool_Put(t *testing.T) {
 	defer assertNotPanic(t)
 
-	pool := newCompressPool(DefaultCompression)
+	pool := newEncoderPool(gzipEncoding, DefaultCompression)
 	// put different types of value
 	pool.Put(nil)
 	pool.Put("string")
@@ -111,60 +131,22 @@ func TestCompressPool_Put(t *testing.T) {
 }
 
 func TestDecompressPool_Get(t *testing.T) {
-	pool := newDecompressPool()
-	assert.NotNil(t, pool.Get())
+	for _, encoding := range defaultEncodings {
+		pool := newDecoderPool(encoding)
+		assert.NotNil(t, pool.Get(), encoding)
+	}
 }
 
 func TestDecompressPool_Put(t *testing.T) {
 	defer assertNotPanic(t)
 
-	pool := newDecompressPool()
+	pool := newDecoderPool(gzipEncoding)
 	// put different types of value
 	pool.Put(nil)
 	pool.Put("string")
 	pool.Put(1)
 }
 
-func TestGzipResponseWriter(t *testing.T) {
-	defer assertNotPanic(t)
-
-	// WriteHeader() write header with http.StatusNoContent
-	rw := NewMockResponseWriter()
-	ctx, _ := gin.CreateTestContext(rw)
-	w := gzip.NewWriter(new(bytes.Buffer))
-	gzipRW := newGzipResponseWriter(w, ctx.Writer)
-	gzipRW.WriteHeader(http.StatusNoContent)
-	assert.Empty(t, rw.Header().Get(headerContentEncoding))
-
-	// WriteHeader() write header with other status code
-	rw = NewMockResponseWriter()
-	ctx, _ = gin.CreateTestContext(rw)
-	w = gzip.NewWriter(new(bytes.Buffer))
-	gzipRW = newGzipResponseWriter(w, ctx.Writer)
-	gzipRW.WriteHeader(http.StatusOK)
-
-	// Write() without Content-Type
-	rw = NewMockResponseWriter()
-	ctx, _ = gin.CreateTestContext(rw)
-	buf := new(bytes.Buffer)
-	w = gzip.NewWriter(buf)
-	gzipRW = newGzipResponseWriter(w, ctx.Writer)
-	gzipRW.Write([]byte("ut-message"))
-	assert.Empty(t, rw.Header().Get(headerContentLength))
-	assert.NotEmpty(t, buf.String())
-
-	// Write() with Content-Type
-	rw = NewMockResponseWriter()
-	ctx, _ = gin.CreateTestContext(rw)
-	buf = new(bytes.Buffer)
-	w = gzip.NewWriter(buf)
-	gzipRW = newGzipResponseWriter(w, ctx.Writer)
-	rw.Header().Set(headerContentType, "ut-type")
-	gzipRW.Write([]byte("ut-message"))
-	assert.NotEmpty(t, rw.Header().Get(headerContentType))
-	assert.NotEmpty(t, buf.String())
-}
-
 func assertNotPanic(t *testing.T) {
 	if r := recover(); r != nil {
 		// Expect panic to be called with non nil error
@@ -0,0 +1,173 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgingzip
+
+import (
+	"bufio"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Copied from https://github.com/labstack/echo/blob/master/middleware/compress.go
+//
+// Why not use middleware.GzipWithConfig directly?
+//
+// rk-echo support multi-entries of echo framework. In order to match rk-echo architecture,
+// we need to modify some of logic in middleware.
+//
+// Response body would be buffered until MinLength reached, then writer decides whether to compress
+// based on status, Content-Type and Content-Encoding of response.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	set      *optionSet
+	encoding string
+	writer   encoder
+	buf      []byte
+	decided  bool
+	written  bool
+}
+
+func newGzipResponseWriter(set *optionSet, encoding string, rw gin.ResponseWriter) *gzipResponseWriter {
+	return &gzipResponseWriter{
+		ResponseWriter: rw,
+		set:            set,
+		encoding:       encoding,
+	}
+}
+
+func (g *gzipResponseWriter) WriteString(s string) (int, error) {
+	return g.Write([]byte(s))
+}
+
+func (g *gzipResponseWriter) Write(data []byte) (int, error) {
+	g.written = true
+
+	if g.decided {
+		return g.write(data)
+	}
+
+	// keep buffering until threshold reached, unless size is known from Content-Length
+	g.buf = append(g.buf, data...)
+	if len(g.buf) < g.set.MinLength && g.contentLength() < 0 {
+		return len(data), nil
+	}
+
+	if err := g.decide(); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// WriteHeaderNow decides whether to compress before header is written.
+func (g *gzipResponseWriter) WriteHeaderNow() {
+	if !g.decided {
+		g.decide()
+	}
+	g.ResponseWriter.WriteHeaderNow()
+}
+
+// Written returns true if body has been written, including buffered one.
+func (g *gzipResponseWriter) Written() bool {
+	return g.written || g.ResponseWriter.Written()
+}
+
+// Flush decides whether to compress with buffered body, and flushes encoder before flushing response,
+// so that streaming response would not stall behind compression.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.decide()
+	}
+
+	if g.writer != nil {
+		g.writer.Flush()
+	}
+
+	g.ResponseWriter.Flush()
+}
+
+// Hijack hijacks connection if nothing has been written, compression would be disabled afterwards.
+func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if g.writer != nil || len(g.buf) > 0 {
+		return nil, nil, errors.New("failed to hijack connection since response is being compressed")
+	}
+
+	g.decided = true
+	return g.ResponseWriter.Hijack()
+}
+
+// CloseNotify implements http.CloseNotifier.
+func (g *gzipResponseWriter) CloseNotify() <-chan bool {
+	return g.ResponseWriter.CloseNotify()
+}
+
+// Decide whether to compress and write buffered body.
+func (g *gzipResponseWriter) decide() error {
+	g.decided = true
+	header := g.ResponseWriter.Header()
+
+	// detect Content-Type from uncompressed data, since http.ResponseWriter would sniff compressed data
+	if len(header.Get(headerContentType)) < 1 && len(g.buf) > 0 {
+		header.Set(headerContentType, http.DetectContentType(g.buf))
+	}
+
+	size := g.contentLength()
+	if size < 0 {
+		size = len(g.buf)
+	}
+
+	if g.set.shouldCompress(g.ResponseWriter.Status(), size, header) {
+		header.Set(headerContentEncoding, g.encoding)
+		header.Del(headerContentLength)
+		g.writer = g.set.compressPools[g.encoding].Get()
+		g.writer.Reset(g.ResponseWriter)
+	}
+
+	buf := g.buf
+	g.buf = nil
+	if len(buf) < 1 {
+		return nil
+	}
+
+	_, err := g.write(buf)
+	return err
+}
+
+// Write data with encoder if compressing.
+func (g *gzipResponseWriter) write(data []byte) (int, error) {
+	if g.writer != nil {
+		return g.writer.Write(data)
+	}
+
+	return g.ResponseWriter.Write(data)
+}
+
+// Content-Length set by handler, -1 if missing or invalid.
+func (g *gzipResponseWriter) contentLength() int {
+	if v := g.ResponseWriter.Header().Get(headerContentLength); len(v) > 0 {
+		if res, err := strconv.Atoi(v); err == nil && res >= 0 {
+			return res
+		}
+	}
+
+	return -1
+}
+
+// Write buffered body and close encoder, encoder would be put back to pool.
+func (g *gzipResponseWriter) finish() {
+	if !g.decided && len(g.buf) > 0 {
+		g.decide()
+	}
+
+	if g.writer != nil {
+		g.writer.Close()
+		g.set.compressPools[g.encoding].Put(g.writer)
+		g.writer = nil
+	}
+}
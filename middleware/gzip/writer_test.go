@@ -0,0 +1,266 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgingzip
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestWriter(opts ...Option) (*gzipResponseWriter, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	return newGzipResponseWriter(newOptionSet(opts...), gzipEncoding, ctx.Writer), w
+}
+
+func TestGzipResponseWriter(t *testing.T) {
+	defer assertNotPanic(t)
+
+	// WriteHeader() write header with http.StatusNoContent
+	rw, w := newTestWriter()
+	rw.WriteHeader(http.StatusNoContent)
+	rw.WriteHeaderNow()
+	rw.finish()
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get(headerContentEncoding))
+
+	// Write() without Content-Type
+	rw, w = newTestWriter()
+	rw.Header().Set(headerContentLength, "10")
+	rw.Write([]byte("ut-message"))
+	rw.finish()
+	assert.Equal(t, gzipEncoding, w.Header().Get(headerContentEncoding))
+	assert.Empty(t, w.Header().Get(headerContentLength))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get(headerContentType))
+	assert.Equal(t, "ut-message", readResponse(true, w.Body))
+
+	// Write() with Content-Type
+	rw, w = newTestWriter()
+	rw.Header().Set(headerContentType, "ut-type")
+	rw.WriteString("ut-message")
+	rw.finish()
+	assert.Equal(t, "ut-type", w.Header().Get(headerContentType))
+	assert.Equal(t, "ut-message", readResponse(true, w.Body))
+
+	// nothing written
+	rw, w = newTestWriter()
+	rw.finish()
+	assert.False(t, rw.Written())
+	assert.Empty(t, w.Header().Get(headerContentEncoding))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestGzipResponseWriter_WithMinLength(t *testing.T) {
+	defer assertNotPanic(t)
+
+	// below threshold
+	rw, w := newTestWriter(WithMinLength(20))
+	rw.Write([]byte("ut-message"))
+	assert.True(t, rw.Written())
+	assert.Empty(t, w.Body.String())
+	rw.finish()
+	assert.Empty(t, w.Header().Get(headerContentEncoding))
+	assert.Equal(t, "ut-message", w.Body.String())
+
+	// reached threshold with multiple writes
+	rw, w = newTestWriter(WithMinLength(20))
+	rw.Write([]byte("ut-message"))
+	rw.Write([]byte("ut-message"))
+	assert.Equal(t, gzipEncoding, w.Header().Get(headerContentEncoding))
+	rw.finish()
+	assert.Equal(t, "ut-messageut-message", readResponse(true, w.Body))
+
+	// with Content-Length below threshold, decided without buffering
+	rw, w = newTestWriter(WithMinLength(20))
+	rw.Header().Set(headerContentLength, "10")
+	rw.Write([]byte("ut-message"))
+	assert.Equal(t, "ut-message", w.Body.String())
+	assert.Equal(t, "10", w.Header().Get(headerContentLength))
+	rw.finish()
+}
+
+func TestGzipResponseWriter_WithContentEncoding(t *testing.T) {
+	defer assertNotPanic(t)
+
+	rw, w := newTestWriter()
+	rw.Header().Set(headerContentEncoding, brotliEncoding)
+	rw.Write([]byte("ut-message"))
+	rw.finish()
+	assert.Equal(t, brotliEncoding, w.Header().Get(headerContentEncoding))
+	assert.Equal(t, "ut-message", w.Body.String())
+}
+
+func TestGzipResponseWriter_WithNotModified(t *testing.T) {
+	defer assertNotPanic(t)
+
+	rw, w := newTestWriter()
+	rw.WriteHeader(http.StatusNotModified)
+	rw.Write([]byte("ut-message"))
+	rw.finish()
+	assert.Empty(t, w.Header().Get(headerContentEncoding))
+}
+
+func TestOptionSet_shouldCompress(t *testing.T) {
+	header := func(contentType string) http.Header {
+		return http.Header{headerContentType: []string{contentType}}
+	}
+
+	// with default excluded content types
+	set := newOptionSet()
+	assert.True(t, set.shouldCompress(http.StatusOK, 0, header("application/json; charset=utf-8")))
+	assert.True(t, set.shouldCompress(http.StatusOK, 0, header("")))
+	assert.False(t, set.shouldCompress(http.StatusOK, 0, header("image/png")))
+	assert.False(t, set.shouldCompress(http.StatusOK, 0, header("VIDEO/mp4")))
+	assert.False(t, set.shouldCompress(http.StatusNoContent, 0, header("")))
+	assert.False(t, set.shouldCompress(http.StatusSwitchingProtocols, 0, header("")))
+
+	// with content types
+	set = newOptionSet(
+		WithMinLength(10),
+		WithContentTypes("application/json", "text/*"),
+		WithExcludedContentTypes("text/csv"))
+	assert.True(t, set.shouldCompress(http.StatusOK, 10, header("application/json")))
+	assert.True(t, set.shouldCompress(http.StatusOK, 10, header("text/html; charset=utf-8")))
+	assert.False(t, set.shouldCompress(http.StatusOK, 10, header("text/csv")))
+	assert.False(t, set.shouldCompress(http.StatusOK, 10, header("application/xml")))
+	assert.False(t, set.shouldCompress(http.StatusOK, 9, header("application/json")))
+}
+
+func TestInterceptor_WithHead(t *testing.T) {
+	defer assertNotPanic(t)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.HEAD("/head", func(ctx *gin.Context) {
+		ctx.Header(headerContentLength, "9")
+		ctx.Status(http.StatusOK)
+	})
+
+	resp := performRequest(router, http.MethodHead, "/head", strings.NewReader(""),
+		header{headerAcceptEncoding, gzipEncoding})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get(headerContentEncoding))
+	assert.Equal(t, "9", resp.Header().Get(headerContentLength))
+}
+
+func TestGzipResponseWriter_Flush(t *testing.T) {
+	defer assertNotPanic(t)
+
+	// compressed data should be readable once flushed
+	rw, w := newTestWriter()
+	rw.Write([]byte("ut-message"))
+	rw.Flush()
+	assert.True(t, w.Flushed)
+	zr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	assert.Nil(t, err)
+	res := make([]byte, len("ut-message"))
+	_, err = io.ReadFull(zr, res)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-message", string(res))
+	rw.finish()
+
+	// flushed before threshold, response would not be compressed
+	rw, w = newTestWriter(WithMinLength(20))
+	rw.Write([]byte("ut-message"))
+	rw.Flush()
+	assert.Equal(t, "ut-message", w.Body.String())
+	rw.Write([]byte("ut-message"))
+	rw.finish()
+	assert.Empty(t, w.Header().Get(headerContentEncoding))
+	assert.Equal(t, "ut-messageut-message", w.Body.String())
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+	closed   chan bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func (h *hijackRecorder) CloseNotify() <-chan bool {
+	return h.closed
+}
+
+func TestGzipResponseWriter_Hijack(t *testing.T) {
+	defer assertNotPanic(t)
+
+	// hijack before writing
+	recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, _ := gin.CreateTestContext(recorder)
+	rw := newGzipResponseWriter(newOptionSet(), gzipEncoding, ctx.Writer)
+	_, _, err := rw.Hijack()
+	assert.Nil(t, err)
+	assert.True(t, recorder.hijacked)
+	rw.finish()
+	assert.Empty(t, recorder.Header().Get(headerContentEncoding))
+
+	// hijack while compressing
+	recorder = &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, _ = gin.CreateTestContext(recorder)
+	rw = newGzipResponseWriter(newOptionSet(), gzipEncoding, ctx.Writer)
+	rw.Write([]byte("ut-message"))
+	_, _, err = rw.Hijack()
+	assert.NotNil(t, err)
+	assert.False(t, recorder.hijacked)
+	rw.finish()
+}
+
+func TestGzipResponseWriter_CloseNotify(t *testing.T) {
+	defer assertNotPanic(t)
+
+	recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool)}
+	ctx, _ := gin.CreateTestContext(recorder)
+	rw := newGzipResponseWriter(newOptionSet(), gzipEncoding, ctx.Writer)
+	assert.Equal(t, (<-chan bool)(recorder.closed), rw.CloseNotify())
+}
+
+func TestInterceptor_WithStreaming(t *testing.T) {
+	defer assertNotPanic(t)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/sse", func(ctx *gin.Context) {
+		ctx.SSEvent("message", "ut-message")
+		ctx.Writer.Flush()
+	})
+	router.GET("/ws", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ut-message")
+	})
+
+	// server-sent events expected by request
+	resp := performRequest(router, http.MethodGet, "/sse", nil,
+		header{headerAcceptEncoding, gzipEncoding},
+		header{headerAccept, eventStream})
+	assert.Empty(t, resp.Header().Get(headerContentEncoding))
+	assert.Contains(t, resp.Body.String(), "ut-message")
+
+	// server-sent events without Accept header, excluded by Content-Type
+	resp = performRequest(router, http.MethodGet, "/sse", nil,
+		header{headerAcceptEncoding, gzipEncoding})
+	assert.Empty(t, resp.Header().Get(headerContentEncoding))
+	assert.Contains(t, resp.Body.String(), "ut-message")
+
+	// upgrade request
+	resp = performRequest(router, http.MethodGet, "/ws", nil,
+		header{headerAcceptEncoding, gzipEncoding},
+		header{headerConnection, "keep-alive, Upgrade"},
+		header{headerUpgrade, "websocket"})
+	assert.Empty(t, resp.Header().Get(headerContentEncoding))
+	assert.Equal(t, "ut-message", resp.Body.String())
+}
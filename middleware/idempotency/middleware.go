@@ -0,0 +1,197 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package rkginidempotency is an Idempotency-Key middleware for gin framework
+package rkginidempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/bodylimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// Middleware Add idempotency interceptors.
+//
+// The first request with an idempotency key would be executed and its response would be recorded in Store,
+// repeated requests with the same key would be answered with recorded response.
+//
+// Concurrent duplicates would be rejected with 409, and reusing key with a different request would be rejected with 422.
+// Key would be released if response is 5xx or handler panics, so that client could retry.
+// Request body larger than limit of body limit middleware or MaxBodyBytes would be rejected with 413.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) {
+			ctx.Next()
+			return
+		}
+
+		idempotencyKey := ctx.GetHeader(set.Header)
+		if len(idempotencyKey) < 1 {
+			if set.Required {
+				abort(ctx, http.StatusBadRequest, fmt.Sprintf("Missing %s header", set.Header))
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		maxBytes := rkginbodylimit.GetLimit(ctx)
+		if maxBytes < 1 {
+			maxBytes = set.MaxBodyBytes
+		}
+
+		fingerprint, err := getFingerprint(ctx.Request, maxBytes)
+		if errors.Is(err, rkginbodylimit.ErrBodyTooLarge) {
+			rkginbodylimit.Abort(ctx)
+			return
+		}
+		if err != nil {
+			abort(ctx, http.StatusBadRequest, "Failed to read request body", err.Error())
+			return
+		}
+
+		// keys of different principals never collide
+		key := fmt.Sprintf("%s:%s:%s", set.EntryName, rkginctx.GetAuthPrincipal(ctx), idempotencyKey)
+
+		existing, ok, err := set.store.Reserve(ctx.Request.Context(), key, &Record{Fingerprint: fingerprint}, set.LockTimeout)
+		if err != nil {
+			abort(ctx, http.StatusServiceUnavailable, "Idempotency store is not available", err.Error())
+			return
+		}
+
+		if !ok {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				abort(ctx, http.StatusUnprocessableEntity, fmt.Sprintf("%s has been used with a different request", set.Header))
+			case !existing.Completed:
+				abort(ctx, http.StatusConflict, fmt.Sprintf("Request with the same %s is in progress", set.Header))
+			default:
+				replay(ctx, existing)
+			}
+			return
+		}
+
+		before := ctx.Writer.Header().Clone()
+		w := &writer{ResponseWriter: ctx.Writer}
+		ctx.Writer = w
+
+		// restore writer and release key if panic occurs
+		completed := false
+		defer func() {
+			ctx.Writer = w.ResponseWriter
+			if !completed {
+				if err := set.store.Release(ctx.Request.Context(), key); err != nil {
+					rkginctx.GetLogger(ctx).Warn("Failed to release idempotency key", zap.Error(err))
+				}
+			}
+		}()
+
+		ctx.Next()
+
+		if ctx.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		record := &Record{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      ctx.Writer.Status(),
+			Header:      diffHeader(before, ctx.Writer.Header()),
+			Body:        w.body.Bytes(),
+		}
+		if err := set.store.Complete(ctx.Request.Context(), key, record, set.TTL); err != nil {
+			rkginctx.GetLogger(ctx).Warn("Failed to record response of idempotency key", zap.Error(err))
+			return
+		}
+		completed = true
+	}
+}
+
+// writer copies response body while writing to client
+type writer struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write will write data to response body
+func (w *writer) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString will write string to response body
+func (w *writer) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Write recorded response to client.
+func replay(ctx *gin.Context, record *Record) {
+	dst := ctx.Writer.Header()
+	for k, vv := range record.Header {
+		dst[k] = append([]string(nil), vv...)
+	}
+	dst.Set(HeaderReplayed, "true")
+
+	ctx.Writer.WriteHeader(record.Status)
+	ctx.Writer.WriteHeaderNow()
+	ctx.Writer.Write(record.Body)
+	ctx.Abort()
+}
+
+// Abort with error response built by error builder.
+func abort(ctx *gin.Context, code int, msg string, details ...interface{}) {
+	resp := rkmid.GetErrorBuilder().New(code, msg, details...)
+	ctx.AbortWithStatusJSON(resp.Code(), resp)
+}
+
+// Fingerprint of method, URI and body, body would be restored for handlers.
+// ErrBodyTooLarge would be returned if body exceeded maxBytes.
+func getFingerprint(req *http.Request, maxBytes int64) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+
+	if req.Body != nil && req.Body != http.NoBody {
+		// read one more byte to detect exceeding
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxBytes+1))
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > maxBytes {
+			return "", rkginbodylimit.ErrBodyTooLarge
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Headers added or changed after handlers, headers of outer middleware like request id are excluded.
+func diffHeader(before, after http.Header) http.Header {
+	res := make(http.Header)
+	for k, vv := range after {
+		if !reflect.DeepEqual(before[k], vv) {
+			res[k] = append([]string(nil), vv...)
+		}
+	}
+
+	return res
+}
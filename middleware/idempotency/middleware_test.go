@@ -0,0 +1,200 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginidempotency
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/bodylimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(router *gin.Engine, path, key, body string, headers ...string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if len(key) > 0 {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Header("X-Request-Id", "ut-request-id")
+		ctx.Set(rkginctx.AuthPrincipalKey, ctx.GetHeader("X-Principal"))
+	}, Middleware())
+	router.POST("/ut-pay", func(ctx *gin.Context) {
+		calls++
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.Header("X-Payment-Id", "ut-payment")
+		ctx.String(http.StatusCreated, "paid:"+string(body))
+	})
+
+	// first request
+	w := serve(router, "/ut-pay", "ut-key", "100")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "paid:100", w.Body.String())
+	assert.Empty(t, w.Header().Get(HeaderReplayed))
+
+	// replayed
+	w = serve(router, "/ut-pay", "ut-key", "100")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "paid:100", w.Body.String())
+	assert.Equal(t, "ut-payment", w.Header().Get("X-Payment-Id"))
+	assert.Equal(t, "ut-request-id", w.Header().Get("X-Request-Id"))
+	assert.Equal(t, "true", w.Header().Get(HeaderReplayed))
+	assert.Equal(t, 1, calls)
+
+	// reused with different payload
+	w = serve(router, "/ut-pay", "ut-key", "200")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, calls)
+
+	// same key of another principal
+	w = serve(router, "/ut-pay", "ut-key", "200", "X-Principal", "ut-other")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, calls)
+
+	// without key
+	serve(router, "/ut-pay", "", "100")
+	serve(router, "/ut-pay", "", "100")
+	assert.Equal(t, 4, calls)
+}
+
+func TestMiddleware_WithConcurrentDuplicate(t *testing.T) {
+	calls := 0
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(Middleware())
+	router.POST("/ut-slow", func(ctx *gin.Context) {
+		calls++
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve(router, "/ut-slow", "ut-key", "")
+	}()
+
+	// wait until first request reserved key
+	var w *httptest.ResponseRecorder
+	assert.Eventually(t, func() bool {
+		w = serve(router, "/ut-slow", "ut-key", "")
+		return w.Code == http.StatusConflict
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, w.Body.String(), "in progress")
+
+	close(release)
+	assert.Equal(t, http.StatusOK, (<-done).Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMiddleware_WithError(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware())
+	router.POST("/ut-error", func(ctx *gin.Context) {
+		calls++
+		ctx.Status(http.StatusInternalServerError)
+	})
+
+	// key is released on server error
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-error", "ut-key", "").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-error", "ut-key", "").Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddleware_WithRequired(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/ut-pay", strings.NewReader("100"))
+	Middleware(WithRequired(true))(ctx)
+
+	assert.True(t, ctx.IsAborted())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Missing Idempotency-Key header")
+}
+
+func TestMiddleware_WithLargeBody(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(Middleware(WithMaxBodyBytes(4)))
+	router.POST("/ut-pay", func(ctx *gin.Context) {
+		calls++
+		ctx.Status(http.StatusCreated)
+	})
+
+	// body within limit
+	assert.Equal(t, http.StatusCreated, serve(router, "/ut-pay", "ut-key", "1000").Code)
+
+	// body exceeded limit
+	w := serve(router, "/ut-pay", "ut-large-key", "10000")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMiddleware_WithBodyLimit(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(rkginbodylimit.Middleware(rkginbodylimit.WithMaxBytes(2)), Middleware())
+	router.POST("/ut-pay", func(ctx *gin.Context) {
+		calls++
+		ctx.Status(http.StatusCreated)
+	})
+
+	// limit of body limit middleware is used, length of chunked body is unknown
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/ut-pay", strings.NewReader("100"))
+	req.ContentLength = -1
+	req.Header.Set(HeaderIdempotencyKey, "ut-key")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, 0, calls)
+}
+
+func TestMiddleware_WithPanic(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		defer func() {
+			if recover() != nil {
+				// writer of idempotency middleware is restored
+				_, ok := ctx.Writer.(*writer)
+				assert.False(t, ok)
+				ctx.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+		ctx.Next()
+	}, Middleware())
+	router.POST("/ut-panic", func(ctx *gin.Context) {
+		calls++
+		panic("ut-panic")
+	})
+
+	// key is released on panic
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-panic", "ut-key", "").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(router, "/ut-panic", "ut-key", "").Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.ReleaseMode)
+	os.Exit(m.Run())
+}
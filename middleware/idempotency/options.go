@@ -0,0 +1,226 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginidempotency
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rs/xid"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderIdempotencyKey is default header of idempotency key
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderReplayed is set in response replayed from Store
+	HeaderReplayed = "Idempotent-Replayed"
+
+	defaultTTL          = 24 * time.Hour
+	defaultLockTimeout  = time.Minute
+	defaultMaxBodyBytes = 4 << 20
+)
+
+// Interceptor would distinguish idempotency set based on.
+var (
+	optionsMap     = make(map[string]*optionSet)
+	defaultSkipper = func(*gin.Context) bool {
+		return false
+	}
+	defaultMethods = []string{http.MethodPost, http.MethodPatch}
+)
+
+// BootConfig for YAML
+type BootConfig struct {
+	Enabled       bool     `yaml:"enabled" json:"enabled"`
+	Ignore        []string `yaml:"ignore" json:"ignore"`
+	Header        string   `yaml:"header" json:"header"`
+	Methods       []string `yaml:"methods" json:"methods"`
+	Required      bool     `yaml:"required" json:"required"`
+	TtlMs         int      `yaml:"ttlMs" json:"ttlMs"`
+	LockTimeoutMs int      `yaml:"lockTimeoutMs" json:"lockTimeoutMs"`
+	MaxBodyBytes  int64    `yaml:"maxBodyBytes" json:"maxBodyBytes"`
+}
+
+// ToOptions convert BootConfig into Option list
+func ToOptions(config *BootConfig, entryName, entryType string) []Option {
+	opts := make([]Option, 0)
+
+	if config.Enabled {
+		opts = append(opts,
+			WithEntryNameAndType(entryName, entryType),
+			WithHeader(config.Header),
+			WithMethods(config.Methods...),
+			WithRequired(config.Required),
+			WithTTL(time.Duration(config.TtlMs)*time.Millisecond),
+			WithLockTimeout(time.Duration(config.LockTimeoutMs)*time.Millisecond),
+			WithMaxBodyBytes(config.MaxBodyBytes),
+			WithPathToIgnore(config.Ignore...))
+	}
+
+	return opts
+}
+
+// Create new optionSet with options.
+func newOptionSet(opts ...Option) *optionSet {
+	set := &optionSet{
+		EntryName:    xid.New().String(),
+		EntryType:    "",
+		Skipper:      defaultSkipper,
+		Header:       HeaderIdempotencyKey,
+		TTL:          defaultTTL,
+		LockTimeout:  defaultLockTimeout,
+		MaxBodyBytes: defaultMaxBodyBytes,
+		methods:      make(map[string]bool),
+		ignorePrefix: make([]string, 0),
+	}
+
+	for i := range opts {
+		opts[i](set)
+	}
+
+	if len(set.methods) < 1 {
+		for i := range defaultMethods {
+			set.methods[defaultMethods[i]] = true
+		}
+	}
+
+	if set.store == nil {
+		set.store = NewMemoryStore()
+	}
+
+	if _, ok := optionsMap[set.EntryName]; !ok {
+		optionsMap[set.EntryName] = set
+	}
+
+	return set
+}
+
+// Options which is used while initializing extension interceptor
+type optionSet struct {
+	EntryName    string
+	EntryType    string
+	Skipper      Skipper
+	Header       string
+	Required     bool
+	TTL          time.Duration
+	LockTimeout  time.Duration
+	MaxBodyBytes int64
+	methods      map[string]bool
+	store        Store
+	ignorePrefix []string
+}
+
+// ShouldIgnore determine whether idempotency should be ignored based on path and method
+func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		if !set.methods[ctx.Request.Method] {
+			return true
+		}
+
+		for i := range set.ignorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, set.ignorePrefix[i]) {
+				return true
+			}
+		}
+
+		return rkmid.ShouldIgnoreGlobal(ctx.Request.URL.Path)
+	}
+
+	return false
+}
+
+// Option if for middleware options while creating middleware
+type Option func(*optionSet)
+
+// WithEntryNameAndType provide entry name and entry type.
+func WithEntryNameAndType(entryName, entryType string) Option {
+	return func(opt *optionSet) {
+		opt.EntryName = entryName
+		opt.EntryType = entryType
+	}
+}
+
+// WithSkipper provide skipper.
+func WithSkipper(skip Skipper) Option {
+	return func(opt *optionSet) {
+		opt.Skipper = skip
+	}
+}
+
+// WithPathToIgnore provide path prefix to ignore middleware
+func WithPathToIgnore(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.ignorePrefix = append(opt.ignorePrefix, prefix...)
+	}
+}
+
+// WithHeader provide header of idempotency key, Idempotency-Key would be used if missing.
+func WithHeader(header string) Option {
+	return func(opt *optionSet) {
+		if len(header) > 0 {
+			opt.Header = header
+		}
+	}
+}
+
+// WithMethods provide HTTP methods which require idempotency, POST and PATCH would be used if missing.
+func WithMethods(methods ...string) Option {
+	return func(opt *optionSet) {
+		for i := range methods {
+			opt.methods[strings.ToUpper(methods[i])] = true
+		}
+	}
+}
+
+// WithRequired provide whether requests without idempotency key should be rejected with 400.
+func WithRequired(required bool) Option {
+	return func(opt *optionSet) {
+		opt.Required = required
+	}
+}
+
+// WithTTL provide duration of completed response kept in Store.
+func WithTTL(ttl time.Duration) Option {
+	return func(opt *optionSet) {
+		if ttl > 0 {
+			opt.TTL = ttl
+		}
+	}
+}
+
+// WithLockTimeout provide duration of in-flight reservation kept in Store,
+// so that key would be available again if instance crashed while processing.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(opt *optionSet) {
+		if timeout > 0 {
+			opt.LockTimeout = timeout
+		}
+	}
+}
+
+// WithMaxBodyBytes provide max size of request body read for fingerprint in bytes,
+// 4MB would be used if missing, limit of body limit middleware would be used instead if enabled.
+func WithMaxBodyBytes(maxBytes int64) Option {
+	return func(opt *optionSet) {
+		if maxBytes > 0 {
+			opt.MaxBodyBytes = maxBytes
+		}
+	}
+}
+
+// WithStore provide Store of records, in-memory store would be used if missing.
+func WithStore(store Store) Option {
+	return func(opt *optionSet) {
+		if store != nil {
+			opt.store = store
+		}
+	}
+}
+
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
@@ -0,0 +1,90 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginidempotency
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewOptionSet(t *testing.T) {
+	// without options
+	set := newOptionSet()
+	assert.NotEmpty(t, set.EntryName)
+	assert.Equal(t, HeaderIdempotencyKey, set.Header)
+	assert.Equal(t, defaultTTL, set.TTL)
+	assert.Equal(t, defaultLockTimeout, set.LockTimeout)
+	assert.Equal(t, int64(defaultMaxBodyBytes), set.MaxBodyBytes)
+	assert.True(t, set.methods[http.MethodPost])
+	assert.True(t, set.methods[http.MethodPatch])
+	assert.False(t, set.Required)
+	assert.NotNil(t, set.store)
+
+	// with options
+	store := NewMemoryStore()
+	set = newOptionSet(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithHeader("X-Request-Key"),
+		WithMethods("put"),
+		WithRequired(true),
+		WithTTL(time.Hour),
+		WithLockTimeout(time.Second),
+		WithMaxBodyBytes(1024),
+		WithStore(store),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}))
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "ut-type", set.EntryType)
+	assert.Equal(t, "X-Request-Key", set.Header)
+	assert.True(t, set.methods[http.MethodPut])
+	assert.False(t, set.methods[http.MethodPost])
+	assert.True(t, set.Required)
+	assert.Equal(t, time.Hour, set.TTL)
+	assert.Equal(t, time.Second, set.LockTimeout)
+	assert.Equal(t, int64(1024), set.MaxBodyBytes)
+	assert.Equal(t, store, set.store)
+	assert.True(t, set.Skipper(nil))
+}
+
+func TestOptionSet_ShouldIgnore(t *testing.T) {
+	set := newOptionSet(WithPathToIgnore("/ut-ignore"))
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+	assert.True(t, set.ShouldIgnore(ctx))
+
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/ut-ignore", nil)
+	assert.True(t, set.ShouldIgnore(ctx))
+
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/ut-path", nil)
+	assert.False(t, set.ShouldIgnore(ctx))
+}
+
+func TestToOptions(t *testing.T) {
+	config := &BootConfig{
+		Enabled:      false,
+		Header:       "X-Request-Key",
+		TtlMs:        1000,
+		MaxBodyBytes: 1024,
+	}
+
+	// with disabled
+	assert.Empty(t, ToOptions(config, "", ""))
+
+	// with enabled
+	config.Enabled = true
+	set := newOptionSet(ToOptions(config, "ut-entry", "ut-type")...)
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "X-Request-Key", set.Header)
+	assert.Equal(t, time.Second, set.TTL)
+	assert.Equal(t, int64(1024), set.MaxBodyBytes)
+	assert.True(t, set.methods[http.MethodPost])
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginidempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Store keeps records of idempotency keys, implementation should be safe for concurrent use among instances.
+type Store interface {
+	// Reserve key with in-flight record atomically.
+	// Existing record would be returned with false if key has been reserved or completed.
+	Reserve(ctx context.Context, key string, record *Record, ttl time.Duration) (*Record, bool, error)
+	// Complete key with response of first request.
+	Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	// Release key so that request could be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// Record of idempotency key.
+type Record struct {
+	Fingerprint string
+	Completed   bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// NewMemoryStore create in-process Store, expired records are swept periodically.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		records: make(map[string]*memoryRecord),
+		now:     time.Now,
+	}
+}
+
+// Record with expiration.
+type memoryRecord struct {
+	record  *Record
+	expires time.Time
+}
+
+// memoryStore keeps records in memory.
+type memoryStore struct {
+	records   map[string]*memoryRecord
+	lastSweep time.Time
+	now       func() time.Time
+	lock      sync.Mutex
+}
+
+// Reserve implements Store.
+func (s *memoryStore) Reserve(ctx context.Context, key string, record *Record, ttl time.Duration) (*Record, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if existing, ok := s.records[key]; ok && now.Before(existing.expires) {
+		return existing.record, false, nil
+	}
+
+	s.records[key] = &memoryRecord{record: record, expires: now.Add(ttl)}
+	return nil, true, nil
+}
+
+// Complete implements Store.
+func (s *memoryStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.records[key] = &memoryRecord{record: record, expires: s.now().Add(ttl)}
+	return nil
+}
+
+// Release implements Store.
+func (s *memoryStore) Release(ctx context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// Remove expired records at most once per minute.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for k, v := range s.records {
+		if !now.Before(v.expires) {
+			delete(s.records, k)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginidempotency
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(100, 0)
+	store := NewMemoryStore().(*memoryStore)
+	store.now = func() time.Time { return now }
+
+	// reserve
+	existing, ok, err := store.Reserve(context.TODO(), "ut-key", &Record{Fingerprint: "ut-fp"}, time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Nil(t, existing)
+
+	// reserved
+	existing, ok, _ = store.Reserve(context.TODO(), "ut-key", &Record{Fingerprint: "ut-other"}, time.Second)
+	assert.False(t, ok)
+	assert.Equal(t, "ut-fp", existing.Fingerprint)
+	assert.False(t, existing.Completed)
+
+	// complete
+	assert.Nil(t, store.Complete(context.TODO(), "ut-key", &Record{Fingerprint: "ut-fp", Completed: true}, time.Minute))
+	existing, ok, _ = store.Reserve(context.TODO(), "ut-key", &Record{}, time.Second)
+	assert.False(t, ok)
+	assert.True(t, existing.Completed)
+
+	// release
+	assert.Nil(t, store.Release(context.TODO(), "ut-key"))
+	_, ok, _ = store.Reserve(context.TODO(), "ut-key", &Record{}, time.Second)
+	assert.True(t, ok)
+
+	// expired records are swept
+	now = now.Add(2 * time.Minute)
+	_, ok, _ = store.Reserve(context.TODO(), "ut-other", &Record{}, time.Second)
+	assert.True(t, ok)
+	assert.Len(t, store.records, 1)
+}
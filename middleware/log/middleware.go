@@ -11,6 +11,7 @@ import (
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rookie-ninja/rk-entry/v2/middleware/log"
 	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"go.uber.org/zap"
 	"strconv"
 )
 
@@ -28,6 +29,19 @@ func Middleware(opts ...rkmidlog.Option) gin.HandlerFunc {
 		ctx.Set(rkmid.EventKey.String(), beforeCtx.Output.Event)
 		ctx.Set(rkmid.LoggerKey.String(), beforeCtx.Output.Logger)
 
+		// record identity of peer if client certificate verified
+		if cert := rkginctx.GetPeerCertificate(ctx); cert != nil && beforeCtx.Output.Event != nil {
+			sans := append([]string{}, cert.DNSNames...)
+			for i := range cert.URIs {
+				sans = append(sans, cert.URIs[i].String())
+			}
+
+			beforeCtx.Output.Event.AddPayloads(
+				zap.String("peerSubject", cert.Subject.String()),
+				zap.Strings("peerSANs", sans),
+				zap.String("peerSpiffeId", rkginctx.GetPeerSpiffeId(ctx)))
+		}
+
 		// call next
 		ctx.Next()
 
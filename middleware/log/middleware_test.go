@@ -6,6 +6,9 @@
 package rkginlog
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"github.com/gin-gonic/gin"
 	"github.com/rookie-ninja/rk-entry/v2/entry"
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
@@ -15,6 +18,7 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
 )
@@ -50,6 +54,41 @@ func TestInterceptor(t *testing.T) {
 	assert.Equal(t, http.StatusOK, ctx.Writer.Status())
 }
 
+func TestInterceptor_WithPeerCertificate(t *testing.T) {
+	defer assertNotPanic(t)
+
+	beforeCtx := rkmidlog.NewBeforeCtx()
+	afterCtx := rkmidlog.NewAfterCtx()
+	mock := rkmidlog.NewOptionSetMock(beforeCtx, afterCtx)
+	inter := Middleware(rkmidlog.WithMockOptionSet(mock))
+	ctx := newCtx()
+
+	spiffeId, _ := url.Parse("spiffe://ut-domain/ut-service")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "ut-service"},
+		DNSNames: []string{"ut-service"},
+		URIs:     []*url.URL{spiffeId},
+	}
+	ctx.Request.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+
+	event := rkquery.NewEventFactory().CreateEvent()
+	beforeCtx.Output.Event = event
+	beforeCtx.Output.Logger = rkentry.LoggerEntryNoop.Logger
+
+	inter(ctx)
+
+	payloads := map[string]zap.Field{}
+	for _, f := range event.ListPayloads() {
+		payloads[f.Key] = f
+	}
+	assert.Equal(t, "CN=ut-service", payloads["peerSubject"].String)
+	assert.Equal(t, "spiffe://ut-domain/ut-service", payloads["peerSpiffeId"].String)
+	assert.Contains(t, payloads, "peerSANs")
+}
+
 func assertNotPanic(t *testing.T) {
 	if r := recover(); r != nil {
 		// Expect panic to be called with non nil error
@@ -10,6 +10,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// HeaderLimit is quota of period
+	HeaderLimit = "RateLimit-Limit"
+	// HeaderRemaining is requests left in quota
+	HeaderRemaining = "RateLimit-Remaining"
+	// HeaderReset is seconds until quota restored
+	HeaderReset = "RateLimit-Reset"
 )
 
 // Middleware Add rate limit interceptors.
@@ -30,3 +45,50 @@ func Middleware(opts ...rkmidlimit.Option) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// StoreMiddleware Add rate limit interceptors which keep counters in Store, so limits could be shared among instances.
+//
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset would be set in response,
+// requests would be allowed if Store is not available.
+func StoreMiddleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) {
+			ctx.Next()
+			return
+		}
+
+		key, limit := set.getKeyAndLimit(ctx)
+
+		res := &Result{Limit: limit, RetryAfter: set.Period, Reset: set.Period}
+		if limit > 0 {
+			var err error
+			if res, err = set.store.Take(ctx.Request.Context(), key, limit, set.Period); err != nil {
+				rkginctx.GetLogger(ctx).Warn("Failed to take quota from rate limit store, request allowed", zap.Error(err))
+				ctx.Next()
+				return
+			}
+		}
+
+		ctx.Header(HeaderLimit, strconv.Itoa(res.Limit))
+		ctx.Header(HeaderRemaining, strconv.Itoa(res.Remaining))
+		ctx.Header(HeaderReset, seconds(res.Reset))
+
+		if !res.Allowed {
+			ctx.Header("Retry-After", seconds(res.RetryAfter))
+			resp := rkmid.GetErrorBuilder().New(http.StatusTooManyRequests, "Rate limit exceeded")
+			ctx.AbortWithStatusJSON(resp.Code(), resp)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Round duration up to seconds.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -1,7 +1,9 @@
 package rkginlimit
 
 import (
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	rkmid "github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
 	"github.com/stretchr/testify/assert"
@@ -36,6 +38,71 @@ func TestInterceptor(t *testing.T) {
 	assert.False(t, ctx.IsAborted())
 }
 
+func TestStoreMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(StoreMiddleware(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithStore(NewMemoryStore(GCRA)),
+		WithReqPerSec(1),
+		WithReqPerSecByPath("/ut-zero", 0),
+		WithKeyBy(KeyByIP),
+		WithPathToIgnore("/ut-ignore")))
+	router.GET("/ut-path", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	serve := func(path, ip string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":80"
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// allowed
+	w := serve("/ut-path", "1.1.1.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(HeaderLimit))
+	assert.Equal(t, "0", w.Header().Get(HeaderRemaining))
+	assert.Equal(t, "1", w.Header().Get(HeaderReset))
+
+	// limited
+	w = serve("/ut-path", "1.1.1.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Rate limit exceeded")
+
+	// other clients are not affected
+	assert.Equal(t, http.StatusOK, serve("/ut-path", "2.2.2.2").Code)
+
+	// with zero limit
+	assert.Equal(t, http.StatusTooManyRequests, serve("/ut-zero", "3.3.3.3").Code)
+
+	// with ignore
+	w = serve("/ut-ignore", "1.1.1.1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderLimit))
+}
+
+func TestStoreMiddleware_WithUnavailableStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	server.Close()
+
+	router := gin.New()
+	router.Use(StoreMiddleware(WithStore(NewRedisStore(client, SlidingWindow, "")), WithReqPerSec(1)))
+	router.GET("/ut-path", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	// requests are allowed
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ut-path", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderLimit))
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.ReleaseMode)
 	os.Exit(m.Run())
@@ -0,0 +1,527 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginlimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"github.com/rs/xid"
+	"strings"
+	"time"
+)
+
+const (
+	// StoreMemory keeps counters in process
+	StoreMemory = "memory"
+	// StoreRedis keeps counters in redis, shared among instances
+	StoreRedis = "redis"
+
+	// KeyByIP limit requests of each client IP
+	KeyByIP = "ip"
+	// KeyByRoute limit requests of each route
+	KeyByRoute = "route"
+	// KeyByHeaderPrefix limit requests of each value of header, e.g. header:X-API-Key,
+	// values are hashed in keys of Store since they may be secrets like API keys
+	KeyByHeaderPrefix = "header:"
+	// KeyByJwtPrefix limit requests of each value of jwt claim, e.g. jwt:sub
+	KeyByJwtPrefix = "jwt:"
+	// KeyByAuth limit requests of each basic auth user or principal of API key authenticated by auth middleware,
+	// principal of API key is a prefix of its SHA-256, see rkginauth.ApiKeyPrincipal
+	KeyByAuth = "auth"
+
+	defaultRedisPrefix = "rk:ratelimit:"
+	defaultPeriod      = time.Second
+
+	// key of limit shared by paths without dedicated limit
+	globalPath = "*"
+	// prefix of key of requests without identity
+	anonymousPrefix = "anonymous:"
+	// length of hex encoded SHA-256 of identity in key, the same as principal of API key
+	hashedIdentityLen = 16
+)
+
+// Interceptor would distinguish rate limit set based on.
+var (
+	optionsMap     = make(map[string]*optionSet)
+	defaultSkipper = func(*gin.Context) bool {
+		return false
+	}
+)
+
+// BootConfig for YAML, superset of rkmidlimit.BootConfig.
+//
+// leakyBucket would be limited in process with rkmidlimit, slidingWindow and gcra would be limited with Store.
+// Identities, tiers and redis store are supported by slidingWindow and gcra only, see UnsupportedKeys.
+//
+// Identities extracted with keyBy could be assigned to tiers with their own limit, either listed in keys of tier,
+// or extracted as tier name with tierBy. Requests without identity are limited with anonymousReqPerSec per client IP.
+type BootConfig struct {
+	Enabled   bool     `yaml:"enabled" json:"enabled"`
+	Ignore    []string `yaml:"ignore" json:"ignore"`
+	Algorithm string   `yaml:"algorithm" json:"algorithm"`
+	ReqPerSec *int     `yaml:"reqPerSec" json:"reqPerSec"`
+	Paths     []struct {
+		Path      string `yaml:"path" json:"path"`
+		ReqPerSec int    `yaml:"reqPerSec" json:"reqPerSec"`
+	} `yaml:"paths" json:"paths"`
+	KeyBy              string `yaml:"keyBy" json:"keyBy"`
+	AnonymousReqPerSec *int   `yaml:"anonymousReqPerSec" json:"anonymousReqPerSec"`
+	TierBy             string `yaml:"tierBy" json:"tierBy"`
+	Tiers              []struct {
+		Name      string   `yaml:"name" json:"name"`
+		ReqPerSec int      `yaml:"reqPerSec" json:"reqPerSec"`
+		Keys      []string `yaml:"keys" json:"keys" secret:"true"`
+	} `yaml:"tiers" json:"tiers"`
+	Store struct {
+		Type  string    `yaml:"type" json:"type"`
+		Redis BootRedis `yaml:"redis" json:"redis"`
+	} `yaml:"store" json:"store"`
+}
+
+// BootRedis is redis store of BootConfig.
+//
+// Password is excluded from JSON, its hash is included instead, so that change of password could be detected.
+type BootRedis struct {
+	Addrs        []string `yaml:"addrs" json:"addrs"`
+	Username     string   `yaml:"username" json:"username"`
+	Password     string   `yaml:"password" json:"-" secret:"true"`
+	PasswordHash string   `yaml:"-" json:"passwordHash,omitempty" secret:"true"`
+	DB           int      `yaml:"db" json:"db"`
+	Prefix       string   `yaml:"prefix" json:"prefix"`
+}
+
+// MarshalJSON marshal config with hash of password.
+func (config BootRedis) MarshalJSON() ([]byte, error) {
+	type alias BootRedis
+	config.PasswordHash = ""
+	if len(config.Password) > 0 {
+		sum := sha256.Sum256([]byte(config.Password))
+		config.PasswordHash = hex.EncodeToString(sum[:])
+	}
+
+	return json.Marshal(alias(config))
+}
+
+// UseStore returns true if algorithm should be limited with Store.
+func (config *BootConfig) UseStore() bool {
+	return config.Algorithm == SlidingWindow || config.Algorithm == GCRA
+}
+
+// UnsupportedKeys returns keys of config which are set but not supported by algorithm,
+// since identities, tiers and redis store are supported only by algorithms limited with Store.
+func (config *BootConfig) UnsupportedKeys() []string {
+	res := make([]string, 0)
+	if config.UseStore() {
+		return res
+	}
+
+	if len(config.KeyBy) > 0 {
+		res = append(res, "keyBy")
+	}
+	if config.AnonymousReqPerSec != nil {
+		res = append(res, "anonymousReqPerSec")
+	}
+	if len(config.TierBy) > 0 {
+		res = append(res, "tierBy")
+	}
+	if len(config.Tiers) > 0 {
+		res = append(res, "tiers")
+	}
+	if config.Store.Type == StoreRedis {
+		res = append(res, "store.type")
+	}
+
+	return res
+}
+
+// ToEntryConfig convert BootConfig into rkmidlimit.BootConfig for in-process limiter.
+func (config *BootConfig) ToEntryConfig() *rkmidlimit.BootConfig {
+	return &rkmidlimit.BootConfig{
+		Enabled:   config.Enabled,
+		Ignore:    config.Ignore,
+		Algorithm: config.Algorithm,
+		ReqPerSec: config.ReqPerSec,
+		Paths:     config.Paths,
+	}
+}
+
+// ToOptions convert BootConfig into Option list.
+//
+// Client of redis store would be shared with other middleware of the same redis config,
+// and should be released with Release once middleware built with options is no longer used.
+func ToOptions(config *BootConfig, entryName, entryType string) []Option {
+	opts := make([]Option, 0)
+
+	if config.Enabled {
+		var store Store
+		switch config.Store.Type {
+		case StoreRedis:
+			prefix := config.Store.Redis.Prefix
+			if len(prefix) < 1 {
+				prefix = defaultRedisPrefix
+			}
+
+			store = NewRedisStore(acquireRedisClient(&config.Store.Redis), config.Algorithm, prefix)
+		default:
+			store = NewMemoryStore(config.Algorithm)
+		}
+
+		opts = append(opts,
+			WithEntryNameAndType(entryName, entryType),
+			WithStore(store),
+			WithKeyBy(config.KeyBy),
+			WithPathToIgnore(config.Ignore...))
+
+		if config.ReqPerSec != nil {
+			opts = append(opts, WithReqPerSec(*config.ReqPerSec))
+		}
+
+		for i := range config.Paths {
+			opts = append(opts, WithReqPerSecByPath(config.Paths[i].Path, config.Paths[i].ReqPerSec))
+		}
+
+		if config.AnonymousReqPerSec != nil {
+			opts = append(opts, WithAnonymousReqPerSec(*config.AnonymousReqPerSec))
+		}
+
+		opts = append(opts, WithTierBy(config.TierBy))
+		for i := range config.Tiers {
+			tier := config.Tiers[i]
+			opts = append(opts, WithTier(tier.Name, tier.ReqPerSec, tier.Keys...))
+		}
+	}
+
+	return opts
+}
+
+// Release resources acquired by ToOptions with the same config, like client of redis store,
+// client would be closed once no middleware uses it.
+func Release(config *BootConfig) {
+	if config.Enabled && config.Store.Type == StoreRedis {
+		releaseRedisClient(&config.Store.Redis)
+	}
+}
+
+// Create new optionSet with options.
+func newOptionSet(opts ...Option) *optionSet {
+	set := &optionSet{
+		EntryName:       xid.New().String(),
+		EntryType:       "",
+		Skipper:         defaultSkipper,
+		ReqPerSec:       rkmidlimit.DefaultLimit,
+		Period:          defaultPeriod,
+		reqPerSecByPath: make(map[string]int),
+		tiers:           make(map[string]int),
+		tierByKey:       make(map[string]string),
+		ignorePrefix:    make([]string, 0),
+	}
+
+	for i := range opts {
+		opts[i](set)
+	}
+
+	if set.store == nil {
+		set.store = NewMemoryStore(SlidingWindow)
+	}
+
+	if _, ok := optionsMap[set.EntryName]; !ok {
+		optionsMap[set.EntryName] = set
+	}
+
+	return set
+}
+
+// Options which is used while initializing extension interceptor
+type optionSet struct {
+	EntryName       string
+	EntryType       string
+	Skipper         Skipper
+	ReqPerSec       int
+	Period          time.Duration
+	reqPerSecByPath map[string]int
+	anonymous       *int
+	tiers           map[string]int
+	tierByKey       map[string]string
+	tierFunc        KeyFunc
+	store           Store
+	keyFunc         KeyFunc
+	hashIdentity    bool
+	ignorePrefix    []string
+}
+
+// ShouldIgnore determine whether rate limit should be ignored based on path
+func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		for i := range set.ignorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, set.ignorePrefix[i]) {
+				return true
+			}
+		}
+
+		return rkmid.ShouldIgnoreGlobal(ctx.Request.URL.Path)
+	}
+
+	return false
+}
+
+// Get limit of request, route has higher priority than URL path.
+// Paths without dedicated limit share the global one.
+func (set *optionSet) getLimit(ctx *gin.Context) (string, int) {
+	if limit, ok := set.reqPerSecByPath[ctx.FullPath()]; ok {
+		return ctx.FullPath(), limit
+	}
+
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		if limit, ok := set.reqPerSecByPath[ctx.Request.URL.Path]; ok {
+			return ctx.Request.URL.Path, limit
+		}
+	}
+
+	return globalPath, set.ReqPerSec
+}
+
+// Get key of counter in Store and limit of request.
+//
+// Key consists of entry name, path of limit and identity of client. Limit of tier or anonymous requests
+// would be used for paths without dedicated limit.
+func (set *optionSet) getKeyAndLimit(ctx *gin.Context) (string, int) {
+	path, limit := set.getLimit(ctx)
+
+	// all clients share the same limit
+	if set.keyFunc == nil {
+		return fmt.Sprintf("%s:%s:", set.EntryName, path), limit
+	}
+
+	identity := set.keyFunc(ctx)
+	if len(identity) < 1 {
+		if set.anonymous != nil && path == globalPath {
+			limit = *set.anonymous
+		}
+
+		return fmt.Sprintf("%s:%s:%s%s", set.EntryName, path, anonymousPrefix, ctx.ClientIP()), limit
+	}
+
+	if path == globalPath {
+		if tierLimit, ok := set.getTierLimit(ctx, identity); ok {
+			limit = tierLimit
+		}
+	}
+
+	if set.hashIdentity {
+		sum := sha256.Sum256([]byte(identity))
+		identity = hex.EncodeToString(sum[:])[:hashedIdentityLen]
+	}
+
+	return fmt.Sprintf("%s:%s:%s", set.EntryName, path, identity), limit
+}
+
+// Get limit of tier which identity belongs to.
+func (set *optionSet) getTierLimit(ctx *gin.Context, identity string) (int, bool) {
+	tier, ok := set.tierByKey[identity]
+	if set.tierFunc != nil {
+		if name := set.tierFunc(ctx); len(name) > 0 {
+			tier, ok = name, true
+		}
+	}
+
+	if !ok {
+		return 0, false
+	}
+
+	limit, ok := set.tiers[tier]
+	return limit, ok
+}
+
+// Option if for middleware options while creating middleware
+type Option func(*optionSet)
+
+// WithEntryNameAndType provide entry name and entry type.
+func WithEntryNameAndType(entryName, entryType string) Option {
+	return func(opt *optionSet) {
+		opt.EntryName = entryName
+		opt.EntryType = entryType
+	}
+}
+
+// WithSkipper provide skipper.
+func WithSkipper(skip Skipper) Option {
+	return func(opt *optionSet) {
+		opt.Skipper = skip
+	}
+}
+
+// WithPathToIgnore provide path prefix to ignore middleware
+func WithPathToIgnore(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.ignorePrefix = append(opt.ignorePrefix, prefix...)
+	}
+}
+
+// WithReqPerSec provide requests per second allowed, zero or negative value would reject all requests.
+func WithReqPerSec(reqPerSec int) Option {
+	return func(opt *optionSet) {
+		if reqPerSec < 0 {
+			reqPerSec = 0
+		}
+		opt.ReqPerSec = reqPerSec
+	}
+}
+
+// WithReqPerSecByPath provide requests per second allowed of route or URL path.
+func WithReqPerSecByPath(path string, reqPerSec int) Option {
+	return func(opt *optionSet) {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		if reqPerSec < 0 {
+			reqPerSec = 0
+		}
+		opt.reqPerSecByPath[path] = reqPerSec
+	}
+}
+
+// WithStore provide Store of counters, in-memory sliding window would be used if missing.
+func WithStore(store Store) Option {
+	return func(opt *optionSet) {
+		if store != nil {
+			opt.store = store
+		}
+	}
+}
+
+// WithKeyFunc provide KeyFunc which extracts identity of client, all clients share the same limit if missing.
+//
+// Requests with empty identity are treated as anonymous.
+func WithKeyFunc(f KeyFunc) Option {
+	return func(opt *optionSet) {
+		opt.keyFunc = f
+		opt.hashIdentity = false
+	}
+}
+
+// WithKeyBy provide KeyFunc with expression, options: ip, route, auth, header:<name>, jwt:<claim>.
+//
+// Requests without header, claim or authenticated principal are treated as anonymous.
+// Values of header are hashed in keys of Store.
+func WithKeyBy(keyBy string) Option {
+	return func(opt *optionSet) {
+		if f := parseKeyFunc(keyBy); f != nil {
+			opt.keyFunc = f
+			opt.hashIdentity = strings.HasPrefix(keyBy, KeyByHeaderPrefix)
+		}
+	}
+}
+
+// WithAnonymousReqPerSec provide requests per second allowed of each client IP without identity.
+//
+// ReqPerSec would be used if missing.
+func WithAnonymousReqPerSec(reqPerSec int) Option {
+	return func(opt *optionSet) {
+		if reqPerSec < 0 {
+			reqPerSec = 0
+		}
+		opt.anonymous = &reqPerSec
+	}
+}
+
+// WithTier provide tier with requests per second allowed of each identity belongs to it.
+//
+// Identities are values extracted with keyBy, which are principals like apikey:ca084a11a659646b with KeyByAuth.
+func WithTier(name string, reqPerSec int, identities ...string) Option {
+	return func(opt *optionSet) {
+		if len(name) < 1 {
+			return
+		}
+		if reqPerSec < 0 {
+			reqPerSec = 0
+		}
+
+		opt.tiers[name] = reqPerSec
+		for i := range identities {
+			opt.tierByKey[identities[i]] = name
+		}
+	}
+}
+
+// WithTierBy provide expression to extract tier name of request, options are the same as WithKeyBy.
+//
+// Tier extracted has higher priority than identities listed in WithTier.
+func WithTierBy(tierBy string) Option {
+	return func(opt *optionSet) {
+		if f := parseKeyFunc(tierBy); f != nil {
+			opt.tierFunc = f
+		}
+	}
+}
+
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
+
+// KeyFunc returns identity of client which shares the same limit
+type KeyFunc func(*gin.Context) string
+
+// IsValidKeyBy returns true if expression of keyBy or tierBy is one of ip, route, auth, header:<name> and jwt:<claim>,
+// empty expression is valid since client IP would be used.
+func IsValidKeyBy(expr string) bool {
+	return len(expr) < 1 || parseKeyFunc(expr) != nil
+}
+
+// Parse expression of KeyFunc, nil would be returned if expression is invalid.
+func parseKeyFunc(expr string) KeyFunc {
+	switch {
+	case expr == KeyByIP:
+		return keyByIP
+	case expr == KeyByRoute:
+		return keyByRoute
+	case expr == KeyByAuth:
+		return rkginctx.GetAuthPrincipal
+	case strings.HasPrefix(expr, KeyByHeaderPrefix) && len(expr) > len(KeyByHeaderPrefix):
+		return keyByHeader(strings.TrimPrefix(expr, KeyByHeaderPrefix))
+	case strings.HasPrefix(expr, KeyByJwtPrefix) && len(expr) > len(KeyByJwtPrefix):
+		return keyByJwtClaim(strings.TrimPrefix(expr, KeyByJwtPrefix))
+	}
+
+	return nil
+}
+
+func keyByIP(ctx *gin.Context) string {
+	return ctx.ClientIP()
+}
+
+func keyByRoute(ctx *gin.Context) string {
+	if path := ctx.FullPath(); len(path) > 0 {
+		return path
+	}
+
+	return globalPath
+}
+
+func keyByHeader(name string) KeyFunc {
+	return func(ctx *gin.Context) string {
+		return ctx.GetHeader(name)
+	}
+}
+
+func keyByJwtClaim(claim string) KeyFunc {
+	return func(ctx *gin.Context) string {
+		if token := rkginctx.GetJwtToken(ctx); token != nil {
+			if claims, ok := token.Claims.(jwt.MapClaims); ok {
+				if v, ok := claims[claim]; ok && v != nil {
+					return fmt.Sprintf("%v", v)
+				}
+			}
+		}
+
+		return ""
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginlimit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-entry/v2/middleware/ratelimit"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/auth"
+	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewOptionSet(t *testing.T) {
+	// without options
+	set := newOptionSet()
+	assert.NotEmpty(t, set.EntryName)
+	assert.Equal(t, rkmidlimit.DefaultLimit, set.ReqPerSec)
+	assert.Equal(t, defaultPeriod, set.Period)
+	assert.NotNil(t, set.store)
+	assert.Nil(t, set.keyFunc)
+
+	// with options
+	store := NewMemoryStore(GCRA)
+	set = newOptionSet(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithReqPerSec(-1),
+		WithReqPerSecByPath("v1/user", 10),
+		WithStore(store),
+		WithKeyBy(KeyByIP),
+		WithPathToIgnore("/ut-ignore"),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}))
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "ut-type", set.EntryType)
+	assert.Equal(t, 0, set.ReqPerSec)
+	assert.Equal(t, 10, set.reqPerSecByPath["/v1/user"])
+	assert.Equal(t, store, set.store)
+	assert.NotNil(t, set.keyFunc)
+	assert.True(t, set.Skipper(nil))
+}
+
+func TestOptionSet_GetLimit(t *testing.T) {
+	set := newOptionSet(WithReqPerSec(1), WithReqPerSecByPath("/v1/user/:id", 2), WithReqPerSecByPath("/v1/order", 3))
+
+	router := gin.New()
+	router.GET("/v1/user/:id", func(ctx *gin.Context) {
+		path, limit := set.getLimit(ctx)
+		assert.Equal(t, "/v1/user/:id", path)
+		assert.Equal(t, 2, limit)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/user/1", nil))
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/order", nil)
+	path, limit := set.getLimit(ctx)
+	assert.Equal(t, "/v1/order", path)
+	assert.Equal(t, 3, limit)
+
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/other", nil)
+	path, limit = set.getLimit(ctx)
+	assert.Equal(t, globalPath, path)
+	assert.Equal(t, 1, limit)
+}
+
+func TestWithKeyBy(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/user", nil)
+	ctx.Request.RemoteAddr = "1.1.1.1:80"
+
+	// without key
+	set := newOptionSet(WithEntryNameAndType("ut-entry", ""), WithKeyBy("unknown"))
+	key, _ := set.getKeyAndLimit(ctx)
+	assert.Equal(t, "ut-entry:*:", key)
+
+	// with ip
+	set = newOptionSet(WithEntryNameAndType("ut-entry", ""), WithKeyBy(KeyByIP))
+	key, _ = set.getKeyAndLimit(ctx)
+	assert.Equal(t, "ut-entry:*:1.1.1.1", key)
+
+	// with route of unmatched request
+	set = newOptionSet(WithKeyBy(KeyByRoute))
+	assert.Equal(t, globalPath, set.keyFunc(ctx))
+
+	// with header
+	set = newOptionSet(WithKeyBy("header:X-API-Key"))
+	assert.Empty(t, set.keyFunc(ctx))
+	ctx.Request.Header.Set("X-API-Key", "ut-key")
+	assert.Equal(t, "ut-key", set.keyFunc(ctx))
+
+	// with jwt claim
+	set = newOptionSet(WithKeyBy("jwt:sub"))
+	assert.Empty(t, set.keyFunc(ctx))
+	ctx.Set(rkmid.JwtTokenKey.String(), jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "ut-user"}))
+	assert.Equal(t, "ut-user", set.keyFunc(ctx))
+
+	// with auth principal
+	set = newOptionSet(WithKeyBy(KeyByAuth))
+	assert.Empty(t, set.keyFunc(ctx))
+	ctx.Set(rkginctx.AuthPrincipalKey, "ut-principal")
+	assert.Equal(t, "ut-principal", set.keyFunc(ctx))
+}
+
+func TestIsValidKeyBy(t *testing.T) {
+	assert.True(t, IsValidKeyBy(""))
+	assert.True(t, IsValidKeyBy(KeyByIP))
+	assert.True(t, IsValidKeyBy("header:X-API-Key"))
+	assert.False(t, IsValidKeyBy("header:"))
+	assert.False(t, IsValidKeyBy("IP"))
+}
+
+func TestOptionSet_GetKeyAndLimit(t *testing.T) {
+	set := newOptionSet(
+		WithEntryNameAndType("ut-entry", ""),
+		WithReqPerSec(10),
+		WithReqPerSecByPath("/ut-login", 1),
+		WithKeyBy("jwt:sub"),
+		WithAnonymousReqPerSec(2),
+		WithTier("gold", 100, "ut-gold"),
+		WithTier("silver", 50))
+
+	newCtx := func(path string, claims jwt.MapClaims) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		ctx.Request.RemoteAddr = "1.1.1.1:80"
+		if claims != nil {
+			ctx.Set(rkmid.JwtTokenKey.String(), jwt.NewWithClaims(jwt.SigningMethodHS256, claims))
+		}
+		return ctx
+	}
+
+	// anonymous
+	key, limit := set.getKeyAndLimit(newCtx("/ut-path", nil))
+	assert.Equal(t, "ut-entry:*:anonymous:1.1.1.1", key)
+	assert.Equal(t, 2, limit)
+
+	// identity without tier
+	key, limit = set.getKeyAndLimit(newCtx("/ut-path", jwt.MapClaims{"sub": "ut-user"}))
+	assert.Equal(t, "ut-entry:*:ut-user", key)
+	assert.Equal(t, 10, limit)
+
+	// identity listed in tier
+	_, limit = set.getKeyAndLimit(newCtx("/ut-path", jwt.MapClaims{"sub": "ut-gold"}))
+	assert.Equal(t, 100, limit)
+
+	// dedicated limit of path has higher priority
+	key, limit = set.getKeyAndLimit(newCtx("/ut-login", jwt.MapClaims{"sub": "ut-gold"}))
+	assert.Equal(t, "ut-entry:/ut-login:ut-gold", key)
+	assert.Equal(t, 1, limit)
+
+	// tier extracted from claim
+	WithTierBy("jwt:plan")(set)
+	_, limit = set.getKeyAndLimit(newCtx("/ut-path", jwt.MapClaims{"sub": "ut-user", "plan": "silver"}))
+	assert.Equal(t, 50, limit)
+
+	// unknown tier
+	_, limit = set.getKeyAndLimit(newCtx("/ut-path", jwt.MapClaims{"sub": "ut-user", "plan": "unknown"}))
+	assert.Equal(t, 10, limit)
+}
+
+func TestOptionSet_GetKeyAndLimit_WithApiKey(t *testing.T) {
+	set := newOptionSet(
+		WithEntryNameAndType("ut-entry", ""),
+		WithReqPerSec(10),
+		WithKeyBy(KeyByAuth),
+		WithTier("gold", 100, rkginauth.ApiKeyPrincipal("ut-gold-key")))
+	auth := rkginauth.Middleware(rkmidauth.WithApiKeyAuth("ut-gold-key", "ut-key"))
+
+	newCtx := func(apiKey string) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+		ctx.Request.Header.Set(rkmid.HeaderApiKey, apiKey)
+		auth(ctx)
+		return ctx
+	}
+
+	// API key listed in tier with its principal, key itself is not part of limit key
+	key, limit := set.getKeyAndLimit(newCtx("ut-gold-key"))
+	assert.Equal(t, "ut-entry:*:"+rkginauth.ApiKeyPrincipal("ut-gold-key"), key)
+	assert.NotContains(t, key, "ut-gold-key")
+	assert.Equal(t, 100, limit)
+
+	// API key without tier
+	_, limit = set.getKeyAndLimit(newCtx("ut-key"))
+	assert.Equal(t, 10, limit)
+}
+
+func TestOptionSet_GetKeyAndLimit_WithHeader(t *testing.T) {
+	set := newOptionSet(
+		WithEntryNameAndType("ut-entry", ""),
+		WithReqPerSec(10),
+		WithKeyBy("header:X-API-Key"),
+		WithTier("gold", 100, "ut-gold-key"))
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+	ctx.Request.Header.Set("X-API-Key", "ut-gold-key")
+
+	// value of header is hashed in key, tier is matched with value itself
+	key, limit := set.getKeyAndLimit(ctx)
+	assert.Equal(t, "ut-entry:*:"+strings.TrimPrefix(rkginauth.ApiKeyPrincipal("ut-gold-key"), "apikey:"), key)
+	assert.NotContains(t, key, "ut-gold-key")
+	assert.Equal(t, 100, limit)
+
+	// with KeyFunc, identity is kept
+	WithKeyFunc(func(ctx *gin.Context) string {
+		return ctx.GetHeader("X-API-Key")
+	})(set)
+	key, _ = set.getKeyAndLimit(ctx)
+	assert.Equal(t, "ut-entry:*:ut-gold-key", key)
+}
+
+func TestBootRedis_MarshalJSON(t *testing.T) {
+	config := BootRedis{Addrs: []string{"localhost:6379"}}
+	raw, err := json.Marshal(config)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(raw), "passwordHash")
+
+	// password is replaced with its hash
+	config.Password = "ut-pass"
+	withPass, err := json.Marshal(config)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(withPass), "ut-pass")
+	assert.Contains(t, string(withPass), "passwordHash")
+
+	// change of password is visible
+	config.Password = "ut-other"
+	withOther, err := json.Marshal(&config)
+	assert.Nil(t, err)
+	assert.NotEqual(t, string(withPass), string(withOther))
+	assert.Empty(t, config.PasswordHash)
+}
+
+func TestToOptions(t *testing.T) {
+	reqPerSec := 5
+	config := &BootConfig{
+		Enabled:   false,
+		Algorithm: GCRA,
+		ReqPerSec: &reqPerSec,
+		KeyBy:     KeyByAuth,
+		TierBy:    "header:X-Tier",
+	}
+	config.Tiers = append(config.Tiers, struct {
+		Name      string   `yaml:"name" json:"name"`
+		ReqPerSec int      `yaml:"reqPerSec" json:"reqPerSec"`
+		Keys      []string `yaml:"keys" json:"keys" secret:"true"`
+	}{Name: "gold", ReqPerSec: 100, Keys: []string{"ut-user"}})
+	anonymous := 1
+	config.AnonymousReqPerSec = &anonymous
+	config.Paths = append(config.Paths, struct {
+		Path      string `yaml:"path" json:"path"`
+		ReqPerSec int    `yaml:"reqPerSec" json:"reqPerSec"`
+	}{Path: "/v1/user", ReqPerSec: 1})
+
+	// with disabled
+	assert.Empty(t, ToOptions(config, "", ""))
+
+	// with memory store
+	config.Enabled = true
+	assert.True(t, config.UseStore())
+	assert.Empty(t, config.UnsupportedKeys())
+	set := newOptionSet(ToOptions(config, "ut-entry", "ut-type")...)
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, 5, set.ReqPerSec)
+	assert.Equal(t, 1, set.reqPerSecByPath["/v1/user"])
+	assert.IsType(t, &memoryStore{}, set.store)
+	assert.NotNil(t, set.keyFunc)
+	assert.NotNil(t, set.tierFunc)
+	assert.Equal(t, 1, *set.anonymous)
+	assert.Equal(t, 100, set.tiers["gold"])
+	assert.Equal(t, "gold", set.tierByKey["ut-user"])
+
+	// with redis store
+	config.Store.Type = StoreRedis
+	config.Store.Redis.Addrs = []string{"localhost:6379"}
+	set = newOptionSet(ToOptions(config, "ut-entry", "ut-type")...)
+	assert.Equal(t, defaultRedisPrefix, set.store.(*redisStore).prefix)
+
+	// client is closed once released
+	client := set.store.(*redisStore).client
+	Release(config)
+	assert.Equal(t, redis.ErrClosed, client.Ping(context.TODO()).Err())
+
+	// convert to rk-entry config
+	config.Algorithm = rkmidlimit.LeakyBucket
+	assert.False(t, config.UseStore())
+	assert.Equal(t, []string{"keyBy", "anonymousReqPerSec", "tierBy", "tiers", "store.type"}, config.UnsupportedKeys())
+	entryConfig := config.ToEntryConfig()
+	assert.Equal(t, &reqPerSec, entryConfig.ReqPerSec)
+	assert.Equal(t, "/v1/user", entryConfig.Paths[0].Path)
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginlimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redis clients created from BootConfig keyed by addresses, db and credentials, shared among reloads of config.
+var (
+	redisClients     = make(map[string]*sharedRedisClient)
+	redisClientsLock sync.Mutex
+)
+
+// sharedRedisClient is redis client with number of middleware using it.
+type sharedRedisClient struct {
+	client redis.UniversalClient
+	refs   int
+}
+
+// Counters of current and previous window are weighted, request would be counted only if allowed.
+// KEYS: current window, previous window
+// ARGV: limit, weight of previous window, ttl in milliseconds
+var slidingWindowScript = redis.NewScript(`
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+local count = prev * tonumber(ARGV[2]) + curr
+if count >= tonumber(ARGV[1]) then
+  return {0, string.format('%.6f', count)}
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {1, string.format('%.6f', count + 1)}
+`)
+
+// Theoretical arrival time is kept in milliseconds, request would be counted only if allowed.
+// KEYS: tat
+// ARGV: now, emission interval, period, all in milliseconds
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]) or ARGV[1])
+if tat < now then
+  tat = now
+end
+if tat + interval - period > now then
+  return {0, string.format('%.3f', tat - now)}
+end
+tat = tat + interval
+redis.call('SET', KEYS[1], string.format('%.3f', tat), 'PX', math.ceil(tat - now) + 1)
+return {1, string.format('%.3f', tat - now)}
+`)
+
+// Key of redis client of config, password is hashed.
+func redisClientKey(config *BootRedis) string {
+	sum := sha256.Sum256([]byte(config.Password))
+	return fmt.Sprintf("%s/%d/%s/%s",
+		strings.Join(config.Addrs, ","), config.DB, config.Username, hex.EncodeToString(sum[:]))
+}
+
+// Acquire redis client of config, client of the same addresses, db and credentials would be shared,
+// so that pools are not leaked while config is reloaded.
+//
+// Client should be released with releaseRedisClient once middleware using it is no longer used.
+func acquireRedisClient(config *BootRedis) redis.UniversalClient {
+	redisClientsLock.Lock()
+	defer redisClientsLock.Unlock()
+
+	key := redisClientKey(config)
+	shared, ok := redisClients[key]
+	if !ok {
+		shared = &sharedRedisClient{
+			client: redis.NewUniversalClient(&redis.UniversalOptions{
+				Addrs:    config.Addrs,
+				Username: config.Username,
+				Password: config.Password,
+				DB:       config.DB,
+			}),
+		}
+		redisClients[key] = shared
+	}
+	shared.refs++
+
+	return shared.client
+}
+
+// Release redis client of config acquired, client would be closed once no middleware uses it.
+func releaseRedisClient(config *BootRedis) {
+	redisClientsLock.Lock()
+	defer redisClientsLock.Unlock()
+
+	key := redisClientKey(config)
+	shared, ok := redisClients[key]
+	if !ok {
+		return
+	}
+
+	shared.refs--
+	if shared.refs < 1 {
+		delete(redisClients, key)
+		shared.client.Close()
+	}
+}
+
+// NewRedisStore create Store backed by redis with algorithm, slidingWindow would be used if algorithm is unknown.
+//
+// Keys would be prefixed with prefix and wrapped with hash tag, so that scripts work with redis cluster.
+func NewRedisStore(client redis.UniversalClient, algorithm, prefix string) Store {
+	return &redisStore{
+		client:    client,
+		algorithm: algorithm,
+		prefix:    prefix,
+		now:       time.Now,
+	}
+}
+
+// redisStore keeps counters in redis, evaluated with lua scripts atomically.
+type redisStore struct {
+	client    redis.UniversalClient
+	algorithm string
+	prefix    string
+	now       func() time.Time
+}
+
+// Take implements Store.
+func (s *redisStore) Take(ctx context.Context, key string, limit int, period time.Duration) (*Result, error) {
+	key = fmt.Sprintf("%s{%s}", s.prefix, key)
+	now := s.now()
+
+	if s.algorithm == GCRA {
+		return s.takeGCRA(ctx, now, key, limit, period)
+	}
+
+	return s.takeSlidingWindow(ctx, now, key, limit, period)
+}
+
+func (s *redisStore) takeSlidingWindow(ctx context.Context, now time.Time, key string, limit int, period time.Duration) (*Result, error) {
+	index := now.UnixNano() / int64(period)
+	elapsed := time.Duration(now.UnixNano() - index*int64(period))
+	weight := 1 - float64(elapsed)/float64(period)
+
+	keys := []string{
+		fmt.Sprintf("%s:%d", key, index),
+		fmt.Sprintf("%s:%d", key, index-1),
+	}
+
+	allowed, value, err := s.eval(ctx, slidingWindowScript, keys, limit, weight, (2 * period).Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return slidingWindowResult(allowed, value, limit, period-elapsed), nil
+}
+
+func (s *redisStore) takeGCRA(ctx context.Context, now time.Time, key string, limit int, period time.Duration) (*Result, error) {
+	periodMs := float64(period) / float64(time.Millisecond)
+	interval := periodMs / float64(limit)
+	nowMs := float64(now.UnixNano()) / float64(time.Millisecond)
+
+	allowed, value, err := s.eval(ctx, gcraScript, []string{key},
+		strconv.FormatFloat(nowMs, 'f', 3, 64),
+		strconv.FormatFloat(interval, 'f', 3, 64),
+		strconv.FormatFloat(periodMs, 'f', 3, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcraResult(allowed, time.Duration(value*float64(time.Millisecond)), limit, period), nil
+}
+
+// Run script and parse reply of {allowed, value}.
+func (s *redisStore) eval(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (bool, float64, error) {
+	raw, err := script.Run(ctx, s.client, keys, args...).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	if len(raw) != 2 {
+		return false, 0, fmt.Errorf("unexpected reply of rate limit script, %v", raw)
+	}
+
+	allowed, _ := raw[0].(int64)
+	str, _ := raw[1].(string)
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return allowed == 1, value, nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginlimit
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newRedisStore(t *testing.T, algorithm string, now *time.Time) (*redisStore, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	store := NewRedisStore(client, algorithm, "ut:").(*redisStore)
+	store.now = func() time.Time { return *now }
+	return store, server
+}
+
+func TestRedisStore_SlidingWindow(t *testing.T) {
+	now := time.Unix(100, 0)
+	store, server := newRedisStore(t, SlidingWindow, &now)
+
+	for i := 0; i < 2; i++ {
+		res, err := store.Take(context.TODO(), "ut-key", 2, time.Second)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 1-i, res.Remaining)
+	}
+	res, err := store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, server.Exists("ut:{ut-key}:100"))
+
+	// half of previous window is counted
+	now = now.Add(1500 * time.Millisecond)
+	res, _ = store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.True(t, res.Allowed)
+	res, _ = store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	// with unavailable server
+	server.Close()
+	_, err = store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.NotNil(t, err)
+}
+
+func TestRedisStore_GCRA(t *testing.T) {
+	now := time.Unix(100, 0)
+	store, server := newRedisStore(t, GCRA, &now)
+
+	for i := 0; i < 4; i++ {
+		res, err := store.Take(context.TODO(), "ut-key", 4, time.Second)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3-i, res.Remaining)
+	}
+	res, err := store.Take(context.TODO(), "ut-key", 4, time.Second)
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 250*time.Millisecond, res.RetryAfter)
+	assert.True(t, server.Exists("ut:{ut-key}"))
+
+	now = now.Add(250 * time.Millisecond)
+	res, _ = store.Take(context.TODO(), "ut-key", 4, time.Second)
+	assert.True(t, res.Allowed)
+}
+
+func TestRedisStore_Shared(t *testing.T) {
+	now := time.Unix(100, 0)
+	store, server := newRedisStore(t, GCRA, &now)
+
+	// another instance shares the same counters
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	other := NewRedisStore(client, GCRA, "ut:").(*redisStore)
+	other.now = store.now
+
+	res, _ := store.Take(context.TODO(), "ut-key", 1, time.Second)
+	assert.True(t, res.Allowed)
+	res, _ = other.Take(context.TODO(), "ut-key", 1, time.Second)
+	assert.False(t, res.Allowed)
+}
+
+func TestAcquireRedisClient(t *testing.T) {
+	server := miniredis.RunT(t)
+	config := &BootRedis{Addrs: []string{server.Addr()}}
+
+	// shared with the same config
+	client := acquireRedisClient(config)
+	assert.Same(t, client, acquireRedisClient(&BootRedis{Addrs: []string{server.Addr()}, Prefix: "ut:"}))
+	assert.Nil(t, client.Ping(context.TODO()).Err())
+
+	// different db
+	other := acquireRedisClient(&BootRedis{Addrs: []string{server.Addr()}, DB: 1})
+	assert.NotSame(t, client, other)
+	releaseRedisClient(&BootRedis{Addrs: []string{server.Addr()}, DB: 1})
+	assert.Equal(t, redis.ErrClosed, other.Ping(context.TODO()).Err())
+
+	// different credentials, previous client is kept while in use
+	changed := &BootRedis{Addrs: []string{server.Addr()}, Password: "ut-pass"}
+	assert.NotSame(t, client, acquireRedisClient(changed))
+	releaseRedisClient(changed)
+	assert.Nil(t, client.Ping(context.TODO()).Err())
+
+	// closed once released by all users
+	releaseRedisClient(config)
+	assert.Nil(t, client.Ping(context.TODO()).Err())
+	releaseRedisClient(config)
+	assert.Equal(t, redis.ErrClosed, client.Ping(context.TODO()).Err())
+
+	// released twice
+	releaseRedisClient(config)
+}
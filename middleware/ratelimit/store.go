@@ -0,0 +1,185 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginlimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// SlidingWindow approximates requests in last period with counters of current and previous window
+	SlidingWindow = "slidingWindow"
+	// GCRA generic cell rate algorithm, allows burst up to limit and spaces requests evenly
+	GCRA = "gcra"
+)
+
+// Store keeps rate limit counters which could be shared among instances.
+type Store interface {
+	// Take one request of key from quota which allows limit requests per period.
+	Take(ctx context.Context, key string, limit int, period time.Duration) (*Result, error)
+}
+
+// Result of Store.Take
+type Result struct {
+	// Allowed is true if request is within quota
+	Allowed bool
+	// Limit is quota of period
+	Limit int
+	// Remaining is requests left in quota
+	Remaining int
+	// Reset is duration until quota would be fully or partially restored
+	Reset time.Duration
+	// RetryAfter is duration to wait before next request would be allowed, zero if allowed
+	RetryAfter time.Duration
+}
+
+// Build result of sliding window with estimated count of requests in period.
+func slidingWindowResult(allowed bool, count float64, limit int, reset time.Duration) *Result {
+	res := &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(float64(limit)-count))),
+		Reset:     reset,
+	}
+
+	if !allowed {
+		res.Remaining = 0
+		res.RetryAfter = reset
+	}
+
+	return res
+}
+
+// Build result of GCRA with duration between now and theoretical arrival time.
+func gcraResult(allowed bool, diff time.Duration, limit int, period time.Duration) *Result {
+	interval := period / time.Duration(limit)
+
+	res := &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(float64(period-diff)/float64(interval)))),
+		Reset:     diff,
+	}
+
+	if !allowed {
+		res.Remaining = 0
+		res.RetryAfter = diff - (period - interval)
+	}
+
+	return res
+}
+
+// NewMemoryStore create in-process Store with algorithm, slidingWindow would be used if algorithm is unknown.
+func NewMemoryStore(algorithm string) Store {
+	return &memoryStore{
+		algorithm: algorithm,
+		windows:   make(map[string]*memoryWindow),
+		tats:      make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// Counters of sliding window.
+type memoryWindow struct {
+	index int64
+	prev  int
+	curr  int
+}
+
+// memoryStore keeps counters in memory, stale keys are swept periodically.
+type memoryStore struct {
+	algorithm string
+	windows   map[string]*memoryWindow
+	tats      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+	lock      sync.Mutex
+}
+
+// Take implements Store.
+func (s *memoryStore) Take(ctx context.Context, key string, limit int, period time.Duration) (*Result, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.sweep(now, period)
+
+	if s.algorithm == GCRA {
+		return s.takeGCRA(now, key, limit, period), nil
+	}
+
+	return s.takeSlidingWindow(now, key, limit, period), nil
+}
+
+func (s *memoryStore) takeSlidingWindow(now time.Time, key string, limit int, period time.Duration) *Result {
+	index := now.UnixNano() / int64(period)
+	elapsed := time.Duration(now.UnixNano() - index*int64(period))
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &memoryWindow{index: index}
+		s.windows[key] = w
+	}
+
+	// roll windows
+	switch {
+	case w.index == index-1:
+		w.prev, w.curr = w.curr, 0
+	case w.index < index-1:
+		w.prev, w.curr = 0, 0
+	}
+	w.index = index
+
+	count := float64(w.prev)*(1-float64(elapsed)/float64(period)) + float64(w.curr)
+	if count >= float64(limit) {
+		return slidingWindowResult(false, count, limit, period-elapsed)
+	}
+
+	w.curr++
+	return slidingWindowResult(true, count+1, limit, period-elapsed)
+}
+
+func (s *memoryStore) takeGCRA(now time.Time, key string, limit int, period time.Duration) *Result {
+	interval := period / time.Duration(limit)
+
+	tat, ok := s.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	if tat.Add(interval - period).After(now) {
+		return gcraResult(false, tat.Sub(now), limit, period)
+	}
+
+	tat = tat.Add(interval)
+	s.tats[key] = tat
+
+	return gcraResult(true, tat.Sub(now), limit, period)
+}
+
+// Remove keys not used in last two periods.
+func (s *memoryStore) sweep(now time.Time, period time.Duration) {
+	if now.Sub(s.lastSweep) < 2*period {
+		return
+	}
+	s.lastSweep = now
+
+	index := now.UnixNano() / int64(period)
+	for k, w := range s.windows {
+		if w.index < index-1 {
+			delete(s.windows, k)
+		}
+	}
+
+	for k, tat := range s.tats {
+		if tat.Before(now) {
+			delete(s.tats, k)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkginlimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	now := time.Unix(100, 0)
+	store := NewMemoryStore(SlidingWindow).(*memoryStore)
+	store.now = func() time.Time { return now }
+
+	// exhaust quota
+	for i := 0; i < 2; i++ {
+		res, err := store.Take(context.TODO(), "ut-key", 2, time.Second)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 1-i, res.Remaining)
+	}
+	res, _ := store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// other keys are not affected
+	res, _ = store.Take(context.TODO(), "ut-other", 2, time.Second)
+	assert.True(t, res.Allowed)
+
+	// half of previous window is counted
+	now = now.Add(1500 * time.Millisecond)
+	res, _ = store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 500*time.Millisecond, res.Reset)
+	res, _ = store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.False(t, res.Allowed)
+
+	// stale keys are swept
+	now = now.Add(3 * time.Second)
+	store.Take(context.TODO(), "ut-key", 2, time.Second)
+	assert.Len(t, store.windows, 1)
+}
+
+func TestMemoryStore_GCRA(t *testing.T) {
+	now := time.Unix(100, 0)
+	store := NewMemoryStore(GCRA).(*memoryStore)
+	store.now = func() time.Time { return now }
+
+	// burst up to limit
+	for i := 0; i < 4; i++ {
+		res, err := store.Take(context.TODO(), "ut-key", 4, time.Second)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3-i, res.Remaining)
+	}
+	res, _ := store.Take(context.TODO(), "ut-key", 4, time.Second)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 250*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, time.Second, res.Reset)
+
+	// one request restored after emission interval
+	now = now.Add(250 * time.Millisecond)
+	res, _ = store.Take(context.TODO(), "ut-key", 4, time.Second)
+	assert.True(t, res.Allowed)
+	res, _ = store.Take(context.TODO(), "ut-key", 4, time.Second)
+	assert.False(t, res.Allowed)
+
+	// stale keys are swept
+	now = now.Add(3 * time.Second)
+	store.Take(context.TODO(), "ut-other", 4, time.Second)
+	assert.Len(t, store.tats, 1)
+}
@@ -8,24 +8,53 @@ package rkgintout
 
 import (
 	"bytes"
+	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/error"
 	"github.com/rookie-ninja/rk-entry/v2/middleware"
 	"github.com/rookie-ninja/rk-entry/v2/middleware/timeout"
 	"github.com/rookie-ninja/rk-gin/v2/middleware/context"
+	"net/http"
+	"reflect"
+	"time"
 )
 
-// Middleware Add timeout interceptors.
+// key of global timeout in option set of rkmidtimeout
+const entryGlobalTimeout = "rk-global"
+
+// Middleware Add timeout interceptors, response would be buffered and written once handler finished.
+//
+// Deadline is propagated with ctx.Request.Context(), which would be canceled once timed out,
+// so that handler and downstream calls could stop early.
 func Middleware(opts ...rkmidtimeout.Option) gin.HandlerFunc {
 	set := rkmidtimeout.NewOptionSet(opts...)
+	timeouts := entryTimeouts(set)
 
 	return func(ctx *gin.Context) {
 		ctx.Set(rkmid.EntryNameKey.String(), set.GetEntryName())
 
 		// case 1: return to user if error occur
 		beforeCtx := set.BeforeCtx(ctx.Request, rkginctx.GetEvent(ctx))
+		if set.ShouldIgnore(beforeCtx.Input.UrlPath) {
+			ctx.Next()
+			return
+		}
+
+		// deadline is unknown with mock of option set, context would be canceled only
+		var reqCtx context.Context
+		var cancel context.CancelFunc
+		if timeout, ok := timeouts.get(beforeCtx.Input.UrlPath); ok {
+			reqCtx, cancel = newDeadlineCtx(ctx.Request.Context(), time.Now().Add(timeout))
+		} else {
+			reqCtx, cancel = context.WithCancel(ctx.Request.Context())
+		}
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
 		toCtx := &timeoutCtx{
-			ginCtx: ctx,
-			before: beforeCtx,
+			ginCtx:  ctx,
+			errResp: beforeCtx.Output.TimeoutErrResp,
+			cancel:  cancel,
 		}
 		// assign handlers
 		beforeCtx.Input.InitHandler = initHandler(toCtx)
@@ -41,13 +70,112 @@ func Middleware(opts ...rkmidtimeout.Option) gin.HandlerFunc {
 	}
 }
 
+// BufferMiddleware Add timeout interceptors, response would be buffered and written once handler finished.
+//
+// It is the same as Middleware, with options provided by this package, like WithSkipper and WithTimeoutByPath
+// which matches route registered in gin.
+func BufferMiddleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) {
+			ctx.Next()
+			return
+		}
+
+		timeout := set.getTimeout(ctx)
+		reqCtx, cancel := newDeadlineCtx(ctx.Request.Context(), time.Now().Add(timeout))
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		toCtx := &timeoutCtx{
+			ginCtx:  ctx,
+			errResp: rkmid.GetErrorBuilder().New(http.StatusRequestTimeout, ""),
+			cancel:  cancel,
+		}
+		initHandler(toCtx)()
+
+		finishChan := make(chan struct{}, 1)
+		panicChan := make(chan interface{}, 1)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		go func() {
+			defer func() {
+				if recv := recover(); recv != nil {
+					panicChan <- recv
+				}
+			}()
+
+			ctx.Next()
+			finishChan <- struct{}{}
+		}()
+
+		select {
+		case recv := <-panicChan:
+			panicHandler(toCtx)()
+			panic(recv)
+		case <-finishChan:
+			finishHandler(toCtx)()
+		case <-timer.C:
+			rkginctx.GetEvent(ctx).SetCounter("timeout", 1)
+			timeoutHandler(toCtx)()
+		}
+	}
+}
+
+// StreamMiddleware Add timeout interceptors which let handler write to response directly.
+//
+// Deadline is propagated with ctx.Request.Context(), headers are committed on first write, and timeout response
+// would be written only if nothing has been written, otherwise handler is expected to stop once context is done.
+// Handler runs in the same goroutine, so that Flush and Hijack are supported.
+func StreamMiddleware(opts ...Option) gin.HandlerFunc {
+	set := newOptionSet(opts...)
+
+	return func(ctx *gin.Context) {
+		ctx.Set(rkmid.EntryNameKey.String(), set.EntryName)
+
+		if set.Skipper(ctx) || set.ShouldIgnore(ctx) {
+			ctx.Next()
+			return
+		}
+
+		timeout := set.getTimeout(ctx)
+		reqCtx, cancel := newDeadlineCtx(ctx.Request.Context(), time.Now().Add(timeout))
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		oldW := ctx.Writer
+		newW := newStreamWriter(oldW)
+		ctx.Writer = newW
+
+		// cancel context after timeout response written, so that handler could not write before it
+		timer := time.AfterFunc(timeout, func() {
+			newW.writeTimeout(rkmid.GetErrorBuilder().New(http.StatusRequestTimeout, ""))
+			cancel()
+		})
+
+		ctx.Next()
+
+		timer.Stop()
+		if newW.finish() {
+			rkginctx.GetEvent(ctx).SetCounter("timeout", 1)
+			ctx.Abort()
+		}
+		ctx.Writer = oldW
+	}
+}
+
 type timeoutCtx struct {
 	bufPool *bufferPool
 	buffer  *bytes.Buffer
 	oldW    gin.ResponseWriter
 	newW    *writer
 	ginCtx  *gin.Context
-	before  *rkmidtimeout.BeforeCtx
+	errResp rkerror.ErrorInterface
+	cancel  context.CancelFunc
 }
 
 func timeoutHandler(ctx *timeoutCtx) func() {
@@ -67,11 +195,14 @@ func timeoutHandler(ctx *timeoutCtx) func() {
 		ctx.ginCtx.Writer = ctx.oldW
 
 		// write timed out response
-		ctx.ginCtx.JSON(ctx.before.Output.TimeoutErrResp.Code(), ctx.before.Output.TimeoutErrResp)
+		ctx.ginCtx.JSON(ctx.errResp.Code(), ctx.errResp)
 
 		// switch back to new writer since user code may still want to write to it.
 		// Panic may occur if we ignore this step.
 		ctx.ginCtx.Writer = ctx.newW
+
+		// notify handler and downstream calls to stop
+		ctx.cancel()
 	}
 }
 
@@ -126,3 +257,69 @@ func initHandler(ctx *timeoutCtx) func() {
 		ctx.ginCtx.Writer = ctx.newW
 	}
 }
+
+// Timeouts of rkmidtimeout by path, including global one.
+type pathTimeouts map[string]time.Duration
+
+// Read timeouts from option set of rkmidtimeout, since they are not exposed.
+// Nil would be returned if option set is not the one created by rkmidtimeout, like mock of it.
+func entryTimeouts(set rkmidtimeout.OptionSetInterface) pathTimeouts {
+	v := reflect.ValueOf(set)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	field := v.Elem().FieldByName("timeouts")
+	if field.Kind() != reflect.Map || field.Type() != reflect.TypeOf(map[string]time.Duration{}) {
+		return nil
+	}
+
+	res := make(pathTimeouts)
+	iter := field.MapRange()
+	for iter.Next() {
+		res[iter.Key().String()] = time.Duration(iter.Value().Int())
+	}
+
+	return res
+}
+
+// Get timeout of path, global one would be returned if missing.
+func (t pathTimeouts) get(path string) (time.Duration, bool) {
+	if timeout, ok := t[path]; ok {
+		return timeout, true
+	}
+
+	timeout, ok := t[entryGlobalTimeout]
+	return timeout, ok
+}
+
+// deadlineCtx reports deadline of request, and is canceled by middleware once timed out.
+type deadlineCtx struct {
+	context.Context
+	deadline time.Time
+}
+
+// Create deadlineCtx with cancel function.
+func newDeadlineCtx(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if cur, ok := parent.Deadline(); ok && cur.Before(deadline) {
+		deadline = cur
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	return &deadlineCtx{Context: ctx, deadline: deadline}, cancel
+}
+
+// Deadline returns deadline of request.
+func (ctx *deadlineCtx) Deadline() (time.Time, bool) {
+	return ctx.deadline, true
+}
+
+// Err returns context.DeadlineExceeded if canceled after deadline.
+func (ctx *deadlineCtx) Err() error {
+	err := ctx.Context.Err()
+	if err == context.Canceled && !time.Now().Before(ctx.deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return err
+}
@@ -6,6 +6,7 @@
 package rkgintout
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/rookie-ninja/rk-entry/v2/middleware/timeout"
@@ -100,6 +101,145 @@ func TestInterceptor_HappyCase(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestInterceptor_WithCancel(t *testing.T) {
+	errChan := make(chan error, 1)
+	r := getGinRouter("/", func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+		errChan <- ctx.Request.Context().Err()
+	}, Middleware(rkmidtimeout.WithTimeoutByPath("/", time.Millisecond)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	assert.Equal(t, context.DeadlineExceeded, <-errChan)
+}
+
+func TestInterceptor_WithDeadline(t *testing.T) {
+	r := gin.New()
+	r.Use(Middleware(
+		rkmidtimeout.WithTimeout(time.Minute),
+		rkmidtimeout.WithTimeoutByPath("/timeout", time.Millisecond),
+		rkmidtimeout.WithPathToIgnore("/ignore")))
+
+	r.GET("/timeout", sleepH)
+	r.GET("/ignore", func(ctx *gin.Context) {
+		_, ok := ctx.Request.Context().Deadline()
+		assert.False(t, ok)
+		ctx.Status(http.StatusNoContent)
+	})
+	r.GET("/happy", func(ctx *gin.Context) {
+		deadline, ok := ctx.Request.Context().Deadline()
+		assert.True(t, ok)
+		assert.True(t, time.Until(deadline) <= time.Minute)
+		assert.True(t, time.Until(deadline) > time.Second)
+		ctx.JSON(http.StatusOK, "{}")
+	})
+
+	// timeout on /timeout
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/timeout", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+
+	// ignored on /ignore
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/ignore", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// deadline of global timeout on /happy
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/happy", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestEntryTimeouts(t *testing.T) {
+	// with option set of rkmidtimeout
+	timeouts := entryTimeouts(rkmidtimeout.NewOptionSet(
+		rkmidtimeout.WithTimeout(time.Second),
+		rkmidtimeout.WithTimeoutByPath("/ut-path", time.Minute)))
+	timeout, ok := timeouts.get("/ut-path")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, timeout)
+	timeout, ok = timeouts.get("/ut-other")
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, timeout)
+
+	// with mock
+	timeouts = entryTimeouts(rkmidtimeout.NewOptionSetMock(rkmidtimeout.NewBeforeCtx()))
+	_, ok = timeouts.get("/ut-path")
+	assert.False(t, ok)
+}
+
+func TestBufferMiddleware_WithTimeout(t *testing.T) {
+	errChan := make(chan error, 1)
+	r := getGinRouter("/", func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+		errChan <- ctx.Request.Context().Err()
+	}, BufferMiddleware(WithTimeout(time.Millisecond)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	assert.Equal(t, context.DeadlineExceeded, <-errChan)
+}
+
+func TestBufferMiddleware_WithPanic(t *testing.T) {
+	defer assertPanic(t)
+
+	r := getGinRouter("/", panicH, BufferMiddleware(WithTimeout(time.Minute)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	r.ServeHTTP(w, req)
+}
+
+func TestBufferMiddleware_HappyCase(t *testing.T) {
+	r := gin.New()
+	r.Use(BufferMiddleware(
+		WithTimeout(time.Minute),
+		WithTimeoutByPath("/timeout", time.Millisecond),
+		WithPathToIgnore("/ignore")))
+
+	r.GET("/timeout", sleepH)
+	r.GET("/ignore", func(ctx *gin.Context) {
+		_, ok := ctx.Request.Context().Deadline()
+		assert.False(t, ok)
+		ctx.Status(http.StatusNoContent)
+	})
+	r.GET("/happy", func(ctx *gin.Context) {
+		deadline, ok := ctx.Request.Context().Deadline()
+		assert.True(t, ok)
+		assert.True(t, time.Until(deadline) <= time.Minute)
+		ctx.Header("X-Ut-Header", "ut-value")
+		ctx.JSON(http.StatusOK, "{}")
+	})
+
+	// timeout on /timeout
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/timeout", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+
+	// ignored on /ignore
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/ignore", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// OK on /happy
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/happy", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ut-value", w.Header().Get("X-Ut-Header"))
+}
+
 func assertPanic(t *testing.T) {
 	if r := recover(); r != nil {
 		// Expect panic to be called with non nil error
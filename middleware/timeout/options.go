@@ -0,0 +1,177 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgintout
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/middleware"
+	"github.com/rs/xid"
+	"strings"
+	"time"
+)
+
+const (
+	// ModeBuffered buffers response and writes it once handler finished, default mode
+	ModeBuffered = "buffered"
+	// ModeStreaming lets handler write to response directly, deadline is propagated with request context
+	ModeStreaming = "streaming"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Interceptor would distinguish timeout set based on.
+var (
+	optionsMap     = make(map[string]*optionSet)
+	defaultSkipper = func(*gin.Context) bool {
+		return false
+	}
+)
+
+// BootConfig for YAML, superset of rkmidtimeout.BootConfig.
+//
+// Response would be buffered with BufferMiddleware in buffered mode, and written directly with StreamMiddleware in streaming mode.
+type BootConfig struct {
+	Enabled   bool     `yaml:"enabled" json:"enabled"`
+	Mode      string   `yaml:"mode" json:"mode"`
+	TimeoutMs int      `yaml:"timeoutMs" json:"timeoutMs"`
+	Ignore    []string `yaml:"ignore" json:"ignore"`
+	Paths     []struct {
+		Path      string `yaml:"path" json:"path"`
+		TimeoutMs int    `yaml:"timeoutMs" json:"timeoutMs"`
+	} `yaml:"paths" json:"paths"`
+}
+
+// UseStreaming returns true if response should be written directly.
+func (config *BootConfig) UseStreaming() bool {
+	return strings.EqualFold(config.Mode, ModeStreaming)
+}
+
+// ToOptions convert BootConfig into Option list
+func ToOptions(config *BootConfig, entryName, entryType string) []Option {
+	opts := make([]Option, 0)
+
+	if config.Enabled {
+		opts = append(opts,
+			WithEntryNameAndType(entryName, entryType),
+			WithTimeout(time.Duration(config.TimeoutMs)*time.Millisecond),
+			WithPathToIgnore(config.Ignore...))
+
+		for i := range config.Paths {
+			e := config.Paths[i]
+			opts = append(opts, WithTimeoutByPath(e.Path, time.Duration(e.TimeoutMs)*time.Millisecond))
+		}
+	}
+
+	return opts
+}
+
+// Create new optionSet with options.
+func newOptionSet(opts ...Option) *optionSet {
+	set := &optionSet{
+		EntryName:     xid.New().String(),
+		EntryType:     "",
+		Skipper:       defaultSkipper,
+		Timeout:       defaultTimeout,
+		timeoutByPath: make(map[string]time.Duration),
+		ignorePrefix:  make([]string, 0),
+	}
+
+	for i := range opts {
+		opts[i](set)
+	}
+
+	if _, ok := optionsMap[set.EntryName]; !ok {
+		optionsMap[set.EntryName] = set
+	}
+
+	return set
+}
+
+// Options which is used while initializing extension interceptor
+type optionSet struct {
+	EntryName     string
+	EntryType     string
+	Skipper       Skipper
+	Timeout       time.Duration
+	timeoutByPath map[string]time.Duration
+	ignorePrefix  []string
+}
+
+// ShouldIgnore determine whether timeout should be ignored based on path
+func (set *optionSet) ShouldIgnore(ctx *gin.Context) bool {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		for i := range set.ignorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, set.ignorePrefix[i]) {
+				return true
+			}
+		}
+
+		return rkmid.ShouldIgnoreGlobal(ctx.Request.URL.Path)
+	}
+
+	return false
+}
+
+// Get timeout of request, matched with route registered in gin first, then URL path.
+func (set *optionSet) getTimeout(ctx *gin.Context) time.Duration {
+	if timeout, ok := set.timeoutByPath[ctx.FullPath()]; ok {
+		return timeout
+	}
+
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		if timeout, ok := set.timeoutByPath[ctx.Request.URL.Path]; ok {
+			return timeout
+		}
+	}
+
+	return set.Timeout
+}
+
+// Option if for middleware options while creating middleware
+type Option func(*optionSet)
+
+// WithEntryNameAndType provide entry name and entry type.
+func WithEntryNameAndType(entryName, entryType string) Option {
+	return func(opt *optionSet) {
+		opt.EntryName = entryName
+		opt.EntryType = entryType
+	}
+}
+
+// WithSkipper provide skipper.
+func WithSkipper(skip Skipper) Option {
+	return func(opt *optionSet) {
+		opt.Skipper = skip
+	}
+}
+
+// WithPathToIgnore provide path prefix to ignore middleware
+func WithPathToIgnore(prefix ...string) Option {
+	return func(opt *optionSet) {
+		opt.ignorePrefix = append(opt.ignorePrefix, prefix...)
+	}
+}
+
+// WithTimeout provide global timeout, 10 seconds would be used if missing.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opt *optionSet) {
+		if timeout > 0 {
+			opt.Timeout = timeout
+		}
+	}
+}
+
+// WithTimeoutByPath provide timeout of path, global timeout would be used if missing.
+func WithTimeoutByPath(path string, timeout time.Duration) Option {
+	return func(opt *optionSet) {
+		if timeout > 0 {
+			opt.timeoutByPath[path] = timeout
+		}
+	}
+}
+
+// Skipper default skipper will always return false
+type Skipper func(*gin.Context) bool
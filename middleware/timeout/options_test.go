@@ -0,0 +1,95 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgintout
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewOptionSet(t *testing.T) {
+	// without options
+	set := newOptionSet()
+	assert.NotEmpty(t, set.EntryName)
+	assert.Equal(t, defaultTimeout, set.Timeout)
+	assert.Empty(t, set.timeoutByPath)
+
+	// with options
+	set = newOptionSet(
+		WithEntryNameAndType("ut-entry", "ut-type"),
+		WithTimeout(time.Second),
+		WithTimeoutByPath("/ut-path", time.Minute),
+		WithTimeoutByPath("/ut-invalid", 0),
+		WithSkipper(func(*gin.Context) bool {
+			return true
+		}))
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, "ut-type", set.EntryType)
+	assert.Equal(t, time.Second, set.Timeout)
+	assert.Equal(t, map[string]time.Duration{"/ut-path": time.Minute}, set.timeoutByPath)
+	assert.True(t, set.Skipper(nil))
+}
+
+func TestOptionSet_getTimeout(t *testing.T) {
+	set := newOptionSet(
+		WithTimeout(time.Second),
+		WithTimeoutByPath("/v1/user/:id", time.Minute),
+		WithTimeoutByPath("/v1/file", time.Hour))
+
+	r := gin.New()
+	var res []time.Duration
+	handler := func(ctx *gin.Context) {
+		res = append(res, set.getTimeout(ctx))
+	}
+	r.GET("/v1/user/:id", handler)
+	r.GET("/v1/file", handler)
+	r.GET("/v1/other", handler)
+
+	for _, path := range []string{"/v1/user/1", "/v1/file", "/v1/other"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	assert.Equal(t, []time.Duration{time.Minute, time.Hour, time.Second}, res)
+}
+
+func TestOptionSet_ShouldIgnore(t *testing.T) {
+	set := newOptionSet(WithPathToIgnore("/ut-ignore"))
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/ut-ignore", nil)
+	assert.True(t, set.ShouldIgnore(ctx))
+
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/ut-path", nil)
+	assert.False(t, set.ShouldIgnore(ctx))
+}
+
+func TestBootConfig(t *testing.T) {
+	config := &BootConfig{
+		Enabled:   false,
+		TimeoutMs: 1000,
+	}
+	config.Paths = append(config.Paths, struct {
+		Path      string `yaml:"path" json:"path"`
+		TimeoutMs int    `yaml:"timeoutMs" json:"timeoutMs"`
+	}{Path: "/ut-path", TimeoutMs: 2000})
+
+	// with disabled
+	assert.Empty(t, ToOptions(config, "", ""))
+	assert.False(t, config.UseStreaming())
+
+	// with enabled
+	config.Enabled = true
+	config.Mode = "Streaming"
+	assert.True(t, config.UseStreaming())
+
+	set := newOptionSet(ToOptions(config, "ut-entry", "ut-type")...)
+	assert.Equal(t, "ut-entry", set.EntryName)
+	assert.Equal(t, time.Second, set.Timeout)
+	assert.Equal(t, 2*time.Second, set.timeoutByPath["/ut-path"])
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgintout
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/v2/error"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// streamWriter writes to response directly, headers are kept privately until committed on first write,
+// so that timeout response could be written safely while handler is still running.
+type streamWriter struct {
+	gin.ResponseWriter
+	headers   http.Header
+	mu        sync.Mutex
+	committed bool
+	timeout   bool
+}
+
+// newStreamWriter will return a streamWriter pointer with headers written by previous middlewares
+func newStreamWriter(w gin.ResponseWriter) *streamWriter {
+	return &streamWriter{ResponseWriter: w, headers: w.Header().Clone()}
+}
+
+// Header will get response headers, headers are not visible to client until committed
+func (w *streamWriter) Header() http.Header {
+	return w.headers
+}
+
+// WriteHeader will write http status code
+func (w *streamWriter) WriteHeader(code int) {
+	checkWriteHeaderCode(code)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout {
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// WriteHeaderNow commits headers and writes them to client
+func (w *streamWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout {
+		return
+	}
+
+	w.commit()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write commits headers and writes data to client
+func (w *streamWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	w.commit()
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString commits headers and writes string to client
+func (w *streamWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush commits headers and flushes data to client
+func (w *streamWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout {
+		return
+	}
+
+	w.commit()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack hijacks connection, timeout response would never be written afterwards
+func (w *streamWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+
+	if w.committed && w.ResponseWriter.Written() {
+		return nil, nil, errors.New("failed to hijack connection since response has been written")
+	}
+
+	w.commit()
+	return w.ResponseWriter.Hijack()
+}
+
+// Status will get status code of response
+func (w *streamWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.ResponseWriter.Status()
+}
+
+// Copy headers to original writer, must be called with lock held.
+func (w *streamWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		if _, ok := w.headers[k]; !ok {
+			dst.Del(k)
+		}
+	}
+	for k, vv := range w.headers {
+		dst[k] = vv
+	}
+}
+
+// Commit headers set by handler if nothing written and not timed out, returns true if timed out.
+func (w *streamWriter) finish() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.timeout {
+		w.commit()
+	}
+
+	return w.timeout
+}
+
+// Write timeout response if nothing has been committed, returns false if response has been committed.
+func (w *streamWriter) writeTimeout(resp rkerror.ErrorInterface) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.committed {
+		return false
+	}
+	w.timeout = true
+
+	body, _ := json.Marshal(resp)
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(resp.Code())
+	w.ResponseWriter.Write(body)
+
+	return true
+}
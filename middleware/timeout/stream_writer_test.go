@@ -0,0 +1,140 @@
+// Copyright (c) 2021 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rkgintout
+
+import (
+	"bufio"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getStreamRouter(handler gin.HandlerFunc, opts ...Option) *gin.Engine {
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) {
+		// header written by previous middleware
+		ctx.Header("X-Ut-Outer", "ut-value")
+	})
+	r.Use(StreamMiddleware(opts...))
+	r.GET("/", handler)
+	return r
+}
+
+func TestStreamMiddleware_WithTimeout(t *testing.T) {
+	// nothing written before timeout
+	var reqErr error
+	r := getStreamRouter(func(ctx *gin.Context) {
+		ctx.Header("X-Ut-Inner", "ut-value")
+		<-ctx.Request.Context().Done()
+		reqErr = ctx.Request.Context().Err()
+		_, err := ctx.Writer.WriteString("ut-late")
+		assert.Equal(t, http.ErrHandlerTimeout, err)
+	}, WithTimeout(10*time.Millisecond))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	assert.Equal(t, context.DeadlineExceeded, reqErr)
+	assert.Equal(t, "ut-value", w.Header().Get("X-Ut-Outer"))
+	assert.Empty(t, w.Header().Get("X-Ut-Inner"))
+	assert.NotContains(t, w.Body.String(), "ut-late")
+
+	// written before timeout
+	r = getStreamRouter(func(ctx *gin.Context) {
+		ctx.Header("X-Ut-Inner", "ut-value")
+		ctx.String(http.StatusOK, "ut-early")
+		ctx.Writer.Flush()
+		<-ctx.Request.Context().Done()
+	}, WithTimeout(10*time.Millisecond))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "ut-value", w.Header().Get("X-Ut-Inner"))
+	assert.Equal(t, "ut-early", w.Body.String())
+}
+
+func TestStreamMiddleware_HappyCase(t *testing.T) {
+	var deadline time.Time
+	r := getStreamRouter(func(ctx *gin.Context) {
+		deadline, _ = ctx.Request.Context().Deadline()
+		ctx.Header("X-Ut-Inner", "ut-value")
+		ctx.Status(http.StatusNoContent)
+	}, WithTimeout(time.Minute))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "ut-value", w.Header().Get("X-Ut-Inner"))
+	assert.Equal(t, "ut-value", w.Header().Get("X-Ut-Outer"))
+	assert.True(t, time.Until(deadline) > 50*time.Second)
+}
+
+func TestStreamMiddleware_WithIgnore(t *testing.T) {
+	var ok bool
+	r := getStreamRouter(func(ctx *gin.Context) {
+		_, ok = ctx.Request.Context().Deadline()
+	}, WithPathToIgnore("/"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, ok)
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestStreamWriter_Hijack(t *testing.T) {
+	// hijack before writing
+	recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, _ := gin.CreateTestContext(recorder)
+	w := newStreamWriter(ctx.Writer)
+	_, _, err := w.Hijack()
+	assert.Nil(t, err)
+	assert.True(t, recorder.hijacked)
+	assert.False(t, w.writeTimeout(nil))
+
+	// hijack after timeout
+	recorder = &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, _ = gin.CreateTestContext(recorder)
+	w = newStreamWriter(ctx.Writer)
+	w.timeout = true
+	_, _, err = w.Hijack()
+	assert.Equal(t, http.ErrHandlerTimeout, err)
+	assert.False(t, recorder.hijacked)
+
+	// hijack after writing
+	recorder = &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, _ = gin.CreateTestContext(recorder)
+	w = newStreamWriter(ctx.Writer)
+	w.WriteString("ut-message")
+	_, _, err = w.Hijack()
+	assert.NotNil(t, err)
+	assert.False(t, recorder.hijacked)
+}
+
+func TestStreamWriter_WriteHeader(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	w := newStreamWriter(ctx.Writer)
+	assert.Panics(t, func() {
+		w.WriteHeader(99)
+	})
+
+	w.WriteHeader(http.StatusCreated)
+	assert.Equal(t, http.StatusCreated, w.Status())
+}